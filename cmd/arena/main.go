@@ -0,0 +1,153 @@
+// arena 让两个引擎对弈多局，交替执黑，并报告胜负和 Elo 差。
+//
+//	arena -a alphabeta -b pbrain:./pbrain-embryo -games 100 -move 1s -archive games.jsonl
+//
+// 引擎写作 basic、alphabeta、mcts 或 pbrain:<可执行文件> [参数...]。
+// 开局文件每行一个开局，着法用 h8 形式的坐标以空格分隔，每个开局双方各执黑一次。
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/pkg/arena"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/engines"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// 未指定开局文件时使用的开局
+var defaultOpenings = []string{
+	"h8",
+	"h8 h9 h10",
+	"h8 h9 i10",
+	"h8 i9 j10",
+	"h8 i9 j8",
+	"h8 i9 g10",
+	"h8 i8 j8",
+	"h8 i8 g9",
+}
+
+func main() {
+	specA := flag.String("a", "basic", "engine A")
+	specB := flag.String("b", "basic", "engine B")
+	games := flag.Int("games", 10, "number of games")
+	openingFile := flag.String("openings", "", "opening book file, one opening per line")
+	base := flag.Duration("base", 0, "base thinking time per game, 0 for none")
+	inc := flag.Duration("inc", 0, "increment per move")
+	move := flag.Duration("move", 5*time.Second, "max thinking time per move, 0 for none")
+	archive := flag.String("archive", "", "append finished games as JSON lines to this file")
+	exact := flag.Bool("exact", false, "only exactly five in a row wins")
+	flag.Parse()
+
+	openings, err := loadOpenings(*openingFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	clock := arena.Clock{Base: *base, Increment: *inc, Move: *move}
+
+	var out *json.Encoder
+	if *archive != "" {
+		f, err := os.OpenFile(*archive, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		out = json.NewEncoder(f)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	m := &arena.Match{
+		A:        func(ctx context.Context) (engine.Engine, error) { return newEngine(ctx, *specA, clock) },
+		B:        func(ctx context.Context) (engine.Engine, error) { return newEngine(ctx, *specB, clock) },
+		Games:    *games,
+		Openings: openings,
+		Rules:    engine.Rules{ExactFive: *exact},
+		Clock:    clock,
+		OnGame: func(n int, aBlack bool, game *arena.Game) {
+			if out != nil {
+				if err := out.Encode(archived(game)); err != nil {
+					log.Fatal(err)
+				}
+			}
+			fmt.Printf("game %d: %v vs %v  opening %v  result %v (%v, %d moves)\n",
+				n+1, game.Black, game.White, engine.FormatMoves(openings[(n/2)%len(openings)]), game.Winner, game.Cause, len(game.Steps))
+		},
+	}
+	stats, err := m.Run(ctx)
+	if err != nil && ctx.Err() == nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("A=%v B=%v  %v\n", *specA, *specB, stats)
+}
+
+func newEngine(ctx context.Context, spec string, clock arena.Clock) (engine.Engine, error) {
+	return engines.New(ctx, spec, pbrain.Options{
+		TurnTimeout:  clock.Move,
+		MatchTimeout: clock.Base,
+	})
+}
+
+// archived 转为服务端对局存档的格式
+func archived(game *arena.Game) *entity.Game {
+	return &entity.Game{
+		Id:        game.Id,
+		Black:     entity.Player{Id: "black", Name: game.Black},
+		White:     entity.Player{Id: "white", Name: game.White},
+		Steps:     game.Steps,
+		Winner:    game.Winner,
+		Cause:     game.Cause,
+		StartTime: game.StartTime.Format(time.DateTime),
+		EndTime:   game.EndTime.Format(time.DateTime),
+	}
+}
+
+func loadOpenings(path string) ([][]protocol.Chess, error) {
+	lines := defaultOpenings
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		lines = nil
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	openings := make([][]protocol.Chess, 0, len(lines))
+	for _, line := range lines {
+		moves, err := engine.ParseMoves(line)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = engine.NewBoard(moves); err != nil {
+			return nil, fmt.Errorf("opening %q: %v", line, err)
+		}
+		openings = append(openings, moves)
+	}
+	if len(openings) == 0 {
+		openings = append(openings, nil)
+	}
+	return openings, nil
+}
@@ -0,0 +1,59 @@
+// gomoku-bot 启动一个引擎，并以玩家身份接入服务端的房间。
+//
+//	gomoku-bot -engine ./pbrain-embryo -room <rid> -rematch
+//	gomoku-bot -engine mcts -turn 2s
+//
+// -engine 可以是内置引擎 basic、alphabeta、mcts，也可以是 pbrain（Gomocup 协议）引擎的可执行文件。
+// 不指定 -room 时机器人会自己创建房间并等待挑战者。
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/bot"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/engines"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+func main() {
+	addr := flag.String("addr", "ws://127.0.0.1:9950/ws", "server websocket address")
+	spec := flag.String("engine", "alphabeta", "builtin engine name or path of a pbrain engine executable")
+	args := flag.String("args", "", "space separated pbrain engine arguments")
+	room := flag.String("room", "", "room id to join as challenger, empty to create one")
+	white := flag.Bool("white", false, "play white when creating a room")
+	name := flag.String("name", "", "player name, defaults to the engine name")
+	turn := flag.Duration("turn", 5*time.Second, "thinking time per move")
+	rematch := flag.Bool("rematch", false, "get ready again after each game")
+	restarts := flag.Int("restarts", 3, "how many times to restart a crashed engine")
+	flag.Parse()
+
+	color := protocol.BLACK
+	if *white {
+		color = protocol.WHITE
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := bot.Run(ctx, bot.Options{
+		URL:         *addr,
+		Name:        *name,
+		RoomId:      *room,
+		Color:       color,
+		Rematch:     *rematch,
+		MaxRestarts: *restarts,
+		NewEngine:   engines.Factory(strings.TrimSpace(*spec+" "+*args), pbrain.Options{TurnTimeout: *turn}),
+		Limits:      engine.Limits{Time: *turn},
+	})
+	if err != nil && err != context.Canceled {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// renderBoard 以文本绘制棋盘，X 为黑子，O 为白子，最后一手用括号标出
+func renderBoard(steps []protocol.Chess) string {
+	size := int(protocol.BoardSize)
+	grid := make([][]byte, size)
+	for i := range grid {
+		grid[i] = []byte(strings.Repeat(".", size))
+	}
+	for k, c := range steps {
+		if k%2 == int(protocol.BLACK) {
+			grid[c.I][c.J] = 'X'
+		} else {
+			grid[c.I][c.J] = 'O'
+		}
+	}
+
+	var last *protocol.Chess
+	if len(steps) > 0 {
+		last = &steps[len(steps)-1]
+	}
+
+	b := &strings.Builder{}
+	b.WriteString("    ")
+	for j := 0; j < size; j++ {
+		fmt.Fprintf(b, " %c ", 'a'+j)
+	}
+	b.WriteString("\n")
+	for i := size - 1; i >= 0; i-- {
+		fmt.Fprintf(b, "%3d ", i+1)
+		for j := 0; j < size; j++ {
+			if last != nil && int(last.I) == i && int(last.J) == j {
+				fmt.Fprintf(b, "(%c)", grid[i][j])
+			} else {
+				fmt.Fprintf(b, " %c ", grid[i][j])
+			}
+		}
+		fmt.Fprintf(b, " %d\n", i+1)
+	}
+	return b.String()
+}
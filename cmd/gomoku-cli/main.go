@@ -0,0 +1,49 @@
+// gomoku-cli 是基于 websocket 协议的终端客户端，可用于在本地服务器上对弈、调试和脚本化对局。
+//
+//	gomoku-cli -addr ws://127.0.0.1:9950/ws
+//
+// 启动后输入 /help 查看命令；直接输入 h8 之类的坐标落子，输入其他文字发送聊天。
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/toujourser/gomoku/pkg/client"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+func main() {
+	addr := flag.String("addr", "ws://127.0.0.1:9950/ws", "server websocket address")
+	lang := flag.String("lang", "zh", "language of server error messages, zh or en")
+	name := flag.String("name", "", "player name to use after connecting")
+	flag.Parse()
+
+	t := newTerminal(os.Stdout)
+	c, err := client.Dial(context.Background(), client.Options{
+		URL:    *addr,
+		Lang:   *lang,
+		Topics: []string{protocol.TopicHall, protocol.TopicLobby},
+	}, t.handlers())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "connect:", err)
+		os.Exit(1)
+	}
+	defer c.Close()
+	t.c = c
+
+	if *name != "" {
+		t.exec("/name " + *name)
+	}
+	t.exec("/rooms")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if !t.exec(scanner.Text()) {
+			return
+		}
+	}
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/client"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+const help = `commands:
+  /rooms                 list rooms
+  /players               list online players
+  /create [black|white]  create a room, black by default
+  /join <rid>            take the challenger seat (rid prefix is enough)
+  /watch <rid>           enter a room as spectator
+  /leave                 leave the current room
+  /ready, /unready       toggle ready state
+  <coord>                make a move, e.g. h8
+  /undo                  ask the opponent to retract
+  /draw                  offer a draw
+  /accept, /reject       answer the opponent's retract or draw request
+  /resign                surrender the current game
+  /board                 redraw the board
+  /hall <text>           hall chat; plain text goes to the room when in one
+  /name <name>           rename
+  /quit                  exit`
+
+// terminal 终端客户端的状态，回调和输入处理在不同的 goroutine 中执行，状态由 mu 保护
+type terminal struct {
+	c   *client.Client
+	out io.Writer
+
+	mu      sync.Mutex
+	room    *protocol.Room
+	pending int // 等待应答的对方请求：protocol.RetractStep 或 protocol.AskDraw
+}
+
+func newTerminal(out io.Writer) *terminal {
+	return &terminal{out: out}
+}
+
+func (t *terminal) printf(format string, args ...interface{}) {
+	fmt.Fprintf(t.out, format+"\n", args...)
+}
+
+func (t *terminal) handlers() client.Handlers {
+	return client.Handlers{
+		OnConnect: func(p protocol.Player) {
+			t.printf("* connected as %v (%v)", p.Name, p.Id)
+		},
+		OnDisconnect: func(err error) {
+			t.mu.Lock()
+			t.room = nil
+			t.mu.Unlock()
+			t.printf("* disconnected: %v, reconnecting...", err)
+		},
+		OnHallChat: func(msg protocol.DialogMsg) {
+			t.printf("[hall %v] %v: %v", msg.Time, msg.From, msg.Content)
+		},
+		OnRooms: func(lobby protocol.LobbyDTO) {
+			t.printf("* lobby: %d room(s), /rooms to list", lobby.Total)
+		},
+		OnLobbyDelta: func(delta protocol.LobbyDeltaDTO) {
+			t.mu.Lock()
+			idle := t.room == nil
+			t.mu.Unlock()
+			if !idle {
+				return
+			}
+			switch delta.Op {
+			case protocol.DeltaAdd:
+				t.printf("* room %v opened by %v", short(delta.RId), summarySeat(delta.Room.Host))
+			case protocol.DeltaRemove:
+				t.printf("* room %v closed", short(delta.RId))
+			}
+		},
+		OnRoom: func(code int, room protocol.Room) {
+			t.mu.Lock()
+			if !t.inRoom(room) {
+				t.mu.Unlock()
+				return
+			}
+			t.room = &room
+			t.mu.Unlock()
+			t.printRoom(room)
+		},
+		OnRoomClosed: func(rid string) {
+			t.mu.Lock()
+			if t.room != nil && t.room.Id == rid {
+				t.room = nil
+			}
+			t.mu.Unlock()
+			t.printf("* room %v closed", short(rid))
+		},
+		OnRoomChat: func(msg client.RoomChat) {
+			t.printf("[room %v] %v: %v", msg.Time, msg.From, msg.Content)
+		},
+		OnStep: func(step client.Step) {
+			t.mu.Lock()
+			if t.room == nil || t.room.Id != step.RId {
+				t.mu.Unlock()
+				return
+			}
+			t.room.Steps = append(t.room.Steps, protocol.Chess{I: step.I, J: step.J})
+			steps := t.room.Steps
+			t.mu.Unlock()
+			t.printf("%v", renderBoard(steps))
+			t.printf("* move %d: %v", len(steps), engine.FormatMove(steps[len(steps)-1]))
+		},
+		OnRetract: func(p client.Proposal) {
+			t.onProposal(protocol.RetractStep, "retract", p)
+		},
+		OnDraw: func(p client.Proposal) {
+			t.onProposal(protocol.AskDraw, "draw", p)
+		},
+		OnGameOver: func(r protocol.GameOverDTO) {
+			if r.Winner.Id == "" {
+				t.printf("* game over: %v", r.Cause)
+				return
+			}
+			t.printf("* game over: %v wins by %v", r.Winner.Name, r.Cause)
+		},
+		OnError: func(err error) {
+			t.printf("! %v", err)
+		},
+	}
+}
+
+// inRoom 判断当前玩家是否在 room 中，调用方需持有 mu
+func (t *terminal) inRoom(room protocol.Room) bool {
+	pid := t.c.Player().Id
+	if room.Host.Id == pid || room.Challenger.Id == pid {
+		return true
+	}
+	for _, p := range room.Spectators {
+		if p.Id == pid {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *terminal) onProposal(code int, what string, p client.Proposal) {
+	switch p.Consent {
+	case client.ConsentAsk:
+		t.mu.Lock()
+		t.pending = code
+		t.mu.Unlock()
+		t.printf("* opponent asks for a %v, /accept or /reject", what)
+	case client.ConsentReject:
+		t.printf("* %v request rejected", what)
+	case client.ConsentAgree:
+		if code != protocol.RetractStep {
+			return
+		}
+		t.mu.Lock()
+		if t.room != nil && p.Count <= len(t.room.Steps) {
+			t.room.Steps = t.room.Steps[:len(t.room.Steps)-p.Count]
+		}
+		var steps []protocol.Chess
+		if t.room != nil {
+			steps = t.room.Steps
+		}
+		t.mu.Unlock()
+		t.printf("%v", renderBoard(steps))
+		t.printf("* %d move(s) retracted", p.Count)
+	}
+}
+
+func (t *terminal) printRoom(room protocol.Room) {
+	t.printf("%v", renderBoard(room.Steps))
+	t.printf("room %v  host %v%v  challenger %v%v  spectators %d  started %v",
+		short(room.Id), seat(room.Host), readyMark(room.Host), seat(room.Challenger), readyMark(room.Challenger),
+		len(room.Spectators), room.Started)
+}
+
+func seat(p protocol.PlayerDetails) string {
+	if p.Id == "" {
+		return "-"
+	}
+	color := "black"
+	if p.Color == protocol.WHITE {
+		color = "white"
+	}
+	return fmt.Sprintf("%v(%v)", p.Name, color)
+}
+
+func readyMark(p protocol.PlayerDetails) string {
+	if p.Ready {
+		return "*"
+	}
+	return ""
+}
+
+func short(id string) string {
+	if len(id) > 8 {
+		return id[:8]
+	}
+	return id
+}
+
+// exec 执行一行输入，返回 false 表示退出
+func (t *terminal) exec(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+
+	t.mu.Lock()
+	room := t.room
+	t.mu.Unlock()
+
+	var err error
+	switch cmd {
+	case "/help":
+		t.printf(help)
+	case "/quit", "/exit":
+		if room != nil {
+			_ = t.c.LeaveRoom(ctx, room.Id)
+		}
+		return false
+	case "/rooms":
+		err = t.listRooms(ctx)
+	case "/players":
+		err = t.listPlayers(ctx)
+	case "/create":
+		color := protocol.BLACK
+		if arg == "white" {
+			color = protocol.WHITE
+		}
+		var r *protocol.Room
+		if r, err = t.c.CreateRoom(ctx, color); err == nil {
+			t.mu.Lock()
+			t.room = r
+			t.mu.Unlock()
+			t.printRoom(*r)
+		}
+	case "/join", "/watch":
+		role := "challenger"
+		if cmd == "/watch" {
+			role = "spectator"
+		}
+		var rid string
+		if rid, err = t.resolveRoom(ctx, arg); err == nil {
+			err = t.c.EnterRoom(ctx, rid, role)
+		}
+	case "/board":
+		if room == nil {
+			t.printf("! not in a room")
+		} else {
+			t.printRoom(*room)
+		}
+	case "/hall":
+		err = t.c.HallChat(ctx, arg)
+	case "/name":
+		err = t.c.Rename(ctx, arg)
+		t.printf("* you are now %v", t.c.Player().Name)
+	default:
+		if room == nil {
+			if strings.HasPrefix(cmd, "/") {
+				t.printf("! unknown command %v, or not in a room; /help for help", cmd)
+				return true
+			}
+			err = t.c.HallChat(ctx, line)
+			break
+		}
+		err = t.execInRoom(ctx, room.Id, cmd, line)
+	}
+	if err != nil {
+		t.printf("! %v", err)
+	}
+	return true
+}
+
+// execInRoom 执行需要在房间内才能使用的命令
+func (t *terminal) execInRoom(ctx context.Context, rid string, cmd string, line string) error {
+	switch cmd {
+	case "/leave":
+		t.mu.Lock()
+		t.room = nil
+		t.mu.Unlock()
+		return t.c.LeaveRoom(ctx, rid)
+	case "/ready", "/unready":
+		return t.c.SetReady(ctx, rid, cmd == "/ready")
+	case "/undo":
+		return t.c.RetractStep(ctx, rid, client.ConsentAsk)
+	case "/draw":
+		return t.c.AskDraw(ctx, rid, client.ConsentAsk)
+	case "/accept", "/reject":
+		consent := client.ConsentReject
+		if cmd == "/accept" {
+			consent = client.ConsentAgree
+		}
+		t.mu.Lock()
+		pending := t.pending
+		t.pending = 0
+		t.mu.Unlock()
+		switch pending {
+		case protocol.RetractStep:
+			return t.c.RetractStep(ctx, rid, consent)
+		case protocol.AskDraw:
+			return t.c.AskDraw(ctx, rid, consent)
+		}
+		return fmt.Errorf("nothing to answer")
+	case "/resign":
+		return t.c.Surrender(ctx, rid)
+	}
+	if c, err := engine.ParseMove(cmd); err == nil && !strings.Contains(line, " ") {
+		return t.c.MakeStep(ctx, rid, c.I, c.J)
+	}
+	if strings.HasPrefix(cmd, "/") {
+		return fmt.Errorf("unknown command %v, /help for help", cmd)
+	}
+	return t.c.RoomChat(ctx, rid, line)
+}
+
+func (t *terminal) listRooms(ctx context.Context) error {
+	lobby, err := t.c.GetRooms(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if lobby.Total == 0 {
+		t.printf("no rooms, /create to open one")
+	}
+	for _, r := range lobby.Rooms {
+		challenger := "-"
+		if r.Challenger != nil {
+			challenger = summarySeat(*r.Challenger)
+		}
+		t.printf("%v  host %v  challenger %v  spectators %d  %v",
+			short(r.Id), summarySeat(r.Host), challenger, r.Spectators, r.State)
+	}
+	if len(lobby.Rooms) < lobby.Total {
+		t.printf("showing %d of %d rooms", len(lobby.Rooms), lobby.Total)
+	}
+	return nil
+}
+
+func summarySeat(s protocol.Seat) string {
+	color := "black"
+	if s.Color == protocol.WHITE {
+		color = "white"
+	}
+	return fmt.Sprintf("%v[%d](%v)", s.Name, s.Rating, color)
+}
+
+func (t *terminal) listPlayers(ctx context.Context) error {
+	players, err := t.c.GetPlayers(ctx)
+	if err != nil {
+		return err
+	}
+	for _, p := range players {
+		t.printf("%v  %v  %v", short(p.Id), p.Name, p.Status)
+	}
+	return nil
+}
+
+// resolveRoom 根据前缀找到唯一的房间 id
+func (t *terminal) resolveRoom(ctx context.Context, prefix string) (string, error) {
+	if prefix == "" {
+		return "", fmt.Errorf("room id required")
+	}
+	var found []string
+	q := &protocol.LobbyQuery{Limit: 100}
+	for {
+		lobby, err := t.c.GetRooms(ctx, q)
+		if err != nil {
+			return "", err
+		}
+		for _, r := range lobby.Rooms {
+			if strings.HasPrefix(r.Id, prefix) {
+				found = append(found, r.Id)
+			}
+		}
+		q.Offset += len(lobby.Rooms)
+		if len(lobby.Rooms) == 0 || q.Offset >= lobby.Total {
+			break
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no room matches %v", prefix)
+	case 1:
+		return found[0], nil
+	}
+	return "", fmt.Errorf("%v matches %d rooms", prefix, len(found))
+}
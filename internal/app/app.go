@@ -1,23 +1,106 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/olahol/melody"
 	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/bus"
+	"github.com/toujourser/gomoku/internal/lock"
 	"github.com/toujourser/gomoku/internal/middleware"
+	"github.com/toujourser/gomoku/internal/redis"
+	"github.com/toujourser/gomoku/internal/rest"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/internal/store/boltdb"
+	"github.com/toujourser/gomoku/internal/store/cache"
+	"github.com/toujourser/gomoku/internal/store/memory"
 	"github.com/toujourser/gomoku/internal/websocket"
+	"github.com/toujourser/gomoku/pkg/logger"
+	pkgredis "github.com/toujourser/gomoku/pkg/redis"
+	"time"
 )
 
 func InitServer() {
-	router := gin.New()
-	router.Use(middleware.RequestLogger(), gin.Recovery())
+	ctx := context.Background()
+	stores := initStores(ctx)
+	service.UseStores(stores)
 	m := melody.New()
 	ms := &websocket.MelodySocket{M: m}
 	m.HandleMessage(ms.Receive)
 	m.HandleConnect(ms.Connect)
 	m.HandleDisconnect(ms.Disconnect)
+	// 总线先于修复启动，修复时需要请求其他实例交出房间锁
+	if stores.Shared {
+		ms.StartBus(ctx, stores.Presence)
+	}
+	if _, err := service.Recover(ctx); err != nil {
+		logger.Error(err)
+	}
+	service.StartReviewer(ctx)
+	router := gin.New()
+	router.Use(middleware.RequestLogger(), gin.Recovery())
 	router.GET("/ws", func(c *gin.Context) {
 		_ = m.HandleRequest(c.Writer, c.Request)
 	})
+	rest.Register(router)
 	_ = router.Run(viper.GetString("server.port"))
 }
+
+// initStores 按 store.backend 选择存储：
+// redis 可以被多个实例共享；file 将数据保存在 store.path 指定的文件中，不依赖任何外部服务；
+// memory 只用于单实例的开发调试，数据在重启后丢失。后两者只支持单实例，不启动跨实例的消息总线。
+func initStores(ctx context.Context) store.Stores {
+	switch backend := viper.GetString("store.backend"); backend {
+	case "memory":
+		return memory.NewStores()
+	case "file":
+		// 数据文件在进程退出前一直保持打开
+		stores, _, err := boltdb.Open(viper.GetString("store.path"))
+		if err != nil {
+			panic(err)
+		}
+		return stores
+	case "", "redis":
+		if err := pkgredis.Ping(ctx); err != nil {
+			panic(err)
+		}
+		n, err := redis.MigrateRooms(ctx)
+		if err != nil {
+			panic(err)
+		}
+		if n > 0 {
+			logger.WithField("rooms", n).Info("rooms migrated to the split layout")
+		}
+		stores := redis.NewStores()
+		if interval := viper.GetDuration("store.write_behind"); interval > 0 {
+			stores = writeBehind(ctx, stores, interval)
+		}
+		return stores
+	default:
+		panic(fmt.Errorf("unknown store backend %q", backend))
+	}
+}
+
+// writeBehind 本实例持有的房间保存在内存中，每隔 interval 写回 Redis。
+// 实例保留拿到的房间锁，其他实例需要修改房间时通过总线请求交出，交出前先写回最新状态；
+// 超过 store.room_idle 没有操作的房间主动交出。
+func writeBehind(ctx context.Context, stores store.Stores, interval time.Duration) store.Stores {
+	rooms := cache.NewRooms(stores.Rooms, stores.RoomLock)
+	stores.Rooms = rooms
+	stores.RoomLock.Sticky(lock.StickyOptions{
+		Owner: bus.InstanceId,
+		Idle:  viper.GetDuration("store.room_idle"),
+		Request: func(owner string, rid string) {
+			if err := bus.PublishTo(ctx, owner, &bus.Envelope{Kind: bus.KindRelease, Target: rid}); err != nil {
+				logger.Error(err)
+			}
+		},
+		Release: rooms.Evict,
+		Lost:    rooms.Forget,
+	})
+	go rooms.Run(ctx, interval)
+	go stores.RoomLock.Keep(ctx)
+	return stores
+}
@@ -0,0 +1,170 @@
+// Package bus 基于 Redis pub/sub 的跨实例消息总线。
+//
+// 每个实例订阅全局频道 bus:global 和自己的频道 bus:instance:<实例>。
+// 房间消息和全局广播发到全局频道，由各实例投递给本地会话；
+// 发给单个玩家的消息按在线状态找到玩家所在的实例后发到该实例的频道。
+package bus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	uuid "github.com/satori/go.uuid"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/pkg/logger"
+	pkgredis "github.com/toujourser/gomoku/pkg/redis"
+	"sync/atomic"
+	"time"
+)
+
+// 消息的投递范围
+const (
+	KindPlayer  = "player"  // Target 为玩家 id
+	KindRoom    = "room"    // Target 为房间 id，PIds 为房间内的玩家
+	KindTopic   = "topic"   // Target 为主题，投递给订阅了该主题的连接
+	KindRelease = "release" // 请求交出 Target 房间的锁，发给持有锁的实例
+)
+
+// 心跳间隔与过期时间，实例超过 heartbeatTTL 没有心跳视为下线
+const (
+	heartbeatInterval = 10 * time.Second
+	heartbeatTTL      = 30 * time.Second
+)
+
+const globalChannel = "bus:global"
+
+// InstanceId 当前实例的 id，每次启动生成
+var InstanceId = uuid.NewV4().String()
+
+// Envelope 总线上传递的消息，Msg 为已编码的 dto.Message
+type Envelope struct {
+	Origin string          `json:"origin"`
+	Kind   string          `json:"kind"`
+	Target string          `json:"target,omitempty"`
+	PIds   []string        `json:"pids,omitempty"`
+	Msg    json.RawMessage `json:"msg"`
+}
+
+// Handler 处理其他实例发来的消息，本实例发出的全局消息不会回到 Handler
+type Handler func(env *Envelope)
+
+func instanceChannel(instance string) string {
+	return "bus:instance:" + instance
+}
+
+// started 只有一个实例、不依赖 Redis 运行时不启动总线，发布和在线状态都不做任何事
+var started atomic.Bool
+
+// presence 玩家所在的实例和实例心跳，Start 时设置
+var presence store.PresenceStore
+
+// Start 订阅总线并定期刷新实例心跳，返回时两个频道都已订阅成功，ctx 结束时退出
+func Start(ctx context.Context, p store.PresenceStore, handler Handler) {
+	presence = p
+	sub := pkgredis.RedisClient.Subscribe(ctx, globalChannel, instanceChannel(InstanceId))
+	for k := 0; k < 2; k++ {
+		if _, err := sub.Receive(ctx); err != nil {
+			logger.Error(err)
+			break
+		}
+	}
+	started.Store(true)
+	go func() {
+		defer sub.Close()
+		defer started.Store(false)
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-ch:
+				if !ok {
+					return
+				}
+				env := &Envelope{}
+				if err := json.Unmarshal([]byte(m.Payload), env); err != nil {
+					logger.Error(err)
+					continue
+				}
+				if env.Origin == InstanceId && m.Channel == globalChannel {
+					continue
+				}
+				handler(env)
+			}
+		}
+	}()
+
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			if err := presence.Heartbeat(ctx, InstanceId, heartbeatTTL); err != nil {
+				logger.Error(err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Publish 发布消息：玩家消息发到玩家所在实例，其他消息发到全局频道。
+// 玩家不在线时返回 false。
+func Publish(ctx context.Context, env *Envelope) (bool, error) {
+	if !started.Load() {
+		return false, nil
+	}
+	env.Origin = InstanceId
+	channel := globalChannel
+	if env.Kind == KindPlayer {
+		instance, err := presence.Get(ctx, env.Target)
+		if err != nil || instance == "" {
+			return false, err
+		}
+		channel = instanceChannel(instance)
+	}
+
+	b, err := json.Marshal(env)
+	if err != nil {
+		return false, fmt.Errorf("error marshal envelope: %v", err)
+	}
+	if err = pkgredis.RedisClient.Publish(ctx, channel, b).Err(); err != nil {
+		return false, fmt.Errorf("error publish to %v: %v", channel, err)
+	}
+	return true, nil
+}
+
+// PublishTo 发布消息到指定实例
+func PublishTo(ctx context.Context, instance string, env *Envelope) error {
+	if !started.Load() {
+		return nil
+	}
+	env.Origin = InstanceId
+	b, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("error marshal envelope: %v", err)
+	}
+	channel := instanceChannel(instance)
+	if err = pkgredis.RedisClient.Publish(ctx, channel, b).Err(); err != nil {
+		return fmt.Errorf("error publish to %v: %v", channel, err)
+	}
+	return nil
+}
+
+// Online 记录玩家连接在当前实例
+func Online(ctx context.Context, pid string) error {
+	if !started.Load() {
+		return nil
+	}
+	return presence.SetOnline(ctx, pid, InstanceId)
+}
+
+// Offline 玩家从当前实例断开
+func Offline(ctx context.Context, pid string) error {
+	if !started.Load() {
+		return nil
+	}
+	return presence.SetOffline(ctx, pid, InstanceId)
+}
@@ -0,0 +1,6 @@
+package constants
+
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// BoardSize 棋盘边长
+const BoardSize = protocol.BoardSize
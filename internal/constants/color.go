@@ -1,6 +1,8 @@
 package constants
 
+import "github.com/toujourser/gomoku/pkg/protocol"
+
 const (
-	BLACK int8 = iota
-	WHITE
+	BLACK = protocol.BLACK
+	WHITE = protocol.WHITE
 )
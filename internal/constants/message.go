@@ -1,24 +1,42 @@
 package constants
 
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// 消息码定义在 pkg/protocol，供第三方客户端使用
 const (
-	Fail = iota
-	Success
-	HallChat
-	GetHallDialog
-	GetRooms
-	CreateRoom
-	EnterRoom
-	LeaveRoom
-	DelRoom
-	RoomChat
-	GetPlayer
-	GetPlayers
-	PlayerRename
-	SetPlayerStatus
-	SetReady
-	MakeStep
-	RetractStep // 悔棋
-	Surrender   // 投降
-	AskDraw     // 求和
-	GameOver
+	Fail            = protocol.Fail
+	Success         = protocol.Success
+	HallChat        = protocol.HallChat
+	GetHallDialog   = protocol.GetHallDialog
+	GetRooms        = protocol.GetRooms
+	CreateRoom      = protocol.CreateRoom
+	EnterRoom       = protocol.EnterRoom
+	LeaveRoom       = protocol.LeaveRoom
+	DelRoom         = protocol.DelRoom
+	RoomChat        = protocol.RoomChat
+	GetPlayer       = protocol.GetPlayer
+	GetPlayers      = protocol.GetPlayers
+	PlayerRename    = protocol.PlayerRename
+	SetPlayerStatus = protocol.SetPlayerStatus
+	SetReady        = protocol.SetReady
+	MakeStep        = protocol.MakeStep
+	RetractStep     = protocol.RetractStep
+	Surrender       = protocol.Surrender
+	AskDraw         = protocol.AskDraw
+	GameOver        = protocol.GameOver
+	Hello           = protocol.Hello
+	Hint            = protocol.Hint
+	GetReview       = protocol.GetReview
+	TreeMove        = protocol.TreeMove
+	TreeGoto        = protocol.TreeGoto
+	TreeDelete      = protocol.TreeDelete
+	TreeLoad        = protocol.TreeLoad
+	StartPuzzle     = protocol.StartPuzzle
+	PuzzleMove      = protocol.PuzzleMove
+	ImportPuzzle    = protocol.ImportPuzzle
+	Explore         = protocol.Explore
+	Subscribe       = protocol.Subscribe
+	Unsubscribe     = protocol.Unsubscribe
+	LobbyDelta      = protocol.LobbyDelta
+	PlayerDelta     = protocol.PlayerDelta
 )
@@ -0,0 +1,16 @@
+package constants
+
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// 服务端支持的 websocket 协议版本范围，客户端在连接时通过 ?version= 声明自己的版本
+const (
+	MinProtocolVersion = protocol.MinProtocolVersion
+	ProtocolVersion    = protocol.ProtocolVersion
+)
+
+// LobbyVersion 起 GetRooms 返回分页的房间摘要，之前的版本返回完整的房间列表
+const LobbyVersion = protocol.LobbyVersion
+
+// TopicVersion 起连接只接收已订阅主题的推送，大厅和玩家列表以增量事件推送；
+// 之前的版本视为订阅了大厅、大厅聊天和玩家列表，并继续接收完整列表
+const TopicVersion = protocol.TopicVersion
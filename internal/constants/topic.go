@@ -0,0 +1,20 @@
+package constants
+
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// 可订阅的主题，房间主题为 room:<rid>
+const (
+	TopicLobby   = protocol.TopicLobby   // 大厅房间增量更新
+	TopicHall    = protocol.TopicHall    // 大厅聊天
+	TopicPlayers = protocol.TopicPlayers // 玩家列表增量更新
+)
+
+// RoomTopic 返回房间 rid 的主题
+func RoomTopic(rid string) string {
+	return protocol.RoomTopic(rid)
+}
+
+// ParseRoomTopic 从房间主题中取出房间 id，不是房间主题时返回 false
+func ParseRoomTopic(topic string) (string, bool) {
+	return protocol.ParseRoomTopic(topic)
+}
@@ -0,0 +1,33 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/entity"
+
+// AccountDTO 返回给客户端的账号信息，不含密码
+type AccountDTO struct {
+	Id         string `json:"id"`
+	Name       string `json:"name"`
+	Rating     int    `json:"rating"`
+	CreateTime string `json:"create_time"`
+}
+
+// CredentialsDTO 注册和登录请求
+type CredentialsDTO struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+// LoginDTO 登录结果，连接 websocket 时通过 ?token= 以该账号进入
+type LoginDTO struct {
+	Token      string     `json:"token"`
+	ExpireTime string     `json:"expire_time"`
+	Account    AccountDTO `json:"account"`
+}
+
+func NewAccountDTO(a *entity.Account) AccountDTO {
+	return AccountDTO{
+		Id:         a.Id,
+		Name:       a.Name,
+		Rating:     a.Rating,
+		CreateTime: a.CreateTime,
+	}
+}
@@ -0,0 +1,24 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/entity"
+
+// 增量事件的操作
+const (
+	DeltaAdd    = "add"
+	DeltaUpdate = "update"
+	DeltaRemove = "remove"
+)
+
+// LobbyDeltaDTO 大厅中一个房间的变化，remove 时 Room 为 null
+type LobbyDeltaDTO struct {
+	Op   string       `json:"op"`
+	RId  string       `json:"rid"`
+	Room *RoomSummary `json:"room"`
+}
+
+// PlayerDeltaDTO 玩家列表中一个玩家的变化，remove 时 Player 为 null
+type PlayerDeltaDTO struct {
+	Op     string         `json:"op"`
+	PId    string         `json:"pid"`
+	Player *entity.Player `json:"player"`
+}
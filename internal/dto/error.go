@@ -0,0 +1,19 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/errcode"
+
+// ErrDTO 返回给客户端的错误
+type ErrDTO struct {
+	Code    errcode.Code   `json:"code"`
+	Message string         `json:"message"`
+	Params  errcode.Params `json:"params,omitempty"`
+}
+
+func NewErrDTO(err error, lang string) ErrDTO {
+	e := errcode.From(err)
+	return ErrDTO{
+		Code:    e.Code,
+		Message: e.Message(lang),
+		Params:  e.Params,
+	}
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/entity"
+
+// ExplorerDTO 开局浏览器中一个局面的统计，Moves 按对局数倒序
+type ExplorerDTO struct {
+	Steps []entity.Chess `json:"steps"`
+	Games int64          `json:"games"`
+	Moves []ExplorerMove `json:"moves"`
+}
+
+// ExplorerMove 在该局面下走过的一手棋，坐标已换回查询局面的方向
+type ExplorerMove struct {
+	Move      entity.Chess `json:"move"`
+	Games     int64        `json:"games"`
+	BlackWins int64        `json:"black_wins"`
+	WhiteWins int64        `json:"white_wins"`
+	Draws     int64        `json:"draws"`
+	WinRate   float64      `json:"win_rate"` // 走这手棋一方的胜率，和棋计半局
+}
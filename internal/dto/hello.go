@@ -0,0 +1,10 @@
+package dto
+
+// HelloDTO 连接建立后下发的协议版本协商结果
+type HelloDTO struct {
+	Version    int `json:"version"`
+	MinVersion int `json:"min_version"`
+	MaxVersion int `json:"max_version"`
+	// ResumeToken 匿名玩家的重连令牌，重连时与玩家 id 一起通过 ?pid=&resume= 出示；登录的连接没有
+	ResumeToken string `json:"resume_token,omitempty"`
+}
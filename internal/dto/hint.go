@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/toujourser/gomoku/pkg/engine/analysis"
+
+// HintDTO 提示结果，Candidates 按评估分从高到低排列
+type HintDTO struct {
+	RId        string               `json:"rid,omitempty"`
+	Moves      int                  `json:"moves"` // 分析时局面的手数
+	Candidates []analysis.Candidate `json:"candidates"`
+}
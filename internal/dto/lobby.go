@@ -0,0 +1,91 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/entity"
+
+// 大厅中房间的状态
+const (
+	RoomOpen    = "open"    // 挑战者座位空着
+	RoomFull    = "full"    // 双方已入座，对局未开始
+	RoomPlaying = "playing" // 对局进行中
+)
+
+// 大厅房间的排序方式
+const (
+	SortCreated    = "created"
+	SortRating     = "rating"
+	SortSpectators = "spectators"
+)
+
+// Seat 房间中一个座位上的玩家
+type Seat struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+	Color  int8   `json:"color"`
+	Ready  bool   `json:"ready"`
+}
+
+// RoomSummary 大厅列表中的房间摘要，不包含棋谱、聊天和旁观者名单
+type RoomSummary struct {
+	Id         string              `json:"id"`
+	Host       Seat                `json:"host"`
+	Challenger *Seat               `json:"challenger"` // 座位空着时为 null
+	Spectators int                 `json:"spectators"`
+	Settings   entity.RoomSettings `json:"settings"`
+	State      string              `json:"state"`
+	CreateTime string              `json:"create_time"`
+}
+
+// LobbyQuery 大厅查询条件，指针字段为 nil 时不按该条件过滤
+type LobbyQuery struct {
+	Open      *bool  `json:"open,omitempty"`       // 是否有空的挑战者座位
+	Playing   *bool  `json:"playing,omitempty"`    // 是否正在对局
+	Rated     *bool  `json:"rated,omitempty"`      // 房间规则
+	Hints     *bool  `json:"hints,omitempty"`      // 房间规则
+	Analysis  *bool  `json:"analysis,omitempty"`   // 房间规则
+	MinRating int    `json:"min_rating,omitempty"` // 房主积分下限，0 不限
+	MaxRating int    `json:"max_rating,omitempty"` // 房主积分上限，0 不限
+	Sort      string `json:"sort,omitempty"`       // created（默认）、rating 或 spectators
+	Asc       bool   `json:"asc,omitempty"`        // 默认倒序：最新、积分最高、旁观最多的在前
+	Offset    int    `json:"offset,omitempty"`
+	Limit     int    `json:"limit,omitempty"` // 0 使用默认分页大小
+}
+
+// LobbyDTO 一页大厅房间，Total 为过滤后的房间总数
+type LobbyDTO struct {
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+	Rooms  []RoomSummary `json:"rooms"`
+}
+
+// NewRoomSummary 生成房间摘要
+func NewRoomSummary(room *entity.Room) RoomSummary {
+	summary := RoomSummary{
+		Id:         room.Id,
+		Host:       newSeat(room.Host),
+		Spectators: len(room.Spectators),
+		Settings:   room.Settings,
+		State:      RoomOpen,
+		CreateTime: room.CreateTime,
+	}
+	if room.Challenger.Id != "" {
+		challenger := newSeat(room.Challenger)
+		summary.Challenger = &challenger
+		summary.State = RoomFull
+	}
+	if room.Started {
+		summary.State = RoomPlaying
+	}
+	return summary
+}
+
+func newSeat(p entity.PlayerDetails) Seat {
+	return Seat{
+		Id:     p.Id,
+		Name:   p.Name,
+		Rating: p.Rating,
+		Color:  p.Color,
+		Ready:  p.Ready,
+	}
+}
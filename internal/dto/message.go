@@ -2,15 +2,46 @@ package dto
 
 import "github.com/toujourser/gomoku/internal/constants"
 
+// Message websocket 消息信封
+// Id 为客户端自定义的请求 id，服务端在对该请求的直接回复、确认和错误中原样返回
 type Message struct {
+	Id   string      `json:"id,omitempty"`
 	Code int         `json:"code"`
 	Data interface{} `json:"data"`
 }
 
+// AckDTO 请求处理成功的确认，Code 为被确认请求的消息码
+type AckDTO struct {
+	Code int `json:"code"`
+}
+
 func NewMsg(code int, data interface{}) *Message {
 	return &Message{Code: code, Data: data}
 }
 
-func NewErrMsg(err error) *Message {
-	return NewMsg(constants.Fail, err.Error())
+// NewErrMsg 构造错误消息，lang 为客户端语言
+func NewErrMsg(err error, lang string) *Message {
+	return NewMsg(constants.Fail, NewErrDTO(err, lang))
+}
+
+// NewAckMsg 构造对请求 req 的成功确认
+func NewAckMsg(req *Message) *Message {
+	return &Message{Id: req.Id, Code: constants.Success, Data: AckDTO{Code: req.Code}}
+}
+
+// NewErrReplyMsg 构造对请求 req 的错误回复
+func NewErrReplyMsg(req *Message, err error, lang string) *Message {
+	msg := NewErrMsg(err, lang)
+	msg.Id = req.Id
+	return msg
+}
+
+// WithoutId 返回去掉请求 id 的副本，用于推送给其他会话的消息
+func (m *Message) WithoutId() *Message {
+	if m.Id == "" {
+		return m
+	}
+	msg := *m
+	msg.Id = ""
+	return &msg
 }
@@ -0,0 +1,41 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/entity"
+
+// PuzzleDTO 发送给玩家的题目，不包含答案，Moves 为本次解题双方已下的着法
+type PuzzleDTO struct {
+	Id     string         `json:"id"`
+	Title  string         `json:"title"`
+	Goal   string         `json:"goal"`
+	Depth  int            `json:"depth"`
+	ToMove string         `json:"to_move"`
+	Steps  []entity.Chess `json:"steps"`
+	Moves  []entity.Chess `json:"moves"`
+	Daily  bool           `json:"daily"`
+}
+
+func NewPuzzleDTO(p *entity.Puzzle, moves []entity.Chess, daily bool) *PuzzleDTO {
+	if moves == nil {
+		moves = make([]entity.Chess, 0)
+	}
+	return &PuzzleDTO{
+		Id:     p.Id,
+		Title:  p.Title,
+		Goal:   p.Goal,
+		Depth:  p.Depth,
+		ToMove: p.ToMove,
+		Steps:  p.Steps,
+		Moves:  moves,
+		Daily:  daily,
+	}
+}
+
+// PuzzleMoveDTO 解题落子的结果，Result 为 continue、solved 或 failed，失败时附带一个正确解
+type PuzzleMoveDTO struct {
+	Id        string         `json:"id"`
+	Move      entity.Chess   `json:"move"`
+	Reply     *entity.Chess  `json:"reply,omitempty"`
+	Result    string         `json:"result"`
+	Remaining int            `json:"remaining"` // 剩余的冲四次数
+	Solution  []entity.Chess `json:"solution,omitempty"`
+}
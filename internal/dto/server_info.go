@@ -0,0 +1,10 @@
+package dto
+
+type ServerInfoDTO struct {
+	ProtocolVersion    int    `json:"protocol_version"`
+	MinProtocolVersion int    `json:"min_protocol_version"`
+	StartTime          string `json:"start_time"`
+	Uptime             int64  `json:"uptime"` // 秒
+	Players            int    `json:"players"`
+	Rooms              int    `json:"rooms"`
+}
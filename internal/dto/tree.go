@@ -0,0 +1,10 @@
+package dto
+
+import "github.com/toujourser/gomoku/internal/entity"
+
+// TreeDTO 分析房间的变化树，Steps 为当前节点的着法序列
+type TreeDTO struct {
+	RId   string           `json:"rid"`
+	Tree  *entity.MoveTree `json:"tree"`
+	Steps []entity.Chess   `json:"steps"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+// Account 注册账号，登录后连接的玩家使用账号 id 作为玩家 id
+type Account struct {
+	Id           string `json:"id"`
+	Name         string `json:"name"`
+	PasswordHash string `json:"password_hash"`
+	Rating       int    `json:"rating"`
+	CreateTime   string `json:"create_time"`
+}
@@ -1,6 +1,6 @@
 package entity
 
-type Chess struct {
-	I int8 `json:"i"`
-	J int8 `json:"j"`
-}
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// Chess 定义在 pkg/protocol，引擎等公开包直接使用
+type Chess = protocol.Chess
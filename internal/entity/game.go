@@ -0,0 +1,14 @@
+package entity
+
+// Game 一局已结束对局的存档
+type Game struct {
+	Id        string  `json:"id"`
+	RId       string  `json:"rid"`
+	Black     Player  `json:"black"`
+	White     Player  `json:"white"`
+	Steps     []Chess `json:"steps"`
+	Winner    string  `json:"winner"` // black, white 或 draw
+	Cause     string  `json:"cause"`
+	StartTime string  `json:"start_time"`
+	EndTime   string  `json:"end_time"`
+}
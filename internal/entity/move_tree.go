@@ -0,0 +1,16 @@
+package entity
+
+// MoveTree 分析房间的变化树，节点 0 为根（空棋盘），Current 为当前所在节点
+type MoveTree struct {
+	Nodes   map[int]*TreeNode `json:"nodes"`
+	Current int               `json:"current"`
+	NextId  int               `json:"next_id"`
+}
+
+// TreeNode 变化树的一个节点，Children 中第一个为主变化
+type TreeNode struct {
+	Id       int    `json:"id"`
+	Parent   int    `json:"parent"` // 根节点为 -1
+	Move     *Chess `json:"move,omitempty"`
+	Children []int  `json:"children"`
+}
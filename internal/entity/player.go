@@ -6,4 +6,5 @@ type Player struct {
 	Status        string `json:"status"`
 	LoginTime     string `json:"login_time"`
 	MatchesPlayed int    `json:"matches_played"`
+	Rating        int    `json:"rating"`
 }
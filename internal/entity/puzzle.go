@@ -0,0 +1,20 @@
+package entity
+
+// Puzzle 题目：在 Steps 还原的局面中由 ToMove 一方行棋，达成 Goal
+type Puzzle struct {
+	Id        string  `json:"id"`
+	Title     string  `json:"title"`
+	Goal      string  `json:"goal"`  // 目前只有 vcf：Depth 次冲四以内连续冲四取胜
+	Depth     int     `json:"depth"` // 允许的冲四次数
+	ToMove    string  `json:"to_move"`
+	Steps     []Chess `json:"steps"`
+	Solution  []Chess `json:"solution"` // 导入时求解得到的一个解，不发送给答题的玩家
+	CreatedAt string  `json:"created_at"`
+}
+
+// PuzzleAttempt 玩家正在进行的解题，Moves 为双方已下的着法
+type PuzzleAttempt struct {
+	PuzzleId string  `json:"puzzle_id"`
+	Daily    bool    `json:"daily"`
+	Moves    []Chess `json:"moves"`
+}
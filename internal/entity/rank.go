@@ -0,0 +1,8 @@
+package entity
+
+// Rank 排行榜中的一项
+type Rank struct {
+	Id   string `json:"id"`   // 玩家 id
+	Name string `json:"name"` // 玩家最近一次获胜时的名字，读取时换成在线玩家的当前名字
+	Wins int    `json:"wins"`
+}
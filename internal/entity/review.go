@@ -0,0 +1,14 @@
+package entity
+
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// Review 一局存档的复盘记录，Moves 与对局的 Steps 一一对应
+type Review struct {
+	GameId    string       `json:"gid"`
+	Engine    string       `json:"engine"`
+	Moves     []MoveReview `json:"moves"`
+	CreatedAt string       `json:"created_at"`
+}
+
+// MoveReview 定义在 pkg/protocol，复盘引擎直接返回该类型
+type MoveReview = protocol.MoveReview
@@ -1,10 +1,21 @@
 package entity
 
+// RoomSettings 房间设置，由房主在创建房间时指定
+type RoomSettings struct {
+	Rated    bool `json:"rated"`    // 计分对局，对局进行中禁止提示
+	Hints    bool `json:"hints"`    // 是否允许在对局中请求提示
+	Analysis bool `json:"analysis"` // 分析房间，不进行对局，玩家在变化树上自由摆棋
+}
+
 type Room struct {
 	Id         string        `json:"id"`
+	Settings   RoomSettings  `json:"settings"`
 	Dialog     []DialogMsg   `json:"dialog"`
-	Steps      []Chess       `json:"steps"`
+	Steps      []Chess       `json:"steps"` // 分析房间中为变化树当前节点的着法序列
+	Tree       *MoveTree     `json:"tree,omitempty"`
+	CreateTime string        `json:"create_time"`
 	Started    bool          `json:"started"`
+	StartTime  string        `json:"start_time"`
 	Host       PlayerDetails `json:"host"`
 	Challenger PlayerDetails `json:"challenger"`
 	Spectators []Player      `json:"spectators"`
@@ -0,0 +1,118 @@
+package errcode
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Code 返回给客户端的机器可读错误码
+type Code string
+
+const (
+	Internal           Code = "INTERNAL"
+	InvalidParam       Code = "INVALID_PARAM"
+	UnknownMessage     Code = "UNKNOWN_MESSAGE"
+	UnsupportedVersion Code = "UNSUPPORTED_VERSION"
+	PlayerNotFound     Code = "PLAYER_NOT_FOUND"
+	NameTaken          Code = "NAME_TAKEN"
+	RoomNotFound       Code = "ROOM_NOT_FOUND"
+	RoomNoHost         Code = "ROOM_NO_HOST"
+	AlreadyInRoom      Code = "ALREADY_IN_ROOM"
+	NotInRoom          Code = "NOT_IN_ROOM"
+	NotPlaying         Code = "NOT_PLAYING"
+	InvalidRole        Code = "INVALID_ROLE"
+	GameNotStarted     Code = "GAME_NOT_STARTED"
+	NotYourTurn        Code = "NOT_YOUR_TURN"
+	OutOfBoard         Code = "OUT_OF_BOARD"
+	PositionOccupied   Code = "POSITION_OCCUPIED"
+	NothingToRetract   Code = "NOTHING_TO_RETRACT"
+	GameNotFound       Code = "GAME_NOT_FOUND"
+	HintsDisabled      Code = "HINTS_DISABLED"
+	TooManyRequests    Code = "TOO_MANY_REQUESTS"
+	ReviewNotReady     Code = "REVIEW_NOT_READY"
+	NotAnalysisRoom    Code = "NOT_ANALYSIS_ROOM"
+	AnalysisRoom       Code = "ANALYSIS_ROOM"
+	NodeNotFound       Code = "NODE_NOT_FOUND"
+	PuzzleNotFound     Code = "PUZZLE_NOT_FOUND"
+	PuzzleInvalid      Code = "PUZZLE_INVALID"
+	NoPuzzleAttempt    Code = "NO_PUZZLE_ATTEMPT"
+	RoomBusy           Code = "ROOM_BUSY"
+	AccountExists      Code = "ACCOUNT_EXISTS"
+	LoginFailed        Code = "LOGIN_FAILED"
+	Unauthorized       Code = "UNAUTHORIZED"
+	AlreadyOnline      Code = "ALREADY_ONLINE"
+)
+
+// Params 错误消息模板中的参数
+type Params map[string]interface{}
+
+// Error 带错误码的业务错误，cause 只用于服务端日志，不会发送给客户端
+type Error struct {
+	Code   Code
+	Params Params
+	cause  error
+}
+
+func New(code Code, params Params) *Error {
+	return &Error{Code: code, Params: params}
+}
+
+// Wrap 包装一个内部错误，客户端只能看到错误码对应的通用消息
+func Wrap(code Code, cause error) *Error {
+	return &Error{Code: code, cause: cause}
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("%v: %v", e.Code, e.Message(EN))
+	if e.cause != nil {
+		msg += ": " + e.cause.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// Message 返回指定语言的错误消息
+func (e *Error) Message(lang string) string {
+	tpl, ok := catalog[lang][e.Code]
+	if !ok {
+		tpl = catalog[EN][e.Code]
+	}
+	return render(tpl, e.Params)
+}
+
+// From 将任意错误转换为 *Error，未登记的错误一律视为内部错误
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return Wrap(Internal, err)
+}
+
+// Is 判断 err 是否为指定错误码
+func Is(err error, code Code) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Code == code
+}
+
+// render 将模板中的 {key} 替换为对应参数
+func render(tpl string, params Params) string {
+	if len(params) == 0 {
+		return tpl
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, "{"+k+"}", fmt.Sprint(params[k]))
+	}
+	return strings.NewReplacer(pairs...).Replace(tpl)
+}
@@ -0,0 +1,95 @@
+package errcode
+
+import "strings"
+
+const (
+	ZH = "zh"
+	EN = "en"
+)
+
+var catalog = map[string]map[Code]string{
+	ZH: {
+		Internal:           "服务器内部错误，请稍后再试",
+		InvalidParam:       "请求参数错误：{field}",
+		UnknownMessage:     "未知的消息类型 {code}",
+		UnsupportedVersion: "不支持协议版本 {version}，最低支持版本为 {min}",
+		PlayerNotFound:     "玩家不存在",
+		NameTaken:          "该用户名已存在，系统自动重置名称为 {name}",
+		RoomNotFound:       "房间不存在",
+		RoomNoHost:         "房间没有房主",
+		AlreadyInRoom:      "你已经在该房间内了哦",
+		NotInRoom:          "你不在该房间内",
+		NotPlaying:         "你没有在该房间对局",
+		InvalidRole:        "角色 {role} 不能进行该操作",
+		GameNotStarted:     "对局还未开始",
+		NotYourTurn:        "还没轮到你落子",
+		OutOfBoard:         "落子位置超出棋盘",
+		PositionOccupied:   "该位置已经有棋子了",
+		NothingToRetract:   "没有可以悔的棋",
+		GameNotFound:       "对局存档不存在",
+		HintsDisabled:      "该房间的对局中不允许使用提示",
+		TooManyRequests:    "请求过于频繁，请 {seconds} 秒后再试",
+		ReviewNotReady:     "对局复盘尚未生成，请稍后再试",
+		NotAnalysisRoom:    "房间 {rid} 不是分析房间",
+		AnalysisRoom:       "分析房间 {rid} 不能开始对局",
+		NodeNotFound:       "变化树中不存在节点 {node}",
+		PuzzleNotFound:     "题目 {id} 不存在",
+		PuzzleInvalid:      "题目无效：{reason}",
+		NoPuzzleAttempt:    "当前没有正在解答的题目",
+		RoomBusy:           "房间 {rid} 繁忙，请稍后再试",
+		AccountExists:      "账号 {name} 已被注册",
+		LoginFailed:        "用户名或密码错误",
+		Unauthorized:       "未登录或登录已过期",
+		AlreadyOnline:      "该账号已在其他连接登录",
+	},
+	EN: {
+		Internal:           "Internal server error, please try again later",
+		InvalidParam:       "Invalid request parameter: {field}",
+		UnknownMessage:     "Unknown message code {code}",
+		UnsupportedVersion: "Protocol version {version} is not supported, minimum is {min}",
+		PlayerNotFound:     "Player not found",
+		NameTaken:          "The name is already taken, you have been renamed to {name}",
+		RoomNotFound:       "Room not found",
+		RoomNoHost:         "The room has no host",
+		AlreadyInRoom:      "You are already in this room",
+		NotInRoom:          "You are not in this room",
+		NotPlaying:         "You are not playing in this room",
+		InvalidRole:        "Role {role} cannot do this",
+		GameNotStarted:     "The game has not started",
+		NotYourTurn:        "It is not your turn",
+		OutOfBoard:         "The move is outside the board",
+		PositionOccupied:   "The position is already taken",
+		NothingToRetract:   "There is no move to retract",
+		GameNotFound:       "Game not found",
+		HintsDisabled:      "Hints are disabled during games in this room",
+		TooManyRequests:    "Too many requests, please retry in {seconds} seconds",
+		ReviewNotReady:     "The game review is not ready yet, please retry later",
+		NotAnalysisRoom:    "Room {rid} is not an analysis room",
+		AnalysisRoom:       "Games cannot be started in analysis room {rid}",
+		NodeNotFound:       "Node {node} does not exist in the variation tree",
+		PuzzleNotFound:     "Puzzle {id} does not exist",
+		PuzzleInvalid:      "Invalid puzzle: {reason}",
+		NoPuzzleAttempt:    "You are not solving any puzzle",
+		RoomBusy:           "Room {rid} is busy, please retry later",
+		AccountExists:      "Account {name} is already registered",
+		LoginFailed:        "Wrong name or password",
+		Unauthorized:       "Not logged in or the login has expired",
+		AlreadyOnline:      "This account is already connected elsewhere",
+	},
+}
+
+// ParseLang 从语言参数或 Accept-Language 中选出支持的语言，默认中文
+func ParseLang(values ...string) string {
+	for _, v := range values {
+		for _, part := range strings.Split(v, ",") {
+			tag := strings.ToLower(strings.TrimSpace(strings.SplitN(part, ";", 2)[0]))
+			switch {
+			case strings.HasPrefix(tag, ZH):
+				return ZH
+			case strings.HasPrefix(tag, EN):
+				return EN
+			}
+		}
+	}
+	return ZH
+}
@@ -0,0 +1,92 @@
+package feed
+
+import (
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"sync"
+)
+
+// 每个订阅者最多缓存的事件数，消费过慢的订阅者会丢弃事件而不会阻塞对局
+const bufferSize = 64
+
+var (
+	RoomFeed *Feed
+)
+
+func init() {
+	RoomFeed = New()
+}
+
+// Event 推送给旁观订阅者的房间事件
+type Event struct {
+	Name string
+	Data interface{}
+}
+
+// 房间消息码与旁观事件名的对应关系，未列出的消息不会推送给旁观订阅者
+var eventNames = map[int]string{
+	constants.MakeStep:    "step",
+	constants.RetractStep: "retract",
+	constants.RoomChat:    "chat",
+	constants.GameOver:    "gameover",
+	constants.EnterRoom:   "room",
+	constants.LeaveRoom:   "room",
+	constants.SetReady:    "room",
+	constants.DelRoom:     "close",
+	constants.TreeMove:    "tree",
+	constants.TreeGoto:    "tree",
+	constants.TreeDelete:  "tree",
+	constants.TreeLoad:    "tree",
+}
+
+// Feed 按房间分发只读事件，供 SSE 等匿名旁观者使用
+type Feed struct {
+	mu   sync.RWMutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func New() *Feed {
+	return &Feed{subs: make(map[string]map[chan Event]struct{})}
+}
+
+// Subscribe 订阅房间 rid 的事件，返回事件通道和取消订阅函数
+func (f *Feed) Subscribe(rid string) (<-chan Event, func()) {
+	ch := make(chan Event, bufferSize)
+
+	f.mu.Lock()
+	if f.subs[rid] == nil {
+		f.subs[rid] = make(map[chan Event]struct{})
+	}
+	f.subs[rid][ch] = struct{}{}
+	f.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			f.mu.Lock()
+			delete(f.subs[rid], ch)
+			if len(f.subs[rid]) == 0 {
+				delete(f.subs, rid)
+			}
+			f.mu.Unlock()
+		})
+	}
+}
+
+// Publish 将房间消息转换为旁观事件推送给订阅者
+func (f *Feed) Publish(rid string, msg *dto.Message) {
+	name, ok := eventNames[msg.Code]
+	if !ok {
+		return
+	}
+	e := Event{Name: name, Data: msg.Data}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for ch := range f.subs[rid] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package lock
+
+import (
+	"context"
+	"sync"
+)
+
+// Keyed 按 id 区分的进程内互斥锁。
+// 每个 id 的锁在第一次使用时创建，记录持有和等待的数量，归零时立即删除，不会留下空闲的条目。
+type Keyed struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+}
+
+type entry struct {
+	ch   chan struct{} // 容量为 1，放入即持有
+	refs int           // 持有者加等待者的数量
+}
+
+func NewKeyed() *Keyed {
+	return &Keyed{entries: make(map[string]*entry)}
+}
+
+// Lock 获取 id 的锁，ctx 结束前没有拿到时返回 ctx.Err()，成功时返回释放函数
+func (k *Keyed) Lock(ctx context.Context, id string) (func(), error) {
+	// 锁空闲时 select 可能选中落子的分支，已结束的 ctx 先返回
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	k.mu.Lock()
+	e, ok := k.entries[id]
+	if !ok {
+		e = &entry{ch: make(chan struct{}, 1)}
+		k.entries[id] = e
+	}
+	e.refs++
+	k.mu.Unlock()
+
+	select {
+	case e.ch <- struct{}{}:
+	case <-ctx.Done():
+		k.release(id, e)
+		return nil, ctx.Err()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-e.ch
+			k.release(id, e)
+		})
+	}, nil
+}
+
+func (k *Keyed) release(id string, e *entry) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	e.refs--
+	if e.refs == 0 {
+		delete(k.entries, id)
+	}
+}
+
+// Len 返回当前有持有者或等待者的 id 数量
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.entries)
+}
@@ -1,62 +1,168 @@
 package lock
 
-import "sync"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync/atomic"
+	"time"
 
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/pkg/redis"
+)
+
+// ErrTimeout 在等待时间内没有拿到锁
+var ErrTimeout = errors.New("lock: timeout")
+
+// Lock 基于 Redis 的分布式锁，多个服务实例共享同一组房间时用于互斥修改。
+// 每次加锁会分配一个全局递增的 fencing token，写入时由存储层校验，
+// 锁过期后仍在执行的旧持有者写入会被拒绝。
+// 同一实例内的竞争先在进程内排队，拿到进程内的锁之后才去 Redis 抢锁。
+// 开启 Sticky 后实例会保留拿到的 Redis 租约，见 sticky.go。
 type Lock struct {
-	idLockMap map[string]*sync.RWMutex
+	prefix string
+	local  *Keyed
+	single bool  // 只有一个实例时只使用进程内的锁
+	fence  int64 // 进程内的 fencing token 计数器，single 时使用
+	sticky *sticky
 }
 
 var (
-	RoomLock Lock
+	RoomLock = NewLock("lock:room:")
 )
 
-func init() {
-	RoomLock = NewLock()
-}
+// 没有配置 lock.wait 和 lock.ttl 时的默认值
+const (
+	defaultWait = 5 * time.Second
+	defaultTTL  = 10 * time.Second
+)
 
-func NewLock() Lock {
-	return Lock{idLockMap: make(map[string]*sync.RWMutex)}
-}
+// fenceKey 全局递增的 fencing token 计数器，不设过期时间以保证单调
+const fenceKey = "lock:fence"
 
-func (l Lock) IsExist(id string) bool {
-	if _, ok := l.idLockMap[id]; !ok {
-		return false
-	}
-	return true
+func NewLock(prefix string) *Lock {
+	return &Lock{prefix: prefix, local: NewKeyed()}
 }
 
-func (l Lock) Add(id string) {
-	l.idLockMap[id] = &sync.RWMutex{}
+// NewLocalLock 只在进程内互斥的锁，用于不依赖 Redis 的单实例部署
+func NewLocalLock() *Lock {
+	return &Lock{local: NewKeyed(), single: true}
 }
 
-func (l Lock) Delete(id string) {
-	delete(l.idLockMap, id)
+// Lease 持有中的锁
+type Lease struct {
+	key     string
+	value   string
+	release func()
+	Token   int64
 }
 
-func (l Lock) Lock(id string) {
-	l.idLockMap[id].Lock()
-}
+type tokenKey struct{}
 
-func (l Lock) Unlock(id string) {
-	l.idLockMap[id].Unlock()
+// Token 返回 ctx 中持有的锁的 fencing token，没有持锁时返回 false
+func Token(ctx context.Context) (int64, bool) {
+	token, ok := ctx.Value(tokenKey{}).(int64)
+	return token, ok
 }
 
-func (l Lock) RLock(id string) {
-	l.idLockMap[id].RLock()
+// WithToken 返回携带 fencing token 的 ctx，用于在锁之外代替持有者写入，如异步落盘
+func WithToken(ctx context.Context, token int64) context.Context {
+	return context.WithValue(ctx, tokenKey{}, token)
 }
 
-func (l Lock) RUnlock(id string) {
-	l.idLockMap[id].RUnlock()
+// Lock 获取 id 的锁，最多等待 lock.wait，超时返回 ErrTimeout，锁在 lock.ttl 后自动过期。
+// 返回的 ctx 携带 fencing token，持锁期间的写操作需要使用该 ctx。
+func (l *Lock) Lock(ctx context.Context, id string) (context.Context, *Lease, error) {
+	client := redis.RedisClient
+
+	timeout := viper.GetDuration("lock.wait")
+	if timeout <= 0 {
+		timeout = defaultWait
+	}
+	wait, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	release, err := l.local.Lock(wait, id)
+	if err != nil {
+		return ctx, nil, waitErr(ctx, err)
+	}
+	if l.single {
+		token := atomic.AddInt64(&l.fence, 1)
+		return WithToken(ctx, token), &Lease{release: release, Token: token}, nil
+	}
+	if token, ok := l.reuse(id); ok {
+		return WithToken(ctx, token), &Lease{release: release, Token: token}, nil
+	}
+
+	lease := &Lease{key: l.prefix + id, value: l.leaseValue(), release: release}
+	ttl := viper.GetDuration("lock.ttl")
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	requested := false
+	for {
+		ok, err := client.SetNX(ctx, lease.key, lease.value, ttl).Result()
+		if err != nil {
+			release()
+			return ctx, nil, fmt.Errorf("error lock %v: %v", lease.key, err)
+		}
+		if ok {
+			break
+		}
+		if !requested {
+			requested = true
+			l.requestRelease(ctx, lease.key, id)
+		}
+		select {
+		case <-wait.Done():
+			release()
+			return ctx, nil, waitErr(ctx, wait.Err())
+		case <-time.After(time.Duration(10+rand.Intn(40)) * time.Millisecond):
+		}
+	}
+
+	token, err := client.Incr(ctx, fenceKey).Result()
+	if err != nil {
+		lease.Unlock()
+		return ctx, nil, fmt.Errorf("error get fencing token: %v", err)
+	}
+	lease.Token = token
+	if l.sticky != nil {
+		l.hold(id, lease)
+		return WithToken(ctx, token), &Lease{release: release, Token: token}, nil
+	}
+	return WithToken(ctx, token), lease, nil
 }
 
-func (l Lock) RLockAll() {
-	for id := range l.idLockMap {
-		l.RLock(id)
+// waitErr 区分等待超时和调用方取消
+func waitErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
 	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return err
 }
 
-func (l Lock) RUnlockAll() {
-	for id := range l.idLockMap {
-		l.RUnlock(id)
+// unlockScript 只删除自己持有的锁，锁过期后被他人获取时不做任何事
+var unlockScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Unlock 释放锁，Redis 中的锁释放失败时会在过期后自动释放。
+// 单实例和保留租约时只释放进程内的锁。
+func (l *Lease) Unlock() {
+	if l.key == "" {
+		l.release()
+		return
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_ = unlockScript.Run(ctx, redis.RedisClient, []string{l.key}, l.value).Err()
+	l.release()
 }
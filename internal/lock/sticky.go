@@ -0,0 +1,229 @@
+package lock
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"github.com/toujourser/gomoku/pkg/redis"
+)
+
+// StickyOptions 保留租约的配置。
+//
+// 开启后实例拿到的 Redis 租约在 Unlock 时不会删除，由 Keep 定期续期，
+// 同一实例后续的加锁只需要进程内的锁，也不再分配新的 fencing token。
+// 其他实例加锁时通过 Request 通知持有者，持有者调用 Release 交出租约；
+// 持有者崩溃时租约在 lock.ttl 后过期，由其他实例接手。
+type StickyOptions struct {
+	Owner   string                                     // 当前实例的 id，写入租约以便其他实例找到持有者
+	Idle    time.Duration                              // 超过 Idle 没有加锁的租约主动释放
+	Request func(owner string, id string)              // 请求实例 owner 交出 id 的租约
+	Release func(ctx context.Context, id string) error // 交出租约之前调用，ctx 携带租约的 fencing token，返回错误时保留租约
+	Lost    func(id string)                            // 续期失败，租约已过期并可能被其他实例获取
+}
+
+type sticky struct {
+	StickyOptions
+	mu   sync.Mutex
+	held map[string]*held
+}
+
+// held 保留中的租约
+type held struct {
+	key   string
+	value string
+	token int64
+	used  time.Time
+}
+
+// Sticky 开启保留租约，需要在第一次加锁之前调用
+func (l *Lock) Sticky(opts StickyOptions) {
+	l.sticky = &sticky{StickyOptions: opts, held: make(map[string]*held)}
+}
+
+// leaseValue 租约的值，保留租约时以实例 id 开头
+func (l *Lock) leaseValue() string {
+	value := uuid.NewV4().String()
+	if l.sticky != nil {
+		value = l.sticky.Owner + "/" + value
+	}
+	return value
+}
+
+// reuse 已保留 id 的租约时返回其 fencing token，调用方需持有 id 的进程内锁
+func (l *Lock) reuse(id string) (int64, bool) {
+	if l.sticky == nil {
+		return 0, false
+	}
+	l.sticky.mu.Lock()
+	defer l.sticky.mu.Unlock()
+	h, ok := l.sticky.held[id]
+	if !ok {
+		return 0, false
+	}
+	h.used = time.Now()
+	return h.token, true
+}
+
+func (l *Lock) hold(id string, lease *Lease) {
+	l.sticky.mu.Lock()
+	defer l.sticky.mu.Unlock()
+	l.sticky.held[id] = &held{key: lease.key, value: lease.value, token: lease.Token, used: time.Now()}
+}
+
+// requestRelease 租约被其他实例保留时请求其交出
+func (l *Lock) requestRelease(ctx context.Context, key string, id string) {
+	if l.sticky == nil || l.sticky.Request == nil {
+		return
+	}
+	value, err := redis.RedisClient.Get(ctx, key).Result()
+	if err != nil {
+		return
+	}
+	if owner, _, ok := strings.Cut(value, "/"); ok && owner != l.sticky.Owner {
+		l.sticky.Request(owner, id)
+	}
+}
+
+// Release 交出保留的 id 的租约，没有保留时什么也不做
+func (l *Lock) Release(ctx context.Context, id string) error {
+	if l.sticky == nil {
+		return nil
+	}
+	timeout := viper.GetDuration("lock.wait")
+	if timeout <= 0 {
+		timeout = defaultWait
+	}
+	wait, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	release, err := l.local.Lock(wait, id)
+	if err != nil {
+		return waitErr(ctx, err)
+	}
+	defer release()
+
+	l.sticky.mu.Lock()
+	h, ok := l.sticky.held[id]
+	l.sticky.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	// 回调失败时继续保留并续期租约，由下次 Release 重试
+	if l.sticky.Release != nil {
+		if err = l.sticky.Release(WithToken(ctx, h.token), id); err != nil {
+			return err
+		}
+	}
+	l.sticky.mu.Lock()
+	if l.sticky.held[id] == h {
+		delete(l.sticky.held, id)
+	}
+	l.sticky.mu.Unlock()
+	return unlockScript.Run(ctx, redis.RedisClient, []string{h.key}, h.value).Err()
+}
+
+// renewScript 只为自己持有的锁续期
+var renewScript = goredis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// Keep 每隔 lock.ttl 的三分之一为保留的租约续期，并交出空闲的租约，ctx 结束时交出全部租约后退出
+func (l *Lock) Keep(ctx context.Context) {
+	if l.sticky == nil {
+		return
+	}
+	ttl := viper.GetDuration("lock.ttl")
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			l.releaseAll()
+			return
+		case <-ticker.C:
+		}
+		l.renew(ctx, ttl)
+	}
+}
+
+func (l *Lock) renew(ctx context.Context, ttl time.Duration) {
+	l.sticky.mu.Lock()
+	all := make(map[string]held, len(l.sticky.held))
+	for id, h := range l.sticky.held {
+		all[id] = *h
+	}
+	l.sticky.mu.Unlock()
+
+	for id, h := range all {
+		if l.sticky.Idle > 0 && time.Since(h.used) > l.sticky.Idle {
+			go func(id string) {
+				if err := l.Release(context.Background(), id); err != nil {
+					logger.Error(err)
+				}
+			}(id)
+			continue
+		}
+		n, err := renewScript.Run(ctx, redis.RedisClient, []string{h.key}, h.value, ttl.Milliseconds()).Int()
+		if err != nil {
+			logger.Error(err)
+			continue
+		}
+		if n == 0 {
+			l.lose(id, h.value)
+		}
+	}
+}
+
+// Drop 写入时发现 fencing token 已过期，丢弃 token 对应的保留租约，下次加锁重新获取
+func (l *Lock) Drop(id string, token int64) {
+	if l.sticky == nil {
+		return
+	}
+	l.sticky.mu.Lock()
+	defer l.sticky.mu.Unlock()
+	if h, ok := l.sticky.held[id]; ok && h.token == token {
+		delete(l.sticky.held, id)
+	}
+}
+
+// lose 租约已被他人获取，丢弃保留的租约
+func (l *Lock) lose(id string, value string) {
+	l.sticky.mu.Lock()
+	h, ok := l.sticky.held[id]
+	if ok && h.value == value {
+		delete(l.sticky.held, id)
+	}
+	l.sticky.mu.Unlock()
+	if ok && h.value == value && l.sticky.Lost != nil {
+		logger.WithField("id", id).Warn("lease lost")
+		l.sticky.Lost(id)
+	}
+}
+
+// releaseAll 退出前交出全部租约，其他实例无需等待租约过期
+func (l *Lock) releaseAll() {
+	l.sticky.mu.Lock()
+	ids := make([]string, 0, len(l.sticky.held))
+	for id := range l.sticky.held {
+		ids = append(ids, id)
+	}
+	l.sticky.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), defaultWait)
+	defer cancel()
+	for _, id := range ids {
+		if err := l.Release(ctx, id); err != nil {
+			logger.Error(err)
+		}
+	}
+}
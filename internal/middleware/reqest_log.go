@@ -10,9 +10,12 @@ import (
 )
 
 func RequestLogger() gin.HandlerFunc {
-	mgoHook, err := mongodb.NewHooker(viper.GetString("mongodb.addr"), viper.GetString("mongodb.db"), viper.GetString("mongodb.collection"))
-	if err == nil {
-		logger.AddHook(mgoHook)
+	// 没有配置 mongodb.addr 时请求日志只写本地日志
+	if addr := viper.GetString("mongodb.addr"); addr != "" {
+		mgoHook, err := mongodb.NewHooker(addr, viper.GetString("mongodb.db"), viper.GetString("mongodb.collection"))
+		if err == nil {
+			logger.AddHook(mgoHook)
+		}
 	}
 
 	return func(c *gin.Context) {
@@ -0,0 +1,93 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/redis"
+	"time"
+)
+
+// Add 新建账号，account:name 为名字到账号 id 的索引，用 HSETNX 保证名字唯一
+func (accountStore) Add(ctx context.Context, account *entity.Account) error {
+	client := redis.RedisClient
+
+	ok, err := client.HSetNX(ctx, "account:name", account.Name, account.Id).Result()
+	if err != nil {
+		return fmt.Errorf("error add account %v: %v", account.Name, err)
+	}
+	if !ok {
+		return errcode.New(errcode.AccountExists, errcode.Params{"name": account.Name})
+	}
+	return accountStore{}.Set(ctx, account)
+}
+
+func (accountStore) Set(ctx context.Context, account *entity.Account) error {
+	client := redis.RedisClient
+
+	str, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("error marshal account: %v", err)
+	}
+	if err = client.HSet(ctx, "account", account.Id, str).Err(); err != nil {
+		return fmt.Errorf("error set account %v: %v", account.Id, err)
+	}
+	return nil
+}
+
+func (accountStore) Get(ctx context.Context, id string) (*entity.Account, error) {
+	client := redis.RedisClient
+
+	b, err := client.HGet(ctx, "account", id).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": id})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get account %v: %v", id, err)
+	}
+	a := &entity.Account{}
+	if err = json.Unmarshal([]byte(b), a); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return a, nil
+}
+
+func (accountStore) GetByName(ctx context.Context, name string) (*entity.Account, error) {
+	client := redis.RedisClient
+
+	id, err := client.HGet(ctx, "account:name", name).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": name})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get account %v: %v", name, err)
+	}
+	return accountStore{}.Get(ctx, id)
+}
+
+// AddSession 登录令牌保存在 session:<token>，由 Redis 负责过期
+func (accountStore) AddSession(ctx context.Context, token string, id string, ttl time.Duration) error {
+	client := redis.RedisClient
+
+	if err := client.Set(ctx, "session:"+token, id, ttl).Err(); err != nil {
+		return fmt.Errorf("error add session: %v", err)
+	}
+	return nil
+}
+
+func (accountStore) Session(ctx context.Context, token string) (string, error) {
+	client := redis.RedisClient
+
+	id, err := client.Get(ctx, "session:"+token).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", errcode.New(errcode.Unauthorized, nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error get session: %v", err)
+	}
+	return id, nil
+}
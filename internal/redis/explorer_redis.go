@@ -0,0 +1,44 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/pkg/redis"
+	"strconv"
+)
+
+// Add 每个局面一个 hash，字段为 "<着法序号>:<结果>"
+func (openingStore) Add(ctx context.Context, openings []store.Opening) error {
+	client := redis.RedisClient
+
+	_, err := client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		for _, o := range openings {
+			pipe.HIncrBy(ctx, "explorer:"+o.Key, fmt.Sprintf("%d:%s", o.Move, o.Winner), 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error add openings: %v", err)
+	}
+	return nil
+}
+
+func (openingStore) Get(ctx context.Context, key string) (map[string]int64, error) {
+	client := redis.RedisClient
+
+	m, err := client.HGetAll(ctx, "explorer:"+key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get openings %v: %v", key, err)
+	}
+	counts := make(map[string]int64, len(m))
+	for field, v := range m {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[field] = n
+	}
+	return counts, nil
+}
@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/redis"
+	"time"
+)
+
+// Add 存档一局对局，game:index 按结束时间排序，leaderboard 按玩家 id 记录胜局数，
+// leaderboard:name 记录玩家最近一次获胜时的名字
+func (gameStore) Add(ctx context.Context, game *entity.Game) error {
+	client := redis.RedisClient
+
+	str, err := json.Marshal(game)
+	if err != nil {
+		return fmt.Errorf("error marshal game: %v", err)
+	}
+
+	end, err := time.ParseInLocation(time.DateTime, game.EndTime, time.Local)
+	if err != nil {
+		end = time.Now()
+	}
+
+	_, err = client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, "game", game.Id, str)
+		pipe.ZAdd(ctx, "game:index", goredis.Z{Score: float64(end.Unix()), Member: game.Id})
+		var winner *entity.Player
+		switch game.Winner {
+		case "black":
+			winner = &game.Black
+		case "white":
+			winner = &game.White
+		default:
+			return nil
+		}
+		pipe.ZIncrBy(ctx, "leaderboard", 1, winner.Id)
+		pipe.HSet(ctx, "leaderboard:name", winner.Id, winner.Name)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error add game %v: %v", game.Id, err)
+	}
+
+	return nil
+}
+
+func (gameStore) Get(ctx context.Context, id string) (*entity.Game, error) {
+	client := redis.RedisClient
+
+	b, err := client.HGet(ctx, "game", id).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.GameNotFound, errcode.Params{"gid": id})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get game %v: %v", id, err)
+	}
+	g := &entity.Game{}
+	if err = json.Unmarshal([]byte(b), g); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return g, nil
+}
+
+// List 按结束时间倒序分页获取存档
+func (gameStore) List(ctx context.Context, offset int64, limit int64) (*[]entity.Game, error) {
+	client := redis.RedisClient
+
+	games := make([]entity.Game, 0, limit)
+	if limit <= 0 {
+		return &games, nil
+	}
+	ids, err := client.ZRevRange(ctx, "game:index", offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get game index: %v", err)
+	}
+	if len(ids) == 0 {
+		return &games, nil
+	}
+	bs, err := client.HMGet(ctx, "game", ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get games: %v", err)
+	}
+	for _, b := range bs {
+		str, ok := b.(string)
+		if !ok {
+			continue
+		}
+		g := &entity.Game{}
+		if err = json.Unmarshal([]byte(str), g); err != nil {
+			return nil, fmt.Errorf("unmarshal: %v", err)
+		}
+		games = append(games, *g)
+	}
+	return &games, nil
+}
+
+func (gameStore) Leaderboard(ctx context.Context, limit int64) (*[]entity.Rank, error) {
+	client := redis.RedisClient
+
+	if limit <= 0 {
+		return &[]entity.Rank{}, nil
+	}
+	zs, err := client.ZRevRangeWithScores(ctx, "leaderboard", 0, limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get leaderboard: %v", err)
+	}
+	ranks := make([]entity.Rank, 0, len(zs))
+	if len(zs) == 0 {
+		return &ranks, nil
+	}
+	ids := make([]string, 0, len(zs))
+	for _, z := range zs {
+		ids = append(ids, z.Member.(string))
+	}
+	names, err := client.HMGet(ctx, "leaderboard:name", ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get leaderboard names: %v", err)
+	}
+	for k, z := range zs {
+		name, _ := names[k].(string)
+		ranks = append(ranks, entity.Rank{Id: ids[k], Name: name, Wins: int(z.Score)})
+	}
+	return &ranks, nil
+}
@@ -5,10 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/store"
 	"github.com/toujourser/gomoku/pkg/redis"
 )
 
-func AddDialogMsg(ctx context.Context, msg *entity.DialogMsg) error {
+func (chatStore) Add(ctx context.Context, msg *entity.DialogMsg) error {
 	client := redis.RedisClient
 	str, err := json.Marshal(msg)
 	if err != nil {
@@ -25,7 +26,7 @@ func AddDialogMsg(ctx context.Context, msg *entity.DialogMsg) error {
 		return fmt.Errorf("error get dialog length: %v", err)
 	}
 
-	if length > 10 {
+	if length > store.DialogSize {
 		_, err = client.LPop(ctx, "dialog").Result()
 		if err != nil {
 			return fmt.Errorf("error left pop dialog: %v", err)
@@ -35,14 +36,14 @@ func AddDialogMsg(ctx context.Context, msg *entity.DialogMsg) error {
 	return nil
 }
 
-func GetDialog(ctx context.Context) (*[]entity.DialogMsg, error) {
+func (chatStore) List(ctx context.Context) (*[]entity.DialogMsg, error) {
 	client := redis.RedisClient
 
 	bs, err := client.LRange(ctx, "dialog", 0, -1).Result()
 	if err != nil {
 		return nil, fmt.Errorf("error get dialog: %v", err)
 	}
-	dialog := make([]entity.DialogMsg, 0, 10)
+	dialog := make([]entity.DialogMsg, 0, store.DialogSize)
 	for _, b := range bs {
 		msg := &entity.DialogMsg{}
 		if err = json.Unmarshal([]byte(b), msg); err != nil {
@@ -3,12 +3,15 @@ package redis
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
 	"github.com/toujourser/gomoku/pkg/redis"
 )
 
-func SetPlayer(ctx context.Context, player *entity.Player) error {
+func (playerStore) Set(ctx context.Context, player *entity.Player) error {
 	client := redis.RedisClient
 
 	str, err := json.Marshal(player)
@@ -24,19 +27,25 @@ func SetPlayer(ctx context.Context, player *entity.Player) error {
 	return nil
 }
 
-func GetPlayer(ctx context.Context, id string) (*entity.Player, error) {
+func (playerStore) Get(ctx context.Context, id string) (*entity.Player, error) {
 	client := redis.RedisClient
-	b, err := client.Do(ctx, "HGET", "player", id).Result()
+	b, err := client.HGet(ctx, "player", id).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": id})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get player %v: %v", id, err)
+	}
 
 	p := &entity.Player{}
-	err = json.Unmarshal([]byte(b.(string)), p)
+	err = json.Unmarshal([]byte(b), p)
 	if err != nil {
 		return nil, err
 	}
 	return p, nil
 }
 
-func GetPlayers(ctx context.Context) (*[]entity.Player, error) {
+func (playerStore) List(ctx context.Context) (*[]entity.Player, error) {
 	client := redis.RedisClient
 
 	bs, err := client.HVals(ctx, "player").Result()
@@ -55,13 +64,35 @@ func GetPlayers(ctx context.Context) (*[]entity.Player, error) {
 	return &players, nil
 }
 
-func DelPlayer(ctx context.Context, id string) error {
+func (playerStore) Del(ctx context.Context, id string) error {
 	client := redis.RedisClient
 
-	_, err := client.HDel(ctx, "player", id).Result()
+	_, err := client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HDel(ctx, "player", id)
+		pipe.HDel(ctx, "player:resume", id)
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("error delete player %v: %v", id, err)
 	}
 
 	return nil
 }
+
+func (playerStore) SetResumeToken(ctx context.Context, id string, token string) error {
+	if err := redis.RedisClient.HSet(ctx, "player:resume", id, token).Err(); err != nil {
+		return fmt.Errorf("error set resume token of %v: %v", id, err)
+	}
+	return nil
+}
+
+func (playerStore) ResumeToken(ctx context.Context, id string) (string, error) {
+	token, err := redis.RedisClient.HGet(ctx, "player:resume", id).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error get resume token of %v: %v", id, err)
+	}
+	return token, nil
+}
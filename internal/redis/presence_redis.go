@@ -0,0 +1,97 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/toujourser/gomoku/pkg/redis"
+	"time"
+)
+
+// 在线状态：presence 记录玩家所在的实例，presence:<实例> 记录实例上的所有玩家，
+// instance:<实例> 为实例的心跳，过期说明实例已经下线
+
+func (presenceStore) SetOnline(ctx context.Context, pid string, instance string) error {
+	client := redis.RedisClient
+
+	_, err := client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, "presence", pid, instance)
+		pipe.SAdd(ctx, "presence:"+instance, pid)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error set presence %v: %v", pid, err)
+	}
+	return nil
+}
+
+// setOfflineScript 只有玩家仍记录在该实例上时才删除，避免覆盖玩家在其他实例上的新连接
+var setOfflineScript = goredis.NewScript(`
+redis.call("SREM", KEYS[2], ARGV[1])
+if redis.call("HGET", KEYS[1], ARGV[1]) == ARGV[2] then
+	redis.call("HDEL", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+func (presenceStore) SetOffline(ctx context.Context, pid string, instance string) error {
+	client := redis.RedisClient
+
+	err := setOfflineScript.Run(ctx, client, []string{"presence", "presence:" + instance}, pid, instance).Err()
+	if err != nil {
+		return fmt.Errorf("error delete presence %v: %v", pid, err)
+	}
+	return nil
+}
+
+func (presenceStore) Get(ctx context.Context, pid string) (string, error) {
+	client := redis.RedisClient
+
+	instance, err := client.HGet(ctx, "presence", pid).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error get presence %v: %v", pid, err)
+	}
+	return instance, nil
+}
+
+func (presenceStore) InstancePlayers(ctx context.Context, instance string) ([]string, error) {
+	client := redis.RedisClient
+
+	pids, err := client.SMembers(ctx, "presence:"+instance).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get players of instance %v: %v", instance, err)
+	}
+	return pids, nil
+}
+
+func (presenceStore) DelInstance(ctx context.Context, instance string) error {
+	client := redis.RedisClient
+
+	if err := client.Del(ctx, "presence:"+instance).Err(); err != nil {
+		return fmt.Errorf("error delete players of instance %v: %v", instance, err)
+	}
+	return nil
+}
+
+func (presenceStore) Heartbeat(ctx context.Context, instance string, ttl time.Duration) error {
+	client := redis.RedisClient
+
+	if err := client.Set(ctx, "instance:"+instance, time.Now().Unix(), ttl).Err(); err != nil {
+		return fmt.Errorf("error heartbeat %v: %v", instance, err)
+	}
+	return nil
+}
+
+func (presenceStore) IsAlive(ctx context.Context, instance string) (bool, error) {
+	client := redis.RedisClient
+
+	n, err := client.Exists(ctx, "instance:"+instance).Result()
+	if err != nil {
+		return false, fmt.Errorf("error check instance %v: %v", instance, err)
+	}
+	return n == 1, nil
+}
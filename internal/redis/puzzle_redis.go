@@ -0,0 +1,130 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/redis"
+	"time"
+)
+
+// Add 保存题目，puzzle:index 按导入时间排序，用于列表和每日一题轮换
+func (puzzleStore) Add(ctx context.Context, puzzle *entity.Puzzle) error {
+	client := redis.RedisClient
+
+	str, err := json.Marshal(puzzle)
+	if err != nil {
+		return fmt.Errorf("error marshal puzzle: %v", err)
+	}
+	_, err = client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.HSet(ctx, "puzzle", puzzle.Id, str)
+		pipe.ZAdd(ctx, "puzzle:index", goredis.Z{Score: float64(time.Now().UnixNano()), Member: puzzle.Id})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error add puzzle %v: %v", puzzle.Id, err)
+	}
+	return nil
+}
+
+func (puzzleStore) Get(ctx context.Context, id string) (*entity.Puzzle, error) {
+	client := redis.RedisClient
+
+	b, err := client.HGet(ctx, "puzzle", id).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": id})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get puzzle %v: %v", id, err)
+	}
+	p := &entity.Puzzle{}
+	if err = json.Unmarshal([]byte(b), p); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return p, nil
+}
+
+func (puzzleStore) Ids(ctx context.Context, offset int64, limit int64) ([]string, error) {
+	client := redis.RedisClient
+
+	if limit <= 0 {
+		return []string{}, nil
+	}
+	ids, err := client.ZRange(ctx, "puzzle:index", offset, offset+limit-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error get puzzle index: %v", err)
+	}
+	return ids, nil
+}
+
+func (puzzleStore) IdAt(ctx context.Context, n int64) (string, error) {
+	client := redis.RedisClient
+
+	count, err := client.ZCard(ctx, "puzzle:index").Result()
+	if err != nil {
+		return "", fmt.Errorf("error count puzzles: %v", err)
+	}
+	if count == 0 {
+		return "", errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": "daily"})
+	}
+	ids, err := client.ZRange(ctx, "puzzle:index", n%count, n%count).Result()
+	if err != nil {
+		return "", fmt.Errorf("error get puzzle index: %v", err)
+	}
+	if len(ids) == 0 {
+		return "", errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": "daily"})
+	}
+	return ids[0], nil
+}
+
+func (puzzleStore) SetAttempt(ctx context.Context, pid string, attempt *entity.PuzzleAttempt) error {
+	client := redis.RedisClient
+
+	str, err := json.Marshal(attempt)
+	if err != nil {
+		return fmt.Errorf("error marshal puzzle attempt: %v", err)
+	}
+	if err = client.HSet(ctx, "puzzle:attempt", pid, str).Err(); err != nil {
+		return fmt.Errorf("error set puzzle attempt %v: %v", pid, err)
+	}
+	return nil
+}
+
+func (puzzleStore) GetAttempt(ctx context.Context, pid string) (*entity.PuzzleAttempt, error) {
+	client := redis.RedisClient
+
+	b, err := client.HGet(ctx, "puzzle:attempt", pid).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.NoPuzzleAttempt, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get puzzle attempt %v: %v", pid, err)
+	}
+	a := &entity.PuzzleAttempt{}
+	if err = json.Unmarshal([]byte(b), a); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return a, nil
+}
+
+func (puzzleStore) DelAttempt(ctx context.Context, pid string) error {
+	client := redis.RedisClient
+
+	if err := client.HDel(ctx, "puzzle:attempt", pid).Err(); err != nil {
+		return fmt.Errorf("error del puzzle attempt %v: %v", pid, err)
+	}
+	return nil
+}
+
+func (puzzleStore) AddSolved(ctx context.Context, name string, score float64) error {
+	client := redis.RedisClient
+
+	if err := client.ZIncrBy(ctx, "puzzle:solved", score, name).Err(); err != nil {
+		return fmt.Errorf("error add puzzle score %v: %v", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"github.com/toujourser/gomoku/pkg/redis"
+	"time"
+)
+
+// Allow 固定窗口限流，计数由所有实例共享
+func (rateLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (bool, time.Duration, error) {
+	client := redis.RedisClient
+
+	key = "rate:" + key
+	n, err := client.Incr(ctx, key).Result()
+	if err != nil {
+		return false, 0, fmt.Errorf("error incr %v: %v", key, err)
+	}
+	if n == 1 {
+		if err = client.Expire(ctx, key, window).Err(); err != nil {
+			return false, 0, fmt.Errorf("error expire %v: %v", key, err)
+		}
+	}
+	if n <= limit {
+		return true, 0, nil
+	}
+	ttl, err := client.TTL(ctx, key).Result()
+	if err != nil {
+		return false, 0, fmt.Errorf("error ttl %v: %v", key, err)
+	}
+	if ttl < 0 {
+		// 没有过期时间的计数会永久限流，补上过期时间
+		_ = client.Expire(ctx, key, window).Err()
+		ttl = window
+	}
+	return false, ttl, nil
+}
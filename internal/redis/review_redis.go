@@ -0,0 +1,42 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/redis"
+)
+
+func (reviewStore) Set(ctx context.Context, review *entity.Review) error {
+	client := redis.RedisClient
+
+	str, err := json.Marshal(review)
+	if err != nil {
+		return fmt.Errorf("error marshal review: %v", err)
+	}
+	if err = client.HSet(ctx, "review", review.GameId, str).Err(); err != nil {
+		return fmt.Errorf("error set review %v: %v", review.GameId, err)
+	}
+	return nil
+}
+
+func (reviewStore) Get(ctx context.Context, gid string) (*entity.Review, error) {
+	client := redis.RedisClient
+
+	b, err := client.HGet(ctx, "review", gid).Result()
+	if errors.Is(err, goredis.Nil) {
+		return nil, errcode.New(errcode.ReviewNotReady, errcode.Params{"gid": gid})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error get review %v: %v", gid, err)
+	}
+	r := &entity.Review{}
+	if err = json.Unmarshal([]byte(b), r); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return r, nil
+}
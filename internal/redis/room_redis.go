@@ -4,62 +4,360 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+
+	goredis "github.com/redis/go-redis/v9"
 	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/store"
 	"github.com/toujourser/gomoku/pkg/redis"
 )
 
-func SetRoom(ctx context.Context, room *entity.Room) error {
-	client := redis.RedisClient
+// 房间按字段拆分保存：
+//
+//	room:<rid>             hash，房间的基本信息，包括设置、座位和变化树
+//	room:<rid>:moves       list，对局的着法
+//	room:<rid>:chat        list，房间聊天，只保留最近的 store.DialogSize 条
+//	room:<rid>:spectators  hash，旁观者 id 到旁观者
+//	room:index             hash，房间 id 到 store.Brief，用于房间列表
+//	fence:room:<rid>       最近一次写入的 fencing token，房间删除后保留一天
+const roomIndexKey = "room:index"
+
+func roomKeys(id string) []string {
+	return []string{"room:" + id, "room:" + id + ":moves", "room:" + id + ":chat", "room:" + id + ":spectators", roomIndexKey, "fence:room:" + id}
+}
+
+// setRoomScript 按 fencing token 写入房间，只写入变化的部分：
+// 着法保留与已保存的着法相同的前缀，之后的部分截断后追加；
+// 聊天找到已保存的消息与新消息重叠的部分，只追加新的消息；旁观者只增删变化的成员。
+// token 小于最近一次写入的 token 说明锁已过期并被他人获取，拒绝写入；token 为空时不校验。
+var setRoomScript = goredis.NewScript(`
+local token = ARGV[2]
+if token ~= "" then
+	local cur = redis.call("GET", KEYS[6])
+	if cur and tonumber(cur) > tonumber(token) then
+		return 0
+	end
+	redis.call("SET", KEYS[6], token)
+end
+
+local i = 5
+local function take()
+	local n = tonumber(ARGV[i])
+	local out = {}
+	for k = 1, n do
+		out[k] = ARGV[i + k]
+	end
+	i = i + n + 1
+	return out
+end
+local meta, moves, chat, spectators = take(), take(), take(), take()
+
+redis.call("HSET", KEYS[1], unpack(meta))
+
+local old = redis.call("LRANGE", KEYS[2], 0, -1)
+local same = 0
+while same < #old and same < #moves and old[same + 1] == moves[same + 1] do
+	same = same + 1
+end
+if same < #old then
+	if same == 0 then
+		redis.call("DEL", KEYS[2])
+	else
+		redis.call("LTRIM", KEYS[2], 0, same - 1)
+	end
+end
+if same < #moves then
+	redis.call("RPUSH", KEYS[2], unpack(moves, same + 1))
+end
+
+old = redis.call("LRANGE", KEYS[3], 0, -1)
+local from = 1
+for s = 0, #old - 1 do
+	local n = #old - s
+	if n <= #chat then
+		local match = true
+		for k = 1, n do
+			if old[s + k] ~= chat[k] then
+				match = false
+				break
+			end
+		end
+		if match then
+			from = n + 1
+			break
+		end
+	end
+end
+if from == 1 and #old > 0 then
+	redis.call("DEL", KEYS[3])
+end
+if from <= #chat then
+	redis.call("RPUSH", KEYS[3], unpack(chat, from))
+	redis.call("LTRIM", KEYS[3], -tonumber(ARGV[3]), -1)
+end
+
+local want = {}
+for k = 1, #spectators, 2 do
+	want[spectators[k]] = spectators[k + 1]
+end
+local cur = redis.call("HGETALL", KEYS[4])
+for k = 1, #cur, 2 do
+	if want[cur[k]] == nil then
+		redis.call("HDEL", KEYS[4], cur[k])
+	elseif want[cur[k]] == cur[k + 1] then
+		want[cur[k]] = nil
+	end
+end
+for pid, p in pairs(want) do
+	redis.call("HSET", KEYS[4], pid, p)
+end
 
-	str, err := json.Marshal(room)
+redis.call("HSET", KEYS[5], ARGV[1], ARGV[4])
+return 1
+`)
+
+// delRoomScript 按 fencing token 删除房间，保留 token 一天，防止旧的持锁者把房间写回来
+var delRoomScript = goredis.NewScript(`
+local token = ARGV[2]
+if token ~= "" then
+	local cur = redis.call("GET", KEYS[6])
+	if cur and tonumber(cur) > tonumber(token) then
+		return 0
+	end
+	redis.call("SET", KEYS[6], token, "EX", 86400)
+else
+	redis.call("EXPIRE", KEYS[6], 86400)
+end
+redis.call("DEL", KEYS[1], KEYS[2], KEYS[3], KEYS[4])
+redis.call("HDEL", KEYS[5], ARGV[1])
+return 1
+`)
+
+// appendRoomScript 按 fencing token 在房间的列表 KEYS[2] 末尾追加 ARGV[2]，ARGV[3] 大于 0 时只保留最近的 ARGV[3] 条。
+// 房间不存在返回 -1，token 过期返回 0。
+var appendRoomScript = goredis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return -1
+end
+local token = ARGV[1]
+if token ~= "" then
+	local cur = redis.call("GET", KEYS[3])
+	if cur and tonumber(cur) > tonumber(token) then
+		return 0
+	end
+	redis.call("SET", KEYS[3], token)
+end
+redis.call("RPUSH", KEYS[2], ARGV[2])
+local limit = tonumber(ARGV[3])
+if limit > 0 then
+	redis.call("LTRIM", KEYS[2], -limit, -1)
+end
+return 1
+`)
+
+// roomArgs 生成 setRoomScript 的参数
+func roomArgs(room *entity.Room, token string) ([]interface{}, error) {
+	summary, err := json.Marshal(store.Brief(room))
 	if err != nil {
-		return fmt.Errorf("error marshal room: %v", err)
+		return nil, fmt.Errorf("error marshal room: %v", err)
+	}
+	settings, _ := json.Marshal(room.Settings)
+	host, _ := json.Marshal(room.Host)
+	challenger, _ := json.Marshal(room.Challenger)
+	tree := []byte("")
+	if room.Tree != nil {
+		if tree, err = json.Marshal(room.Tree); err != nil {
+			return nil, fmt.Errorf("error marshal tree: %v", err)
+		}
 	}
 
-	_, err = client.HSet(ctx, "room", room.Id, str).Result()
-	if err != nil {
-		return fmt.Errorf("error add player: %v", err)
+	args := []interface{}{room.Id, token, store.DialogSize, summary}
+	args = append(args, 16,
+		"id", room.Id,
+		"settings", settings,
+		"tree", tree,
+		"started", strconv.FormatBool(room.Started),
+		"create_time", room.CreateTime,
+		"start_time", room.StartTime,
+		"host", host,
+		"challenger", challenger,
+	)
+	args = append(args, len(room.Steps))
+	for _, c := range room.Steps {
+		b, _ := json.Marshal(c)
+		args = append(args, b)
+	}
+	args = append(args, len(room.Dialog))
+	for _, msg := range room.Dialog {
+		b, _ := json.Marshal(msg)
+		args = append(args, b)
+	}
+	args = append(args, 2*len(room.Spectators))
+	for _, p := range room.Spectators {
+		b, _ := json.Marshal(p)
+		args = append(args, p.Id, b)
+	}
+	return args, nil
+}
+
+// Set 保存房间，ctx 持有房间锁时校验 fencing token，锁已失效返回 RoomBusy
+func (roomStore) Set(ctx context.Context, room *entity.Room) error {
+	token := ""
+	if t, ok := lock.Token(ctx); ok {
+		token = strconv.FormatInt(t, 10)
 	}
+	return setRoom(ctx, room, token)
+}
+
+func setRoom(ctx context.Context, room *entity.Room, token string) error {
+	client := redis.RedisClient
 
+	args, err := roomArgs(room, token)
+	if err != nil {
+		return err
+	}
+	n, err := setRoomScript.Run(ctx, client, roomKeys(room.Id), args...).Int()
+	if err != nil {
+		return fmt.Errorf("error set room %v: %v", room.Id, err)
+	}
+	if n == 0 {
+		return errcode.New(errcode.RoomBusy, errcode.Params{"rid": room.Id})
+	}
 	return nil
 }
 
-func DelRoom(ctx context.Context, id string) error {
+// Del 删除房间，fencing token 校验同 Set
+func (roomStore) Del(ctx context.Context, id string) error {
 	client := redis.RedisClient
 
-	_, err := client.HDel(ctx, "room", id).Result()
+	token := ""
+	if t, ok := lock.Token(ctx); ok {
+		token = strconv.FormatInt(t, 10)
+	}
+	n, err := delRoomScript.Run(ctx, client, roomKeys(id), id, token).Int()
 	if err != nil {
 		return fmt.Errorf("error delete room %v: %v", id, err)
 	}
-
+	if n == 0 {
+		return errcode.New(errcode.RoomBusy, errcode.Params{"rid": id})
+	}
 	return nil
 }
 
-func GetRoom(ctx context.Context, id string) (*entity.Room, error) {
-	client := redis.RedisClient
+// AppendMove 只追加一步着法，fencing token 校验同 Set
+func (roomStore) AppendMove(ctx context.Context, id string, c entity.Chess) error {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error marshal chess: %v", err)
+	}
+	return appendRoom(ctx, id, 1, b, 0)
+}
 
-	b, err := client.HGet(ctx, "room", id).Result()
+// AppendChat 只追加一条聊天并截断到 store.DialogSize 条，fencing token 校验同 Set
+func (roomStore) AppendChat(ctx context.Context, id string, msg *entity.DialogMsg) error {
+	b, err := json.Marshal(msg)
 	if err != nil {
-		err = fmt.Errorf("redis: room with id '%v' not found", id)
-		return nil, err
+		return fmt.Errorf("error marshal dialog: %v", err)
+	}
+	return appendRoom(ctx, id, 2, b, store.DialogSize)
+}
+
+// appendRoom 在 roomKeys(id)[list] 对应的列表末尾追加 value
+func appendRoom(ctx context.Context, id string, list int, value []byte, limit int) error {
+	client := redis.RedisClient
+
+	token := ""
+	if t, ok := lock.Token(ctx); ok {
+		token = strconv.FormatInt(t, 10)
 	}
-	r := &entity.Room{}
-	err = json.Unmarshal([]byte(b), r)
+	keys := roomKeys(id)
+	n, err := appendRoomScript.Run(ctx, client, []string{keys[0], keys[list], keys[5]}, token, value, limit).Int()
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("error update room %v: %v", id, err)
 	}
+	switch n {
+	case -1:
+		return errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+	case 0:
+		return errcode.New(errcode.RoomBusy, errcode.Params{"rid": id})
+	}
+	return nil
+}
+
+func (roomStore) Get(ctx context.Context, id string) (*entity.Room, error) {
+	client := redis.RedisClient
 
+	keys := roomKeys(id)
+	pipe := client.Pipeline()
+	metaCmd := pipe.HGetAll(ctx, keys[0])
+	movesCmd := pipe.LRange(ctx, keys[1], 0, -1)
+	chatCmd := pipe.LRange(ctx, keys[2], 0, -1)
+	spectatorsCmd := pipe.HVals(ctx, keys[3])
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("error get room %v: %v", id, err)
+	}
+	meta := metaCmd.Val()
+	if len(meta) == 0 {
+		return nil, errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+	}
+
+	r := &entity.Room{
+		Id:         meta["id"],
+		CreateTime: meta["create_time"],
+		StartTime:  meta["start_time"],
+		Steps:      make([]entity.Chess, 0, len(movesCmd.Val())),
+		Dialog:     make([]entity.DialogMsg, 0, len(chatCmd.Val())),
+		Spectators: make([]entity.Player, 0, len(spectatorsCmd.Val())),
+	}
+	r.Started, _ = strconv.ParseBool(meta["started"])
+	for field, v := range map[string]interface{}{"settings": &r.Settings, "host": &r.Host, "challenger": &r.Challenger} {
+		if err := json.Unmarshal([]byte(meta[field]), v); err != nil {
+			return nil, fmt.Errorf("unmarshal room %v %v: %v", id, field, err)
+		}
+	}
+	if meta["tree"] != "" {
+		r.Tree = &entity.MoveTree{}
+		if err := json.Unmarshal([]byte(meta["tree"]), r.Tree); err != nil {
+			return nil, fmt.Errorf("unmarshal room %v tree: %v", id, err)
+		}
+	}
+	for _, b := range movesCmd.Val() {
+		c := entity.Chess{}
+		if err := json.Unmarshal([]byte(b), &c); err != nil {
+			return nil, fmt.Errorf("unmarshal: %v", err)
+		}
+		r.Steps = append(r.Steps, c)
+	}
+	for _, b := range chatCmd.Val() {
+		msg := entity.DialogMsg{}
+		if err := json.Unmarshal([]byte(b), &msg); err != nil {
+			return nil, fmt.Errorf("unmarshal: %v", err)
+		}
+		r.Dialog = append(r.Dialog, msg)
+	}
+	for _, b := range spectatorsCmd.Val() {
+		p := entity.Player{}
+		if err := json.Unmarshal([]byte(b), &p); err != nil {
+			return nil, fmt.Errorf("unmarshal: %v", err)
+		}
+		r.Spectators = append(r.Spectators, p)
+	}
+	sort.Slice(r.Spectators, func(x, y int) bool { return r.Spectators[x].Id < r.Spectators[y].Id })
 	return r, nil
 }
 
-func GetRooms(ctx context.Context) (*[]entity.Room, error) {
+// List 从 room:index 读取全部房间
+func (roomStore) List(ctx context.Context) (*[]entity.Room, error) {
 	client := redis.RedisClient
 
-	bs, err := client.HVals(ctx, "room").Result()
+	bs, err := client.HVals(ctx, roomIndexKey).Result()
 	if err != nil {
 		return nil, err
 	}
-	rooms := make([]entity.Room, 0, 100)
+	rooms := make([]entity.Room, 0, len(bs))
 	for _, b := range bs {
 		r := &entity.Room{}
 		err = json.Unmarshal([]byte(b), r)
@@ -70,3 +368,27 @@ func GetRooms(ctx context.Context) (*[]entity.Room, error) {
 	}
 	return &rooms, nil
 }
+
+// MigrateRooms 把旧格式中整个房间保存在 room hash 一个字段里的房间迁移为按字段拆分的格式，返回迁移的房间数。
+// 迁移可以重复执行；迁移期间不能有旧版本的实例在写入房间。
+func MigrateRooms(ctx context.Context) (int, error) {
+	client := redis.RedisClient
+
+	old, err := client.HGetAll(ctx, "room").Result()
+	if err != nil {
+		return 0, fmt.Errorf("error get rooms: %v", err)
+	}
+	for id, b := range old {
+		r := &entity.Room{}
+		if err = json.Unmarshal([]byte(b), r); err != nil {
+			return 0, fmt.Errorf("unmarshal room %v: %v", id, err)
+		}
+		if err = setRoom(ctx, r, ""); err != nil {
+			return 0, err
+		}
+		if err = client.HDel(ctx, "room", id).Err(); err != nil {
+			return 0, fmt.Errorf("error delete room %v: %v", id, err)
+		}
+	}
+	return len(old), nil
+}
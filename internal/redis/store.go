@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/store"
+)
+
+type (
+	playerStore   struct{}
+	roomStore     struct{}
+	chatStore     struct{}
+	gameStore     struct{}
+	accountStore  struct{}
+	reviewStore   struct{}
+	openingStore  struct{}
+	puzzleStore   struct{}
+	rateLimiter   struct{}
+	presenceStore struct{}
+)
+
+// NewStores 基于 Redis 的存储，多个实例可以共享，房间使用分布式锁
+func NewStores() store.Stores {
+	return store.Stores{
+		Players:  playerStore{},
+		Rooms:    roomStore{},
+		Chat:     chatStore{},
+		Games:    gameStore{},
+		Accounts: accountStore{},
+		Reviews:  reviewStore{},
+		Openings: openingStore{},
+		Puzzles:  puzzleStore{},
+		Limiter:  rateLimiter{},
+		Presence: presenceStore{},
+		RoomLock: lock.RoomLock,
+		Shared:   true,
+	}
+}
@@ -0,0 +1,51 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"net/http"
+	"strings"
+)
+
+// RegisterAccount 注册账号，body 为 {"name": "", "password": ""}
+func RegisterAccount(c *gin.Context) {
+	req := dto.CredentialsDTO{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errcode.New(errcode.InvalidParam, errcode.Params{"field": "body"}))
+		return
+	}
+	a, err := service.Register(c, req.Name, req.Password)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dto.NewAccountDTO(a))
+}
+
+// Login 登录并返回令牌，令牌用于 Authorization: Bearer 和 websocket 连接的 ?token=
+func Login(c *gin.Context) {
+	req := dto.CredentialsDTO{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		fail(c, errcode.New(errcode.InvalidParam, errcode.Params{"field": "body"}))
+		return
+	}
+	login, err := service.Login(c, req.Name, req.Password)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, login)
+}
+
+// Me 返回 Authorization: Bearer 令牌对应的账号
+func Me(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	a, err := service.Authenticate(c, token)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, dto.NewAccountDTO(a))
+}
@@ -0,0 +1,172 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/pkg/engine"
+)
+
+const maxPageSize = 100
+
+func GetServerInfo(c *gin.Context) {
+	info, err := service.GetServerInfo(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, info)
+}
+
+func GetPlayers(c *gin.Context) {
+	players, err := service.GetPlayers(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, players)
+}
+
+func GetPlayer(c *gin.Context) {
+	player, err := service.GetPlayer(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, player)
+}
+
+// GetRooms 分页获取大厅房间摘要，
+// ?open=true&playing=false&rated=true&hints=&analysis=&min_rating=1400&max_rating=1800&sort=rating&asc=false&offset=0&limit=20
+func GetRooms(c *gin.Context) {
+	q, err := lobbyQuery(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	lobby, err := service.Lobby(c, q)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, lobby)
+}
+
+func lobbyQuery(c *gin.Context) (*dto.LobbyQuery, error) {
+	q := &dto.LobbyQuery{Sort: c.Query("sort")}
+	var err error
+	for key, v := range map[string]**bool{
+		"open":     &q.Open,
+		"playing":  &q.Playing,
+		"rated":    &q.Rated,
+		"hints":    &q.Hints,
+		"analysis": &q.Analysis,
+	} {
+		if *v, err = queryBool(c, key); err != nil {
+			return nil, err
+		}
+	}
+	if asc, err := queryBool(c, "asc"); err != nil {
+		return nil, err
+	} else if asc != nil {
+		q.Asc = *asc
+	}
+	for key, v := range map[string]*int{
+		"min_rating": &q.MinRating,
+		"max_rating": &q.MaxRating,
+		"offset":     &q.Offset,
+		"limit":      &q.Limit,
+	} {
+		n, err := queryInt(c, key, 0)
+		if err != nil {
+			return nil, err
+		}
+		*v = int(n)
+	}
+	return q, nil
+}
+
+func GetRoom(c *gin.Context) {
+	room, err := service.GetRoom(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, room)
+}
+
+// GetGames 分页获取对局存档，?offset=0&limit=20
+func GetGames(c *gin.Context) {
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	limit, err := queryInt(c, "limit", 20)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	games, err := service.GetGames(c, offset, limit)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, games)
+}
+
+func GetGame(c *gin.Context) {
+	game, err := service.GetGame(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, game)
+}
+
+// GetReview 获取对局复盘，尚未生成时返回 202 并提交复盘
+func GetReview(c *gin.Context) {
+	review, err := service.GetReview(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, review)
+}
+
+// GetLeaderboard 按胜局数排行，?limit=20
+func GetLeaderboard(c *gin.Context) {
+	limit, err := queryInt(c, "limit", 20)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	ranks, err := service.GetLeaderboard(c, limit)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, ranks)
+}
+
+// Explore 开局浏览器，?moves=h8,i9 为开局着法
+func Explore(c *gin.Context) {
+	steps, err := engine.ParseMoves(c.Query("moves"))
+	if err != nil {
+		fail(c, errcode.New(errcode.InvalidParam, errcode.Params{"field": "moves"}))
+		return
+	}
+	result, err := service.Explore(c, steps)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, result)
+}
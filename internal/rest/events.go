@@ -0,0 +1,48 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/feed"
+	"github.com/toujourser/gomoku/internal/service"
+	"io"
+	"time"
+)
+
+const keepAliveInterval = 15 * time.Second
+
+// RoomEvents 以 Server-Sent Events 推送房间的旁观事件。
+// 连接建立后先发送 snapshot 事件（完整房间状态），之后推送 step、retract、chat、gameover、room 事件，
+// 房间解散时发送 close 事件并结束。旁观者无需建立 websocket，也不会出现在玩家列表中。
+func RoomEvents(c *gin.Context) {
+	rid := c.Param("id")
+
+	// 先订阅再读取快照，避免漏掉两者之间发生的事件
+	events, cancel := feed.RoomFeed.Subscribe(rid)
+	defer cancel()
+
+	room, err := service.GetRoom(c, rid)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("snapshot", room)
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(keepAliveInterval)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case e := <-events:
+			c.SSEvent(e.Name, e.Data)
+			return e.Name != "close"
+		case <-ticker.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package rest
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/render"
+	"net/http"
+)
+
+// GameBoardPNG 对局棋盘图片，?ply=n 为第 n 手之后的局面，默认终局；?numbers=0 不标手数；?cell=32 格子像素
+func GameBoardPNG(c *gin.Context) {
+	game, err := service.GetGame(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ply, err := queryInt(c, "ply", int64(len(game.Steps)))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if ply > int64(len(game.Steps)) {
+		ply = int64(len(game.Steps))
+	}
+	writePNG(c, game.Steps[:ply])
+}
+
+// GameReplayGIF 对局回放动画，?delay=80 为每帧间隔（百分之一秒）
+func GameReplayGIF(c *gin.Context) {
+	game, err := service.GetGame(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	opts, err := renderOptions(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	delay, err := queryInt(c, "delay", 80)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	opts.Delay = int(delay)
+
+	buf := &bytes.Buffer{}
+	if err = render.GIF(buf, game.Steps, opts); err != nil {
+		fail(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "image/gif", buf.Bytes())
+}
+
+// BoardPNG 任意局面的棋盘图片，?moves=h8,i9
+func BoardPNG(c *gin.Context) {
+	steps, err := engine.ParseMoves(c.Query("moves"))
+	if err == nil {
+		_, err = engine.NewBoard(steps)
+	}
+	if err != nil {
+		fail(c, errcode.New(errcode.InvalidParam, errcode.Params{"field": "moves"}))
+		return
+	}
+	writePNG(c, steps)
+}
+
+func writePNG(c *gin.Context, steps []entity.Chess) {
+	opts, err := renderOptions(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err = render.PNG(buf, steps, opts); err != nil {
+		fail(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
+
+// renderOptions 读取 numbers 和 cell 查询参数，cell 限制在 8 到 64 之间
+func renderOptions(c *gin.Context) (render.Options, error) {
+	numbers, err := queryInt(c, "numbers", 1)
+	if err != nil {
+		return render.Options{}, err
+	}
+	cell, err := queryInt(c, "cell", 32)
+	if err != nil || cell < 8 || cell > 64 {
+		return render.Options{}, errcode.New(errcode.InvalidParam, errcode.Params{"field": "cell"})
+	}
+	return render.Options{Cell: int(cell), Numbers: numbers != 0}, nil
+}
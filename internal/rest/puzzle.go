@@ -0,0 +1,60 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"io"
+)
+
+// maxPuzzleSize 导入题目文本的最大字节数
+const maxPuzzleSize = 64 << 10
+
+// GetPuzzles 按导入顺序分页获取题目，?offset=0&limit=20
+func GetPuzzles(c *gin.Context) {
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	limit, err := queryInt(c, "limit", 20)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	puzzles, err := service.GetPuzzles(c, offset, limit)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, puzzles)
+}
+
+// GetPuzzle 获取题目，/puzzles/daily 为每日一题
+func GetPuzzle(c *gin.Context) {
+	p, err := service.GetPuzzle(c, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, p)
+}
+
+// ImportPuzzle 导入请求体中文本格式的题目
+func ImportPuzzle(c *gin.Context) {
+	text, err := io.ReadAll(io.LimitReader(c.Request.Body, maxPuzzleSize))
+	if err != nil {
+		fail(c, errcode.New(errcode.InvalidParam, errcode.Params{"field": "body"}))
+		return
+	}
+	p, err := service.ImportPuzzle(c, string(text))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	ok(c, dto.NewPuzzleDTO(p, nil, false))
+}
@@ -0,0 +1,83 @@
+package rest
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"net/http"
+	"strconv"
+)
+
+// Register 在 router 上注册 /api 下的 HTTP 接口
+func Register(router gin.IRouter) {
+	api := router.Group("/api")
+	api.GET("/server", GetServerInfo)
+	api.POST("/register", RegisterAccount)
+	api.POST("/login", Login)
+	api.GET("/me", Me)
+	api.GET("/players", GetPlayers)
+	api.GET("/players/:id", GetPlayer)
+	api.GET("/rooms", GetRooms)
+	api.GET("/rooms/:id", GetRoom)
+	api.GET("/rooms/:id/events", RoomEvents)
+	api.GET("/games", GetGames)
+	api.GET("/games/:id", GetGame)
+	api.GET("/games/:id/review", GetReview)
+	api.GET("/games/:id/board.png", GameBoardPNG)
+	api.GET("/games/:id/replay.gif", GameReplayGIF)
+	api.GET("/board.png", BoardPNG)
+	api.GET("/leaderboard", GetLeaderboard)
+	api.GET("/puzzles", GetPuzzles)
+	api.POST("/puzzles", ImportPuzzle)
+	api.GET("/puzzles/:id", GetPuzzle)
+	api.GET("/explorer", Explore)
+}
+
+func ok(c *gin.Context, data interface{}) {
+	c.JSON(http.StatusOK, data)
+}
+
+// fail 按错误码返回对应的 HTTP 状态码和本地化错误
+func fail(c *gin.Context, err error) {
+	e := errcode.From(err)
+	status := http.StatusBadRequest
+	switch e.Code {
+	case errcode.Internal:
+		status = http.StatusInternalServerError
+	case errcode.PlayerNotFound, errcode.RoomNotFound, errcode.GameNotFound, errcode.PuzzleNotFound:
+		status = http.StatusNotFound
+	case errcode.ReviewNotReady:
+		status = http.StatusAccepted
+	case errcode.LoginFailed, errcode.Unauthorized:
+		status = http.StatusUnauthorized
+	case errcode.AccountExists:
+		status = http.StatusConflict
+	}
+	c.AbortWithStatusJSON(status, dto.NewErrDTO(e, errcode.ParseLang(c.Query("lang"), c.GetHeader("Accept-Language"))))
+}
+
+// queryInt 读取非负整数查询参数，缺省时返回 def
+func queryInt(c *gin.Context, key string, def int64) (int64, error) {
+	v := c.Query(key)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errcode.New(errcode.InvalidParam, errcode.Params{"field": key})
+	}
+	return n, nil
+}
+
+// queryBool 读取布尔查询参数，未提供时返回 nil
+func queryBool(c *gin.Context, key string) (*bool, error) {
+	v := c.Query(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": key})
+	}
+	return &b, nil
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	uuid "github.com/satori/go.uuid"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"golang.org/x/crypto/bcrypt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxAccountName    = 20
+	minPasswordLength = 6
+	maxPasswordLength = 72 // bcrypt 只使用前 72 个字节
+	defaultSessionTTL = 24 * time.Hour
+)
+
+// Register 注册账号，密码以 bcrypt 哈希保存
+func Register(ctx context.Context, name string, password string) (*entity.Account, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAccountName {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "name"})
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "password"})
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	a := &entity.Account{
+		Id:           uuid.NewV4().String(),
+		Name:         name,
+		PasswordHash: string(hash),
+		Rating:       DefaultRating,
+		CreateTime:   time.Now().Format(time.DateTime),
+	}
+	if err = stores.Accounts.Add(ctx, a); err != nil {
+		return nil, err
+	}
+	logger.WithField("account", a.Id).Info("account registered")
+	return a, nil
+}
+
+// Login 校验密码并签发登录令牌，令牌的有效期为 account.session_ttl
+func Login(ctx context.Context, name string, password string) (*dto.LoginDTO, error) {
+	a, err := stores.Accounts.GetByName(ctx, strings.TrimSpace(name))
+	if errcode.Is(err, errcode.PlayerNotFound) {
+		return nil, errcode.New(errcode.LoginFailed, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password)) != nil {
+		return nil, errcode.New(errcode.LoginFailed, nil)
+	}
+
+	ttl := viper.GetDuration("account.session_ttl")
+	if ttl <= 0 {
+		ttl = defaultSessionTTL
+	}
+	token := newToken()
+	if err = stores.Accounts.AddSession(ctx, token, a.Id, ttl); err != nil {
+		return nil, err
+	}
+	return &dto.LoginDTO{
+		Token:      token,
+		ExpireTime: time.Now().Add(ttl).Format(time.DateTime),
+		Account:    dto.NewAccountDTO(a),
+	}, nil
+}
+
+// Authenticate 返回登录令牌对应的账号，令牌无效时返回 Unauthorized
+func Authenticate(ctx context.Context, token string) (*entity.Account, error) {
+	if token == "" {
+		return nil, errcode.New(errcode.Unauthorized, nil)
+	}
+	id, err := stores.Accounts.Session(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	a, err := stores.Accounts.Get(ctx, id)
+	if errcode.Is(err, errcode.PlayerNotFound) {
+		return nil, errcode.New(errcode.Unauthorized, nil)
+	}
+	return a, err
+}
+
+// AccountConnect 登录的连接以账号 id 作为玩家 id，名字和积分取自账号。
+// 服务端重启后可以回到原来的对局，此时 resumed 为 true；账号已在其他连接在线时返回 AlreadyOnline
+func AccountConnect(ctx context.Context, a *entity.Account) (p *entity.Player, room *entity.Room, resumed bool, err error) {
+	// 登录令牌已经证明了身份，不需要重连令牌
+	if p, room, ok := resumePlayer(ctx, a.Id); ok {
+		return p, room, true, nil
+	}
+	if _, err = stores.Players.Get(ctx, a.Id); err == nil {
+		return nil, nil, false, errcode.New(errcode.AlreadyOnline, nil)
+	}
+	if p, err = NewPlayerConnect(ctx, a.Id); err != nil {
+		return nil, nil, false, err
+	}
+	p.Name = a.Name
+	p.Rating = a.Rating
+	if err = stores.Players.Set(ctx, p); err != nil {
+		logger.Error(err)
+		return nil, nil, false, err
+	}
+	return p, nil, false, nil
+}
+
+// newToken 生成随机令牌
+func newToken() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
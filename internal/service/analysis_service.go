@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/logger"
+)
+
+// NewMoveTree 创建只有根节点的变化树
+func NewMoveTree() *entity.MoveTree {
+	return &entity.MoveTree{
+		Nodes:  map[int]*entity.TreeNode{0: {Id: 0, Parent: -1, Children: make([]int, 0)}},
+		NextId: 1,
+	}
+}
+
+// TreeMove 在当前节点下落子，已有相同着法的分支时直接进入该分支
+func TreeMove(ctx context.Context, pid string, rid string, c entity.Chess) (*entity.Room, error) {
+	return updateTree(ctx, pid, rid, func(room *entity.Room) error {
+		tree := room.Tree
+		if c.I < 0 || c.J < 0 || c.I >= constants.BoardSize || c.J >= constants.BoardSize {
+			return errcode.New(errcode.OutOfBoard, errcode.Params{"i": c.I, "j": c.J})
+		}
+		for _, s := range room.Steps {
+			if s == c {
+				return errcode.New(errcode.PositionOccupied, errcode.Params{"i": c.I, "j": c.J})
+			}
+		}
+
+		cur := tree.Nodes[tree.Current]
+		for _, id := range cur.Children {
+			if *tree.Nodes[id].Move == c {
+				tree.Current = id
+				return nil
+			}
+		}
+		tree.Current = addNode(tree, cur.Id, c)
+		return nil
+	})
+}
+
+// TreeGoto 跳转到变化树的节点
+func TreeGoto(ctx context.Context, pid string, rid string, node int) (*entity.Room, error) {
+	return updateTree(ctx, pid, rid, func(room *entity.Room) error {
+		if _, ok := room.Tree.Nodes[node]; !ok {
+			return errcode.New(errcode.NodeNotFound, errcode.Params{"node": node})
+		}
+		room.Tree.Current = node
+		return nil
+	})
+}
+
+// TreeDelete 删除节点及其所有后续变化，当前节点被删除时回到被删节点的父节点
+func TreeDelete(ctx context.Context, pid string, rid string, node int) (*entity.Room, error) {
+	return updateTree(ctx, pid, rid, func(room *entity.Room) error {
+		tree := room.Tree
+		n, ok := tree.Nodes[node]
+		if !ok || node == 0 {
+			return errcode.New(errcode.NodeNotFound, errcode.Params{"node": node})
+		}
+
+		parent := tree.Nodes[n.Parent]
+		for k, id := range parent.Children {
+			if id == node {
+				parent.Children = append(parent.Children[:k], parent.Children[k+1:]...)
+				break
+			}
+		}
+		for cur := tree.Current; cur != -1; cur = tree.Nodes[cur].Parent {
+			if cur == node {
+				tree.Current = n.Parent
+				break
+			}
+		}
+		removeSubtree(tree, node)
+		return nil
+	})
+}
+
+// TreeLoad 用存档对局替换变化树，对局着法作为主变化，当前节点为最后一手。
+// 存档的着法不合法（出界或重复落子）时不替换。
+func TreeLoad(ctx context.Context, pid string, rid string, gid string) (*entity.Room, error) {
+	game, err := stores.Games.Get(ctx, gid)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if _, err = engine.NewBoard(game.Steps); err != nil {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "steps"})
+	}
+	return updateTree(ctx, pid, rid, func(room *entity.Room) error {
+		tree := NewMoveTree()
+		for _, c := range game.Steps {
+			tree.Current = addNode(tree, tree.Current, c)
+		}
+		room.Tree = tree
+		return nil
+	})
+}
+
+// updateTree 在房间锁内修改分析房间的变化树，并将 Steps 同步为当前节点的着法序列。
+// 房间内的所有人，包括旁观者都可以修改，修改后的变化树通过房间消息实时推送给所有人。
+func updateTree(ctx context.Context, pid string, rid string, fn func(room *entity.Room) error) (*entity.Room, error) {
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Unlock()
+
+	room, err := stores.Rooms.Get(ctx, rid)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	if room.Tree == nil {
+		return nil, errcode.New(errcode.NotAnalysisRoom, errcode.Params{"rid": rid})
+	}
+	if inRoom, _, _ := isInRoom(pid, room); !inRoom {
+		return nil, errcode.New(errcode.NotInRoom, errcode.Params{"pid": pid, "rid": rid})
+	}
+
+	if err = fn(room); err != nil {
+		return nil, err
+	}
+	room.Steps = treePath(room.Tree, room.Tree.Current)
+
+	if err = stores.Rooms.Set(ctx, room); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	return room, nil
+}
+
+func addNode(tree *entity.MoveTree, parent int, c entity.Chess) int {
+	id := tree.NextId
+	tree.NextId++
+	move := c
+	tree.Nodes[id] = &entity.TreeNode{Id: id, Parent: parent, Move: &move, Children: make([]int, 0)}
+	tree.Nodes[parent].Children = append(tree.Nodes[parent].Children, id)
+	return id
+}
+
+func removeSubtree(tree *entity.MoveTree, id int) {
+	for _, child := range tree.Nodes[id].Children {
+		removeSubtree(tree, child)
+	}
+	delete(tree.Nodes, id)
+}
+
+// treePath 返回从根到节点 id 的着法序列
+func treePath(tree *entity.MoveTree, id int) []entity.Chess {
+	steps := make([]entity.Chess, 0)
+	for cur := tree.Nodes[id]; cur.Move != nil; cur = tree.Nodes[cur.Parent] {
+		steps = append(steps, *cur.Move)
+	}
+	for l, r := 0, len(steps)-1; l < r; l, r = l+1, r-1 {
+		steps[l], steps[r] = steps[r], steps[l]
+	}
+	return steps
+}
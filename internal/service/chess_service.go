@@ -2,21 +2,23 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"github.com/toujourser/gomoku/internal/constants"
 	"github.com/toujourser/gomoku/internal/dto"
 	"github.com/toujourser/gomoku/internal/entity"
-	"github.com/toujourser/gomoku/internal/lock"
-	"github.com/toujourser/gomoku/internal/redis"
+	"github.com/toujourser/gomoku/internal/errcode"
 	"github.com/toujourser/gomoku/internal/util"
 	"github.com/toujourser/gomoku/pkg/logger"
+	"time"
 )
 
 func SetReady(ctx context.Context, rid string, pid string, ready bool) (*entity.Room, error) {
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Unlock()
 
-	room, err := redis.GetRoom(ctx, rid)
+	room, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
@@ -25,26 +27,30 @@ func SetReady(ctx context.Context, rid string, pid string, ready bool) (*entity.
 	inRoom, role, _ := isInRoom(pid, room)
 
 	if !inRoom {
-		err = fmt.Errorf("error: Player %v not in room %v", pid, rid)
+		err = errcode.New(errcode.NotInRoom, errcode.Params{"pid": pid, "rid": rid})
 		logger.Error(err)
 		return nil, err
 	}
+	if room.Settings.Analysis {
+		return nil, errcode.New(errcode.AnalysisRoom, errcode.Params{"rid": rid})
+	}
 
 	if role == "host" {
 		room.Host.Ready = ready
 	} else if role == "challenger" {
 		room.Challenger.Ready = ready
 	} else {
-		err = fmt.Errorf("error: Role %v cannot get ready", role)
+		err = errcode.New(errcode.InvalidRole, errcode.Params{"role": role})
 		logger.Error(err)
 		return nil, err
 	}
 	room.Started = room.Host.Ready && room.Challenger.Ready
 	if room.Started {
 		room.Steps = make([]entity.Chess, 0)
+		room.StartTime = time.Now().Format(time.DateTime)
 	}
 
-	if err = redis.SetRoom(ctx, room); err != nil {
+	if err = stores.Rooms.Set(ctx, room); err != nil {
 		logger.Error(err)
 		return nil, err
 	}
@@ -52,35 +58,69 @@ func SetReady(ctx context.Context, rid string, pid string, ready bool) (*entity.
 	return room, nil
 }
 
-func MakeStep(ctx context.Context, rid string, c entity.Chess) (bool, *dto.GameOverDTO, *entity.Room, error) {
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
+func MakeStep(ctx context.Context, pid string, rid string, c entity.Chess) (bool, *dto.GameOverDTO, *entity.Room, error) {
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return false, nil, nil, err
+	}
+	defer lease.Unlock()
 
-	room, err := redis.GetRoom(ctx, rid)
+	room, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
 		return false, nil, nil, err
 	}
-	if room.Started {
-		room.Steps = append(room.Steps, c)
-	} else {
-		err = fmt.Errorf("error: Can not make step while game is not started")
+	if err = checkStep(pid, room, c); err != nil {
 		logger.Error(err)
 		return false, nil, nil, err
 	}
+	room.Steps = append(room.Steps, c)
 
 	over, gameOverDTO, err := CheckFive(room)
 	if err != nil {
 		logger.Error(err)
 		return false, nil, nil, err
 	}
-	if err = redis.SetRoom(ctx, room); err != nil {
+	// 对局未结束时只追加着法，结束时积分等也会变化，写入整个房间
+	if over {
+		ArchiveGame(ctx, room, gameOverDTO)
+		err = stores.Rooms.Set(ctx, room)
+	} else {
+		err = stores.Rooms.AppendMove(ctx, rid, c)
+	}
+	if err != nil {
 		logger.Error(err)
 		return false, nil, nil, err
 	}
 	return over, gameOverDTO, room, nil
 }
 
+// checkStep 检查玩家 pid 能否在房间 room 中落子 c
+func checkStep(pid string, room *entity.Room, c entity.Chess) error {
+	if !room.Started {
+		return errcode.New(errcode.GameNotStarted, errcode.Params{"rid": room.Id})
+	}
+	inRoom, role, _ := isInRoom(pid, room)
+	if !inRoom || role == "spectator" {
+		return errcode.New(errcode.NotPlaying, errcode.Params{"pid": pid, "rid": room.Id})
+	}
+	color := room.Host.Color
+	if role == "challenger" {
+		color = room.Challenger.Color
+	}
+	// 黑方先行，轮到的颜色由已落子数的奇偶决定
+	if int8(len(room.Steps)%2) != color {
+		return errcode.New(errcode.NotYourTurn, nil)
+	}
+	if c.I < 0 || c.J < 0 || c.I >= constants.BoardSize || c.J >= constants.BoardSize {
+		return errcode.New(errcode.OutOfBoard, errcode.Params{"i": c.I, "j": c.J})
+	}
+	if util.HasStep(c.I, c.J, constants.BLACK, &room.Steps) || util.HasStep(c.I, c.J, constants.WHITE, &room.Steps) {
+		return errcode.New(errcode.PositionOccupied, errcode.Params{"i": c.I, "j": c.J})
+	}
+	return nil
+}
+
 func PrepareNewGame(room *entity.Room) {
 	room.Host.Ready = false
 	room.Challenger.Ready = false
@@ -121,18 +161,26 @@ func CheckFive(room *entity.Room) (bool, *dto.GameOverDTO, error) {
 
 // RetractStep 悔棋
 func RetractStep(ctx context.Context, pid string, rid string, consent int) (string, *entity.Room, int, error) {
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return "", nil, 0, err
+	}
+	defer lease.Unlock()
 
 	// 首先，获取房间对象。如果获取失败或者房间未开始或者没有任何棋步，则返回错误。
-	room, err := redis.GetRoom(ctx, rid)
+	room, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
 		return "", nil, 0, err
 	}
 	length := len(room.Steps)
-	if !room.Started || length == 0 {
-		err = fmt.Errorf("error: room %v is not started or there is no step", rid)
+	if !room.Started {
+		err = errcode.New(errcode.GameNotStarted, errcode.Params{"rid": rid})
+		logger.Error(err)
+		return "", nil, 0, err
+	}
+	if length == 0 {
+		err = errcode.New(errcode.NothingToRetract, nil)
 		logger.Error(err)
 		return "", nil, 0, err
 	}
@@ -140,7 +188,7 @@ func RetractStep(ctx context.Context, pid string, rid string, consent int) (stri
 	// 判断当前玩家是否在房间内并且扮演的是参赛者角色。如果不是，则返回错误。
 	inRoom, role, _ := isInRoom(pid, room)
 	if !inRoom || role == "spectator" {
-		err = fmt.Errorf("error: player %v is not playing in room %v", pid, rid)
+		err = errcode.New(errcode.NotPlaying, errcode.Params{"pid": pid, "rid": rid})
 		logger.Error(err)
 		return "", nil, 0, err
 	}
@@ -161,7 +209,7 @@ func RetractStep(ctx context.Context, pid string, rid string, consent int) (stri
 	var count int
 	if consent == 2 {
 		if length == 1 && color == constants.WHITE {
-			err = fmt.Errorf("error: there is no white step so white side can't retract")
+			err = errcode.New(errcode.NothingToRetract, nil)
 			logger.Error(err)
 			return "", nil, 0, err
 		}
@@ -176,7 +224,7 @@ func RetractStep(ctx context.Context, pid string, rid string, consent int) (stri
 	}
 
 	// 更新房间对象并返回结果。
-	if err = redis.SetRoom(ctx, room); err != nil {
+	if err = stores.Rooms.Set(ctx, room); err != nil {
 		logger.Error(err)
 		return "", nil, 0, err
 	}
@@ -185,17 +233,20 @@ func RetractStep(ctx context.Context, pid string, rid string, consent int) (stri
 
 // Surrender 投降
 func Surrender(ctx context.Context, pid string, rid string) (*dto.GameOverDTO, *entity.Room, error) {
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lease.Unlock()
 
-	room, err := redis.GetRoom(ctx, rid)
+	room, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
 		return nil, nil, err
 	}
 
 	if !room.Started {
-		err = fmt.Errorf("error: room %v is not started", rid)
+		err = errcode.New(errcode.GameNotStarted, errcode.Params{"rid": rid})
 		logger.Error(err)
 		return nil, nil, err
 	}
@@ -203,7 +254,7 @@ func Surrender(ctx context.Context, pid string, rid string) (*dto.GameOverDTO, *
 	// 判断当前玩家是否在房间内并且扮演的是参赛者角色
 	inRoom, role, _ := isInRoom(pid, room)
 	if !inRoom || role == "spectator" {
-		err = fmt.Errorf("error: player %v is not playing in room %v", pid, rid)
+		err = errcode.New(errcode.NotPlaying, errcode.Params{"pid": pid, "rid": rid})
 		logger.Error(err)
 		return nil, nil, err
 	}
@@ -223,8 +274,9 @@ func Surrender(ctx context.Context, pid string, rid string) (*dto.GameOverDTO, *
 	}
 
 	// 准备新的游戏，即重置房间对象的状态和数据, 更新房间对象并返回 GameOverDTO 结构体指针和房间对象指针。
+	ArchiveGame(ctx, room, gameOverDTO)
 	PrepareNewGame(room)
-	if err = redis.SetRoom(ctx, room); err != nil {
+	if err = stores.Rooms.Set(ctx, room); err != nil {
 		logger.Error(err)
 		return nil, nil, err
 	}
@@ -235,23 +287,26 @@ func Surrender(ctx context.Context, pid string, rid string) (*dto.GameOverDTO, *
 // Draw 请求平局的功能.
 // pid，玩家 ID；rid，房间 ID；consent，一个整数值表示玩家是否同意平局请求（1：拒绝；2：同意）
 func Draw(ctx context.Context, pid string, rid string, consent int) (string, *entity.Room, error) {
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return "", nil, err
+	}
+	defer lease.Unlock()
 
-	room, err := redis.GetRoom(ctx, rid)
+	room, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
 		return "", nil, err
 	}
 	if !room.Started {
-		err = fmt.Errorf("error: room %v is not started", rid)
+		err = errcode.New(errcode.GameNotStarted, errcode.Params{"rid": rid})
 		logger.Error(err)
 		return "", nil, err
 	}
 
 	inRoom, role, _ := isInRoom(pid, room)
 	if !inRoom || role == "spectator" {
-		err = fmt.Errorf("error: player %v is not playing in room %v", pid, rid)
+		err = errcode.New(errcode.NotPlaying, errcode.Params{"pid": pid, "rid": rid})
 		logger.Error(err)
 		return "", nil, err
 	}
@@ -260,8 +315,9 @@ func Draw(ctx context.Context, pid string, rid string, consent int) (string, *en
 	// 若玩家同意，则准备新的游戏，即重置房间对象的状态和数据，并更新房间对象。
 	// 若玩家拒绝，则不进行任何操作。
 	if consent == 2 {
+		ArchiveGame(ctx, room, &dto.GameOverDTO{RId: rid, Cause: "draw"})
 		PrepareNewGame(room)
-		if err = redis.SetRoom(ctx, room); err != nil {
+		if err = stores.Rooms.Set(ctx, room); err != nil {
 			logger.Error(err)
 			return "", nil, err
 		}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RecordOpening 将一局存档的前 explorer.max_ply 手计入开局库，局面按 8 种对称归一，explorer.enabled 关闭时不记录
+func RecordOpening(ctx context.Context, game *entity.Game) {
+	if !viper.GetBool("explorer.enabled") {
+		return
+	}
+	n := viper.GetInt("explorer.max_ply")
+	if n > len(game.Steps) {
+		n = len(game.Steps)
+	}
+	openings := make([]store.Opening, 0, n)
+	for ply := 0; ply < n; ply++ {
+		key, syms := engine.Canonical(game.Steps[:ply])
+		move := engine.CanonicalMove(game.Steps[ply], syms)
+		openings = append(openings, store.Opening{
+			Key:    key,
+			Move:   int(move.I)*engine.Size + int(move.J),
+			Winner: game.Winner,
+		})
+	}
+	if len(openings) == 0 {
+		return
+	}
+	if err := stores.Openings.Add(ctx, openings); err != nil {
+		logger.Error(err)
+	}
+}
+
+// Explore 查询 steps 之后各着法的对局数和胜率
+func Explore(ctx context.Context, steps []entity.Chess) (*dto.ExplorerDTO, error) {
+	if _, err := engine.NewBoard(steps); err != nil {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "steps"})
+	}
+	key, syms := engine.Canonical(steps)
+	counts, err := stores.Openings.Get(ctx, key)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+
+	// 规范坐标换回查询局面的方向
+	inverse := syms[0].Inverse()
+	byMove := make(map[int]*dto.ExplorerMove)
+	result := &dto.ExplorerDTO{Steps: steps, Moves: make([]dto.ExplorerMove, 0)}
+	for field, n := range counts {
+		idx, winner, _ := strings.Cut(field, ":")
+		m, err := strconv.Atoi(idx)
+		if err != nil {
+			continue
+		}
+		em, ok := byMove[m]
+		if !ok {
+			c := entity.Chess{I: int8(m / engine.Size), J: int8(m % engine.Size)}
+			em = &dto.ExplorerMove{Move: inverse.Apply(c)}
+			byMove[m] = em
+		}
+		em.Games += n
+		switch winner {
+		case "black":
+			em.BlackWins += n
+		case "white":
+			em.WhiteWins += n
+		default:
+			em.Draws += n
+		}
+		result.Games += n
+	}
+
+	black := len(steps)%2 == 0
+	for _, em := range byMove {
+		wins := em.WhiteWins
+		if black {
+			wins = em.BlackWins
+		}
+		em.WinRate = (float64(wins) + float64(em.Draws)/2) / float64(em.Games)
+		result.Moves = append(result.Moves, *em)
+	}
+	sort.Slice(result.Moves, func(x, y int) bool {
+		a, b := result.Moves[x], result.Moves[y]
+		if a.Games != b.Games {
+			return a.Games > b.Games
+		}
+		return a.WinRate > b.WinRate
+	})
+	return result, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	uuid "github.com/satori/go.uuid"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"time"
+)
+
+// ArchiveGame 存档刚结束的对局，需要在 PrepareNewGame 交换颜色之前调用。
+// 存档失败只记录日志，不影响对局结束的流程。
+func ArchiveGame(ctx context.Context, room *entity.Room, gameOverDTO *dto.GameOverDTO) {
+	game := NewGameRecord(room, gameOverDTO)
+	updateRatings(ctx, room, game)
+	if err := stores.Games.Add(ctx, game); err != nil {
+		logger.Error(err)
+		return
+	}
+	logger.WithField("gid", game.Id).Debug("game archived")
+	RecordOpening(ctx, game)
+	EnqueueReview(game.Id)
+}
+
+// NewGameRecord 根据房间和对局结果生成存档
+func NewGameRecord(room *entity.Room, gameOverDTO *dto.GameOverDTO) *entity.Game {
+	game := &entity.Game{
+		Id:        uuid.NewV4().String(),
+		RId:       room.Id,
+		Steps:     append([]entity.Chess(nil), room.Steps...),
+		Winner:    "draw",
+		Cause:     gameOverDTO.Cause,
+		StartTime: room.StartTime,
+		EndTime:   time.Now().Format(time.DateTime),
+	}
+
+	black, white := room.Host, room.Challenger
+	if gameOverDTO.Winner.Id != "" {
+		black, white = gameOverDTO.Winner, gameOverDTO.Loser
+		game.Winner = "black"
+		if gameOverDTO.Winner.Color == constants.WHITE {
+			game.Winner = "white"
+		}
+	}
+	if black.Color == constants.WHITE {
+		black, white = white, black
+	}
+	game.Black = black.Player
+	game.White = white.Player
+	return game
+}
+
+func GetGame(ctx context.Context, id string) (*entity.Game, error) {
+	return stores.Games.Get(ctx, id)
+}
+
+func GetGames(ctx context.Context, offset int64, limit int64) (*[]entity.Game, error) {
+	return stores.Games.List(ctx, offset, limit)
+}
+
+// GetLeaderboard 排行榜按玩家 id 统计，在线玩家显示当前的名字，离线的登录玩家显示账号名
+func GetLeaderboard(ctx context.Context, limit int64) (*[]entity.Rank, error) {
+	ranks, err := stores.Games.Leaderboard(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	for k := range *ranks {
+		if p, err := stores.Players.Get(ctx, (*ranks)[k].Id); err == nil {
+			(*ranks)[k].Name = p.Name
+		} else if a, err := stores.Accounts.Get(ctx, (*ranks)[k].Id); err == nil {
+			(*ranks)[k].Name = a.Name
+		}
+	}
+	return ranks, nil
+}
@@ -3,12 +3,11 @@ package service
 import (
 	"context"
 	"github.com/toujourser/gomoku/internal/entity"
-	"github.com/toujourser/gomoku/internal/redis"
 	"github.com/toujourser/gomoku/pkg/logger"
 )
 
 func HallChat(ctx context.Context, msg *entity.DialogMsg) error {
-	err := redis.AddDialogMsg(ctx, msg)
+	err := stores.Chat.Add(ctx, msg)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -17,7 +16,7 @@ func HallChat(ctx context.Context, msg *entity.DialogMsg) error {
 }
 
 func GetHallDialog(ctx context.Context) (*[]entity.DialogMsg, error) {
-	dialog, err := redis.GetDialog(ctx)
+	dialog, err := stores.Chat.List(ctx)
 	if err != nil {
 		logger.Error(err)
 	}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/analysis"
+	"github.com/toujourser/gomoku/pkg/engine/engines"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"math"
+)
+
+// Hint 分析房间 rid 的当前局面，rid 为空时分析 steps 给出的任意局面，返回前 k 个候选着法。
+// 房间提示只对房间内的玩家和旁观者开放，计分房间或关闭了提示的房间在对局进行中不提供提示；
+// 正在这样的房间中对局的玩家分析任意局面同样被拒绝，否则可以把对局的着法作为 steps 绕过限制。
+func Hint(ctx context.Context, pid string, rid string, steps []entity.Chess, k int) (*dto.HintDTO, error) {
+	// hint.rate 为 0 时不限流
+	if rate := viper.GetInt64("hint.rate"); rate > 0 {
+		ok, wait, err := stores.Limiter.Allow(ctx, "hint:"+pid, rate, viper.GetDuration("hint.window"))
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		if !ok {
+			return nil, errcode.New(errcode.TooManyRequests, errcode.Params{"seconds": int(math.Ceil(wait.Seconds()))})
+		}
+	}
+
+	if rid != "" {
+		room, err := stores.Rooms.Get(ctx, rid)
+		if err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+		if inRoom, _, _ := isInRoom(pid, room); !inRoom {
+			return nil, errcode.New(errcode.NotInRoom, errcode.Params{"pid": pid, "rid": rid})
+		}
+		if room.Started && (room.Settings.Rated || !room.Settings.Hints) {
+			return nil, errcode.New(errcode.HintsDisabled, errcode.Params{"rid": rid})
+		}
+		steps = room.Steps
+	} else if err := checkSeatedHints(ctx, pid); err != nil {
+		return nil, err
+	}
+	if _, err := engine.NewBoard(steps); err != nil {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "steps"})
+	}
+
+	if max := viper.GetInt("hint.max_candidates"); k <= 0 || k > max {
+		k = max
+	}
+	e, err := engines.New(ctx, viper.GetString("hint.engine"), pbrain.Options{})
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	defer e.Close()
+
+	cands, err := analysis.TopMoves(ctx, e, engine.Position{Steps: steps}, k, engine.Limits{Time: viper.GetDuration("hint.time")})
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	return &dto.HintDTO{RId: rid, Moves: len(steps), Candidates: cands}, nil
+}
+
+// checkSeatedHints 玩家 pid 正在不提供提示的房间中对局时返回 HintsDisabled
+func checkSeatedHints(ctx context.Context, pid string) error {
+	rooms, err := stores.Rooms.List(ctx)
+	if err != nil {
+		logger.Error(err)
+		return err
+	}
+	for k := range *rooms {
+		r := &(*rooms)[k]
+		if _, role, _ := isInRoom(pid, r); role != "host" && role != "challenger" {
+			continue
+		}
+		if r.Started && (r.Settings.Rated || !r.Settings.Hints) {
+			return errcode.New(errcode.HintsDisabled, errcode.Params{"rid": r.Id})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"sort"
+)
+
+const (
+	defaultLobbyPageSize = 20
+	defaultLobbyMaxPage  = 100
+)
+
+// Lobby 按条件过滤、排序并分页返回大厅中的房间摘要
+func Lobby(ctx context.Context, q *dto.LobbyQuery) (*dto.LobbyDTO, error) {
+	if err := CheckLobbyQuery(q); err != nil {
+		return nil, err
+	}
+	rooms, err := stores.Rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return LobbyPage(rooms, q), nil
+}
+
+// LobbyPage 从已读取的房间列表中取出一页，q 需要已经过 CheckLobbyQuery 检查。
+// 推送大厅更新时只读取一次房间列表，再按每个连接的查询条件分别取页。
+func LobbyPage(rooms *[]entity.Room, q *dto.LobbyQuery) *dto.LobbyDTO {
+	summaries := make([]dto.RoomSummary, 0, len(*rooms))
+	for i := range *rooms {
+		summary := dto.NewRoomSummary(&(*rooms)[i])
+		if matchLobby(q, &summary) {
+			summaries = append(summaries, summary)
+		}
+	}
+	sortLobby(q, summaries)
+
+	limit := lobbyLimit(q.Limit)
+	page := &dto.LobbyDTO{
+		Total:  len(summaries),
+		Offset: q.Offset,
+		Limit:  limit,
+		Rooms:  make([]dto.RoomSummary, 0),
+	}
+	if q.Offset < len(summaries) {
+		end := q.Offset + limit
+		if end > len(summaries) {
+			end = len(summaries)
+		}
+		page.Rooms = summaries[q.Offset:end]
+	}
+	return page
+}
+
+// CheckLobbyQuery 检查大厅查询条件
+func CheckLobbyQuery(q *dto.LobbyQuery) error {
+	switch q.Sort {
+	case "", dto.SortCreated, dto.SortRating, dto.SortSpectators:
+	default:
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "sort"})
+	}
+	if q.Offset < 0 {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "offset"})
+	}
+	if q.Limit < 0 {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "limit"})
+	}
+	if q.MinRating < 0 || q.MaxRating < 0 || (q.MaxRating > 0 && q.MinRating > q.MaxRating) {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "rating"})
+	}
+	return nil
+}
+
+// lobbyLimit 返回分页大小，0 取 lobby.page_size，超过 lobby.max_page_size 时截断
+func lobbyLimit(limit int) int {
+	if limit == 0 {
+		limit = viper.GetInt("lobby.page_size")
+		if limit <= 0 {
+			limit = defaultLobbyPageSize
+		}
+	}
+	maxSize := viper.GetInt("lobby.max_page_size")
+	if maxSize <= 0 {
+		maxSize = defaultLobbyMaxPage
+	}
+	if limit > maxSize {
+		limit = maxSize
+	}
+	return limit
+}
+
+func matchLobby(q *dto.LobbyQuery, r *dto.RoomSummary) bool {
+	if q.Open != nil && *q.Open != (r.Challenger == nil) {
+		return false
+	}
+	if q.Playing != nil && *q.Playing != (r.State == dto.RoomPlaying) {
+		return false
+	}
+	if q.Rated != nil && *q.Rated != r.Settings.Rated {
+		return false
+	}
+	if q.Hints != nil && *q.Hints != r.Settings.Hints {
+		return false
+	}
+	if q.Analysis != nil && *q.Analysis != r.Settings.Analysis {
+		return false
+	}
+	if q.MinRating > 0 && r.Host.Rating < q.MinRating {
+		return false
+	}
+	if q.MaxRating > 0 && r.Host.Rating > q.MaxRating {
+		return false
+	}
+	return true
+}
+
+// sortLobby 按查询指定的字段排序，相同时按房间 id 排序，保证翻页时顺序稳定
+func sortLobby(q *dto.LobbyQuery, rooms []dto.RoomSummary) {
+	sort.Slice(rooms, func(i, j int) bool {
+		a, b := &rooms[i], &rooms[j]
+		var c int
+		switch q.Sort {
+		case dto.SortRating:
+			c = a.Host.Rating - b.Host.Rating
+		case dto.SortSpectators:
+			c = a.Spectators - b.Spectators
+		default:
+			if a.CreateTime < b.CreateTime {
+				c = -1
+			} else if a.CreateTime > b.CreateTime {
+				c = 1
+			}
+		}
+		if c == 0 {
+			return a.Id < b.Id
+		}
+		if q.Asc {
+			return c < 0
+		}
+		return c > 0
+	})
+}
@@ -2,9 +2,8 @@ package service
 
 import (
 	"context"
-	"fmt"
 	"github.com/toujourser/gomoku/internal/entity"
-	"github.com/toujourser/gomoku/internal/redis"
+	"github.com/toujourser/gomoku/internal/errcode"
 	"github.com/toujourser/gomoku/pkg/logger"
 	"time"
 )
@@ -16,8 +15,9 @@ func NewPlayerConnect(ctx context.Context, id string) (*entity.Player, error) {
 		Status:        "leisure",
 		LoginTime:     time.Now().Format(time.DateTime),
 		MatchesPlayed: 0,
+		Rating:        DefaultRating,
 	}
-	err := redis.SetPlayer(ctx, p)
+	err := stores.Players.Set(ctx, p)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
@@ -26,32 +26,42 @@ func NewPlayerConnect(ctx context.Context, id string) (*entity.Player, error) {
 	return p, nil
 }
 
+// IssueResumeToken 为新玩家生成重连令牌，只发给玩家自己的连接，重连时与玩家 id 一起出示
+func IssueResumeToken(ctx context.Context, pid string) (string, error) {
+	token := newToken()
+	if err := stores.Players.SetResumeToken(ctx, pid, token); err != nil {
+		logger.Error(err)
+		return "", err
+	}
+	return token, nil
+}
+
 func GetPlayer(ctx context.Context, id string) (*entity.Player, error) {
-	return redis.GetPlayer(ctx, id)
+	return stores.Players.Get(ctx, id)
 }
 
 func GetPlayers(ctx context.Context) (*[]entity.Player, error) {
-	return redis.GetPlayers(ctx)
+	return stores.Players.List(ctx)
 }
 
 func PlayerDisconnect(ctx context.Context, id string) (*[]entity.Room, error) {
-	err := redis.DelPlayer(ctx, id)
+	err := stores.Players.Del(ctx, id)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
 	logger.WithField("pid", id).Debug("player disconnects")
-	return redis.GetRooms(ctx)
+	return stores.Rooms.List(ctx)
 }
 
 func PlayerRename(ctx context.Context, id string, newName string) error {
-	p, err := redis.GetPlayer(ctx, id)
+	p, err := stores.Players.Get(ctx, id)
 	if err != nil {
 		logger.Error(err)
 		return err
 	}
 	flag := false
-	ps, _ := redis.GetPlayers(ctx)
+	ps, _ := stores.Players.List(ctx)
 	for _, op := range *ps {
 		if op.Name == newName {
 			//return fmt.Errorf("player %v already exists", newName)
@@ -60,25 +70,25 @@ func PlayerRename(ctx context.Context, id string, newName string) error {
 		}
 	}
 	p.Name = newName
-	err = redis.SetPlayer(ctx, p)
+	err = stores.Players.Set(ctx, p)
 	if err != nil {
 		logger.Error(err)
 		return err
 	}
 	if flag {
-		return fmt.Errorf("该用户名已存在，系统自动重置名称为 %v", newName)
+		return errcode.New(errcode.NameTaken, errcode.Params{"name": newName})
 	}
 	return nil
 }
 
 func SetPlayerStatus(ctx context.Context, id string, status string) error {
-	p, err := redis.GetPlayer(ctx, id)
+	p, err := stores.Players.Get(ctx, id)
 	if err != nil {
 		logger.Error(err)
 		return err
 	}
 	p.Status = status
-	err = redis.SetPlayer(ctx, p)
+	err = stores.Players.Set(ctx, p)
 	if err != nil {
 		logger.Error(err)
 		return err
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	uuid "github.com/satori/go.uuid"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/engine/puzzle"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"time"
+)
+
+// DailyPuzzle StartPuzzle 中表示每日一题的 id
+const DailyPuzzle = "daily"
+
+// ImportPuzzle 导入文本格式的题目，导入前求解验证，无解的题目返回 PuzzleInvalid
+func ImportPuzzle(ctx context.Context, text string) (*entity.Puzzle, error) {
+	parsed, err := puzzle.Parse(ctx, text)
+	if err != nil {
+		return nil, errcode.New(errcode.PuzzleInvalid, errcode.Params{"reason": err.Error()})
+	}
+	p := &entity.Puzzle{
+		Id:        uuid.NewV4().String(),
+		Title:     parsed.Title,
+		Goal:      parsed.Goal,
+		Depth:     parsed.Depth,
+		ToMove:    parsed.ToMove,
+		Steps:     parsed.Steps,
+		Solution:  parsed.Solution,
+		CreatedAt: time.Now().Format(time.DateTime),
+	}
+	if err = stores.Puzzles.Add(ctx, p); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetPuzzle 获取题目，不包含答案
+func GetPuzzle(ctx context.Context, id string) (*dto.PuzzleDTO, error) {
+	daily := id == DailyPuzzle
+	if daily {
+		var err error
+		if id, err = dailyPuzzleId(ctx); err != nil {
+			return nil, err
+		}
+	}
+	p, err := stores.Puzzles.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return dto.NewPuzzleDTO(p, nil, daily), nil
+}
+
+// GetPuzzles 按导入顺序分页获取题目
+func GetPuzzles(ctx context.Context, offset int64, limit int64) (*[]dto.PuzzleDTO, error) {
+	ids, err := stores.Puzzles.Ids(ctx, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	puzzles := make([]dto.PuzzleDTO, 0, len(ids))
+	for _, id := range ids {
+		p, err := stores.Puzzles.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		puzzles = append(puzzles, *dto.NewPuzzleDTO(p, nil, false))
+	}
+	return &puzzles, nil
+}
+
+// dailyPuzzleId 每日一题按日期在所有题目中轮换
+func dailyPuzzleId(ctx context.Context) (string, error) {
+	now := time.Now()
+	_, offset := now.Zone()
+	days := (now.Unix() + int64(offset)) / 86400
+	return stores.Puzzles.IdAt(ctx, days)
+}
+
+// StartPuzzle 开始解题，id 为 daily 时为每日一题，会放弃玩家正在进行的解题
+func StartPuzzle(ctx context.Context, pid string, id string) (*dto.PuzzleDTO, error) {
+	p, err := GetPuzzle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	attempt := &entity.PuzzleAttempt{PuzzleId: p.Id, Daily: p.Daily, Moves: make([]entity.Chess, 0)}
+	if err = stores.Puzzles.SetAttempt(ctx, pid, attempt); err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	return p, nil
+}
+
+// PuzzleMove 检查玩家的着法并给出防守方的应对，解题成功或失败后结束本次解题
+func PuzzleMove(ctx context.Context, pid string, c entity.Chess) (*dto.PuzzleMoveDTO, error) {
+	attempt, err := stores.Puzzles.GetAttempt(ctx, pid)
+	if err != nil {
+		return nil, err
+	}
+	p, err := stores.Puzzles.Get(ctx, attempt.PuzzleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.I < 0 || c.J < 0 || c.I >= constants.BoardSize || c.J >= constants.BoardSize {
+		return nil, errcode.New(errcode.OutOfBoard, errcode.Params{"i": c.I, "j": c.J})
+	}
+	outcome, reply, err := puzzle.Play(ctx, &puzzle.Puzzle{Goal: p.Goal, Depth: p.Depth, ToMove: p.ToMove, Steps: p.Steps}, attempt.Moves, c)
+	if err != nil {
+		return nil, errcode.New(errcode.PositionOccupied, errcode.Params{"i": c.I, "j": c.J})
+	}
+	result := &dto.PuzzleMoveDTO{Id: p.Id, Move: c, Reply: reply, Result: string(outcome)}
+
+	switch outcome {
+	case puzzle.Continue:
+		attempt.Moves = append(attempt.Moves, c, *reply)
+		result.Remaining = p.Depth - len(attempt.Moves)/2
+		err = stores.Puzzles.SetAttempt(ctx, pid, attempt)
+	case puzzle.Solved:
+		err = stores.Puzzles.DelAttempt(ctx, pid)
+		if err == nil {
+			err = addPuzzleSolved(ctx, pid, attempt.Daily)
+		}
+	case puzzle.Failed:
+		result.Solution = p.Solution
+		err = stores.Puzzles.DelAttempt(ctx, pid)
+	}
+	if err != nil {
+		logger.Error(err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// addPuzzleSolved 解题积分，每日一题计 2 分
+func addPuzzleSolved(ctx context.Context, pid string, daily bool) error {
+	player, err := stores.Players.Get(ctx, pid)
+	if err != nil {
+		return err
+	}
+	score := 1.0
+	if daily {
+		score = 2
+	}
+	return stores.Puzzles.AddSolved(ctx, player.Name, score)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"math"
+)
+
+const (
+	DefaultRating = 1500 // 新玩家的初始积分
+	ratingK       = 32
+)
+
+// Elo 根据双方积分和 a 的得分（胜 1，和 0.5，负 0）计算对局后的新积分
+func Elo(a, b int, score float64) (int, int) {
+	expected := 1 / (1 + math.Pow(10, float64(b-a)/400))
+	delta := int(math.Round(ratingK * (score - expected)))
+	return a + delta, b - delta
+}
+
+// ratingOf 返回玩家积分，积分字段出现之前保存的玩家按初始积分计算
+func ratingOf(p entity.Player) int {
+	if p.Rating == 0 {
+		return DefaultRating
+	}
+	return p.Rating
+}
+
+// updateRatings 计分对局结束后更新双方积分，房间中的座位、玩家记录和账号同时更新。
+// 玩家可能已经断开连接，找不到玩家记录时只更新房间和账号。
+func updateRatings(ctx context.Context, room *entity.Room, game *entity.Game) {
+	if !room.Settings.Rated || room.Settings.Analysis || room.Challenger.Id == "" {
+		return
+	}
+	score := 0.5
+	if game.Winner != "draw" {
+		winner := game.Black.Id
+		if game.Winner == "white" {
+			winner = game.White.Id
+		}
+		score = 0
+		if winner == room.Host.Id {
+			score = 1
+		}
+	}
+	room.Host.Rating, room.Challenger.Rating = Elo(ratingOf(room.Host.Player), ratingOf(room.Challenger.Player), score)
+
+	for _, seat := range []entity.PlayerDetails{room.Host, room.Challenger} {
+		// 登录玩家的 id 即账号 id，积分同时保存到账号
+		if a, err := stores.Accounts.Get(ctx, seat.Id); err == nil {
+			a.Rating = seat.Rating
+			if err = stores.Accounts.Set(ctx, a); err != nil {
+				logger.Error(err)
+			}
+		}
+		p, err := stores.Players.Get(ctx, seat.Id)
+		if err != nil {
+			logger.WithField("pid", seat.Id).Debug("rated player is gone")
+			continue
+		}
+		p.Rating = seat.Rating
+		if err = stores.Players.Set(ctx, p); err != nil {
+			logger.Error(err)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"crypto/subtle"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"time"
+)
+
+// PlayerOffline 服务端重启后尚未重连、但仍在未结束对局中的玩家状态
+const PlayerOffline = "offline"
+
+// RecoverySummary 启动时修复的结果
+type RecoverySummary struct {
+	StalePlayers   int // 不在任何存活实例上的玩家
+	DroppedPlayers int // 删除的玩家
+	OfflinePlayers int // 保留并标记为离线的玩家，等待重连后继续对局
+	DeletedRooms   int // 删除的孤儿房间和过期对局
+	RepairedRooms  int // 移除了离线玩家的房间
+	RestoredGames  int // 保留等待玩家重连的对局
+	DeadInstances  int // 清理的已下线实例
+}
+
+// liveness 判断玩家是否连接在存活的实例上，缓存每个实例的心跳结果
+type liveness struct {
+	alive map[string]bool
+}
+
+func (l *liveness) isLive(ctx context.Context, pid string) (bool, string, error) {
+	if !stores.Shared {
+		// 只有一个实例时，启动前留下的玩家都已断开
+		return false, "", nil
+	}
+	instance, err := stores.Presence.Get(ctx, pid)
+	if err != nil || instance == "" {
+		return false, "", err
+	}
+	alive, ok := l.alive[instance]
+	if !ok {
+		if alive, err = stores.Presence.IsAlive(ctx, instance); err != nil {
+			return false, instance, err
+		}
+		l.alive[instance] = alive
+	}
+	return alive, instance, nil
+}
+
+// Recover 启动时根据共享存储中的状态修复上次异常退出留下的房间和玩家：
+// 进行中的对局保留，等待玩家带着原来的 id 重连；未开始的房间移除离线的玩家，没有房主的房间删除；
+// 超过 recovery.max_game_age 的对局视为过期删除；不在任何对局中的离线玩家删除。
+func Recover(ctx context.Context) (*RecoverySummary, error) {
+	summary := &RecoverySummary{}
+	l := &liveness{alive: make(map[string]bool)}
+
+	players, err := stores.Players.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stale := make(map[string]string) // pid -> 所在的已下线实例
+	for _, p := range *players {
+		live, instance, err := l.isLive(ctx, p.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !live {
+			stale[p.Id] = instance
+		}
+	}
+	summary.StalePlayers = len(stale)
+
+	rooms, err := stores.Rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	seated := make(map[string]bool) // 保留的对局中的离线玩家
+	for _, r := range *rooms {
+		if err = recoverRoom(ctx, r.Id, stale, seated, summary); err != nil {
+			logger.Error(err)
+		}
+		// 修复不代表本实例会用到这个房间，不保留房间锁
+		ReleaseRoom(ctx, r.Id)
+	}
+
+	for pid, instance := range stale {
+		if seated[pid] {
+			if err = SetPlayerStatus(ctx, pid, PlayerOffline); err != nil {
+				return nil, err
+			}
+			summary.OfflinePlayers++
+		} else {
+			if err = stores.Players.Del(ctx, pid); err != nil {
+				return nil, err
+			}
+			summary.DroppedPlayers++
+		}
+		if instance != "" {
+			if err = stores.Presence.SetOffline(ctx, pid, instance); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for instance, alive := range l.alive {
+		if alive {
+			continue
+		}
+		if err = stores.Presence.DelInstance(ctx, instance); err != nil {
+			return nil, err
+		}
+		summary.DeadInstances++
+	}
+
+	logger.WithField("summary", *summary).Info("startup recovery finished")
+	return summary, nil
+}
+
+// recoverRoom 在房间锁内修复单个房间
+func recoverRoom(ctx context.Context, rid string, stale map[string]string, seated map[string]bool, summary *RecoverySummary) error {
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return err
+	}
+	defer lease.Unlock()
+
+	r, err := stores.Rooms.Get(ctx, rid)
+	if errcode.Is(err, errcode.RoomNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	isStale := func(pid string) bool {
+		_, ok := stale[pid]
+		return ok
+	}
+
+	changed := false
+	spectators := make([]entity.Player, 0, len(r.Spectators))
+	for _, p := range r.Spectators {
+		if isStale(p.Id) {
+			changed = true
+			continue
+		}
+		spectators = append(spectators, p)
+	}
+	r.Spectators = spectators
+
+	hostStale := r.Host.Id != "" && isStale(r.Host.Id)
+	challengerStale := r.Challenger.Id != "" && isStale(r.Challenger.Id)
+	switch {
+	case r.Started && gameExpired(r):
+		summary.DeletedRooms++
+		return stores.Rooms.Del(ctx, rid)
+	case r.Started:
+		if hostStale {
+			seated[r.Host.Id] = true
+		}
+		if challengerStale {
+			seated[r.Challenger.Id] = true
+		}
+		if hostStale || challengerStale {
+			summary.RestoredGames++
+		}
+	case r.Host.Id == "" || hostStale && (r.Challenger.Id == "" || challengerStale):
+		summary.DeletedRooms++
+		return stores.Rooms.Del(ctx, rid)
+	case hostStale:
+		r.Host = r.Challenger
+		r.Challenger = entity.PlayerDetails{}
+		changed = true
+	case challengerStale:
+		r.Challenger = entity.PlayerDetails{Color: 1 - r.Host.Color}
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	summary.RepairedRooms++
+	return stores.Rooms.Set(ctx, r)
+}
+
+// gameExpired 对局开始超过 recovery.max_game_age 视为过期，未配置时不过期
+func gameExpired(r *entity.Room) bool {
+	age := viper.GetDuration("recovery.max_game_age")
+	start, err := time.ParseInLocation(time.DateTime, r.StartTime, time.Local)
+	if age <= 0 || err != nil {
+		return false
+	}
+	return time.Since(start) > age
+}
+
+// ResumePlayer 玩家带着重启前的 id 和重连令牌重连时恢复该玩家，
+// 玩家不存在、令牌不匹配或玩家仍在线时返回 false
+func ResumePlayer(ctx context.Context, pid string, token string) (*entity.Player, *entity.Room, bool) {
+	if pid == "" || token == "" {
+		return nil, nil, false
+	}
+	want, err := stores.Players.ResumeToken(ctx, pid)
+	if err != nil {
+		logger.Error(err)
+		return nil, nil, false
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+		return nil, nil, false
+	}
+	return resumePlayer(ctx, pid)
+}
+
+// resumePlayer 恢复已经验证过身份的玩家
+func resumePlayer(ctx context.Context, pid string) (*entity.Player, *entity.Room, bool) {
+	p, err := stores.Players.Get(ctx, pid)
+	if err != nil {
+		return nil, nil, false
+	}
+	if !stores.Shared {
+		// 只有一个实例时断开的玩家会被删除，留下的只有重启前没有重连的玩家
+		if p.Status != PlayerOffline {
+			return nil, nil, false
+		}
+	} else {
+		l := &liveness{alive: make(map[string]bool)}
+		if live, _, err := l.isLive(ctx, pid); err != nil || live {
+			return nil, nil, false
+		}
+	}
+
+	var room *entity.Room
+	rooms, err := stores.Rooms.List(ctx)
+	if err != nil {
+		logger.Error(err)
+		return nil, nil, false
+	}
+	for k := range *rooms {
+		if inRoom, _, _ := isInRoom(pid, &(*rooms)[k]); inRoom {
+			// 房间列表不含着法和聊天，重连的玩家需要完整的房间
+			if room, err = stores.Rooms.Get(ctx, (*rooms)[k].Id); err != nil {
+				logger.Error(err)
+				return nil, nil, false
+			}
+			break
+		}
+	}
+	if p.Status == PlayerOffline {
+		p.Status = "leisure"
+		if err = stores.Players.Set(ctx, p); err != nil {
+			logger.Error(err)
+			return nil, nil, false
+		}
+	}
+	logger.WithField("pid", pid).Info("player resumes")
+	return p, room, true
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/analysis"
+	"github.com/toujourser/gomoku/pkg/engine/engines"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"sync"
+	"time"
+)
+
+// 等待复盘的对局 id，由 StartReviewer 启动的任务逐个处理
+var reviewQueue = make(chan string, 64)
+
+// 已在队列中或正在复盘的对局，避免重复提交
+var reviewPending sync.Map
+
+// StartReviewer 启动复盘任务，ctx 结束时退出
+func StartReviewer(ctx context.Context) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case gid := <-reviewQueue:
+				if _, err := ReviewGame(ctx, gid); err != nil {
+					logger.Error(err)
+				}
+				reviewPending.Delete(gid)
+			}
+		}
+	}()
+}
+
+// EnqueueReview 提交对局复盘，队列已满时丢弃，客户端获取复盘时会重新提交
+func EnqueueReview(gid string) {
+	if !viper.GetBool("review.enabled") {
+		return
+	}
+	if _, loaded := reviewPending.LoadOrStore(gid, true); loaded {
+		return
+	}
+	select {
+	case reviewQueue <- gid:
+	default:
+		reviewPending.Delete(gid)
+		logger.WithField("gid", gid).Warn("review queue is full")
+	}
+}
+
+// ReviewGame 复盘一局存档并保存复盘记录
+func ReviewGame(ctx context.Context, gid string) (*entity.Review, error) {
+	game, err := stores.Games.Get(ctx, gid)
+	if err != nil {
+		return nil, err
+	}
+
+	spec := viper.GetString("review.engine")
+	e, err := engines.New(ctx, spec, pbrain.Options{})
+	if err != nil {
+		return nil, err
+	}
+	defer e.Close()
+
+	moves, err := analysis.Review(ctx, e, engine.Position{Steps: game.Steps}, analysis.ReviewOptions{
+		Limits:   engine.Limits{Time: viper.GetDuration("review.time")},
+		VCFDepth: viper.GetInt("review.vcf_depth"),
+		Blunder:  viper.GetInt("review.blunder"),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	review := &entity.Review{
+		GameId:    gid,
+		Engine:    e.Name(),
+		Moves:     moves,
+		CreatedAt: time.Now().Format(time.DateTime),
+	}
+	if err = stores.Reviews.Set(ctx, review); err != nil {
+		return nil, err
+	}
+	logger.WithField("gid", gid).Debug("game reviewed")
+	return review, nil
+}
+
+// GetReview 获取对局的复盘记录，尚未生成时提交复盘并返回 ReviewNotReady
+func GetReview(ctx context.Context, gid string) (*entity.Review, error) {
+	review, err := stores.Reviews.Get(ctx, gid)
+	if !errcode.Is(err, errcode.ReviewNotReady) {
+		return review, err
+	}
+	if _, err := stores.Games.Get(ctx, gid); err != nil {
+		return nil, err
+	}
+	EnqueueReview(gid)
+	return nil, err
+}
@@ -2,19 +2,45 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	uuid "github.com/satori/go.uuid"
 	"github.com/toujourser/gomoku/internal/dto"
 	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
 	"github.com/toujourser/gomoku/internal/lock"
-	"github.com/toujourser/gomoku/internal/redis"
+	"github.com/toujourser/gomoku/internal/store"
 	"github.com/toujourser/gomoku/pkg/logger"
+	"time"
 )
 
+// GetRooms 获取所有房间，房间保存在同一个 hash 中，一次读取即可得到一致的快照
 func GetRooms(ctx context.Context) (*[]entity.Room, error) {
-	lock.RoomLock.RLockAll()
-	defer lock.RoomLock.RUnlockAll()
-	return redis.GetRooms(ctx)
+	return stores.Rooms.List(ctx)
+}
+
+// lockRoom 获取房间锁，返回的 ctx 携带 fencing token，等待超时返回 RoomBusy
+func lockRoom(ctx context.Context, rid string) (context.Context, *lock.Lease, error) {
+	ctx, lease, err := stores.RoomLock.Lock(ctx, rid)
+	if errors.Is(err, lock.ErrTimeout) {
+		err = errcode.New(errcode.RoomBusy, errcode.Params{"rid": rid})
+	}
+	if err != nil {
+		logger.Error(err)
+		return ctx, nil, err
+	}
+	return ctx, lease, nil
+}
+
+// ReleaseRoom 其他实例请求修改房间时，交出本实例保留的房间锁
+func ReleaseRoom(ctx context.Context, rid string) {
+	if err := stores.RoomLock.Release(ctx, rid); err != nil {
+		logger.Error(err)
+	}
+}
+
+// GetRoom 获取单个房间，只读操作无需加锁
+func GetRoom(ctx context.Context, rid string) (*entity.Room, error) {
+	return stores.Rooms.Get(ctx, rid)
 }
 
 func isInRoom(pid string, room *entity.Room) (inRoom bool, role string, index int) {
@@ -40,29 +66,32 @@ func isInRoom(pid string, room *entity.Room) (inRoom bool, role string, index in
 }
 
 func EnterRoom(ctx context.Context, pid string, rid string, role string) (*entity.Room, error) {
-	p, err := redis.GetPlayer(ctx, pid)
+	p, err := stores.Players.Get(ctx, pid)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
 
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Unlock()
 
-	r, err := redis.GetRoom(ctx, rid)
+	r, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
 	}
 
 	if r.Host.Id == "" {
-		err = fmt.Errorf("error: Room %v has no host", rid)
+		err = errcode.New(errcode.RoomNoHost, errcode.Params{"rid": rid})
 		logger.Error(err)
 		return nil, err
 	}
 
 	if inRoom, _, _ := isInRoom(pid, r); inRoom {
-		err = fmt.Errorf("你已经在该房间内了哦")
+		err = errcode.New(errcode.AlreadyInRoom, errcode.Params{"rid": rid})
 		logger.Error(err)
 		return nil, err
 	}
@@ -86,12 +115,12 @@ func EnterRoom(ctx context.Context, pid string, rid string, role string) (*entit
 		}
 		r.Spectators = append(r.Spectators, *p)
 	} else {
-		err = fmt.Errorf("error: The role /'%v/' can't enter room", role)
+		err = errcode.New(errcode.InvalidRole, errcode.Params{"role": role})
 		logger.Error(err)
 		return nil, err
 	}
 
-	if err = redis.SetRoom(ctx, r); err != nil {
+	if err = stores.Rooms.Set(ctx, r); err != nil {
 		return nil, err
 	}
 
@@ -100,8 +129,8 @@ func EnterRoom(ctx context.Context, pid string, rid string, role string) (*entit
 	return r, nil
 }
 
-func CreateRoom(ctx context.Context, pid string, color int8) (*entity.Room, error) {
-	p, err := redis.GetPlayer(ctx, pid)
+func CreateRoom(ctx context.Context, pid string, color int8, settings entity.RoomSettings) (*entity.Room, error) {
+	p, err := stores.Players.Get(ctx, pid)
 	if err != nil {
 		logger.Error(err)
 		return nil, err
@@ -115,62 +144,67 @@ func CreateRoom(ctx context.Context, pid string, color int8) (*entity.Room, erro
 	}
 
 	id := uuid.NewV4().String()
-	lock.RoomLock.Add(id)
-	lock.RoomLock.Lock(id)
-	defer lock.RoomLock.Unlock(id)
+	ctx, lease, err := lockRoom(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer lease.Unlock()
 
 	r := &entity.Room{
-		Id:      id,
-		Dialog:  make([]entity.DialogMsg, 0),
-		Steps:   make([]entity.Chess, 0),
-		Started: false,
-		Host:    h,
+		Id:       id,
+		Settings: settings,
+		Dialog:   make([]entity.DialogMsg, 0),
+		Steps:    make([]entity.Chess, 0),
+		Started:  false,
+		Host:     h,
 		Challenger: entity.PlayerDetails{
 			Color: 1 - color,
 		},
 		Spectators: make([]entity.Player, 0),
+		CreateTime: time.Now().Format(time.DateTime),
+	}
+	if settings.Analysis {
+		r.Tree = NewMoveTree()
 	}
 
-	if err = redis.SetRoom(ctx, r); err != nil {
+	if err = stores.Rooms.Set(ctx, r); err != nil {
 		return nil, err
 	}
 	return r, nil
 }
 
 func LeaveRoom(ctx context.Context, pid string, rid string) (*entity.Room, *dto.GameOverDTO, error) {
-	lock.RoomLock.Lock(rid)
+	ctx, lease, err := lockRoom(ctx, rid)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer lease.Unlock()
 
-	r, err := redis.GetRoom(ctx, rid)
+	r, err := stores.Rooms.Get(ctx, rid)
 	if err != nil {
 		logger.Error(err)
-		lock.RoomLock.Unlock(rid)
 		return nil, nil, err
 	}
 
 	inRoom, role, i := isInRoom(pid, r)
 	if !inRoom {
-		lock.RoomLock.Unlock(rid)
 		return r, nil, nil
 	}
 
 	var gameOverDTO *dto.GameOverDTO
 	if role == "host" {
 		if r.Challenger.Id == "" {
-			if err = redis.DelRoom(ctx, rid); err != nil {
+			if err = stores.Rooms.Del(ctx, rid); err != nil {
 				logger.Error(err)
-				lock.RoomLock.Unlock(rid)
 				return nil, nil, err
 			}
 			r.Host = entity.PlayerDetails{}
 			for _, player := range r.Spectators {
 				if err := SetPlayerStatus(ctx, player.Id, "leisure"); err != nil {
 					logger.Error(err)
-					lock.RoomLock.Unlock(rid)
 					return nil, nil, err
 				}
 			}
-			lock.RoomLock.Unlock(rid)
-			lock.RoomLock.Delete(rid)
 			return r, nil, nil
 		} else {
 			if r.Started {
@@ -181,6 +215,7 @@ func LeaveRoom(ctx context.Context, pid string, rid string) (*entity.Room, *dto.
 					Loser:  r.Host,
 					Cause:  "escape",
 				}
+				ArchiveGame(ctx, r, gameOverDTO)
 			}
 			r.Host = r.Challenger
 		}
@@ -194,38 +229,36 @@ func LeaveRoom(ctx context.Context, pid string, rid string) (*entity.Room, *dto.
 				Loser:  r.Challenger,
 				Cause:  "escape",
 			}
+			ArchiveGame(ctx, r, gameOverDTO)
 		}
 		r.Challenger = entity.PlayerDetails{}
 	} else if role == "spectator" {
 		r.Spectators = append(r.Spectators[:i], r.Spectators[i+1:]...)
 	}
 
-	if err = redis.SetRoom(ctx, r); err != nil {
+	if err = stores.Rooms.Set(ctx, r); err != nil {
 		logger.Error(err)
-		lock.RoomLock.Unlock(rid)
 		return nil, nil, err
 	}
 
-	lock.RoomLock.Unlock(rid)
 	return r, gameOverDTO, nil
 }
 
 func RoomChat(ctx context.Context, rid string, msg *entity.DialogMsg) (*entity.Room, error) {
-	lock.RoomLock.Lock(rid)
-	defer lock.RoomLock.Unlock(rid)
-
-	r, err := redis.GetRoom(ctx, rid)
+	ctx, lease, err := lockRoom(ctx, rid)
 	if err != nil {
-		logger.Error(err)
 		return nil, err
 	}
+	defer lease.Unlock()
 
-	if len(r.Dialog) >= 10 {
-		r.Dialog = r.Dialog[1:]
+	r, err := stores.Rooms.Get(ctx, rid)
+	if err != nil {
+		logger.Error(err)
+		return nil, err
 	}
-	r.Dialog = append(r.Dialog, *msg)
 
-	if err = redis.SetRoom(ctx, r); err != nil {
+	r.Dialog = store.AppendDialog(r.Dialog, *msg)
+	if err = stores.Rooms.AppendChat(ctx, rid, msg); err != nil {
 		logger.Error(err)
 		return nil, err
 	}
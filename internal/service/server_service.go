@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"time"
+)
+
+var startTime = time.Now()
+
+func GetServerInfo(ctx context.Context) (*dto.ServerInfoDTO, error) {
+	players, err := stores.Players.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rooms, err := stores.Rooms.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ServerInfoDTO{
+		ProtocolVersion:    constants.ProtocolVersion,
+		MinProtocolVersion: constants.MinProtocolVersion,
+		StartTime:          startTime.Format(time.DateTime),
+		Uptime:             int64(time.Since(startTime).Seconds()),
+		Players:            len(*players),
+		Rooms:              len(*rooms),
+	}, nil
+}
@@ -0,0 +1,11 @@
+package service
+
+import "github.com/toujourser/gomoku/internal/store"
+
+// stores 服务使用的存储，启动时由 UseStores 注入
+var stores store.Stores
+
+// UseStores 注入存储，需要在处理任何请求之前调用
+func UseStores(s store.Stores) {
+	stores = s
+}
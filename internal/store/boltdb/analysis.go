@@ -0,0 +1,202 @@
+package boltdb
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+type reviews struct {
+	db *bolt.DB
+}
+
+func (s *reviews) Set(ctx context.Context, review *entity.Review) error {
+	if err := put(s.db, reviewBucket, review.GameId, review); err != nil {
+		return fmt.Errorf("error set review %v: %v", review.GameId, err)
+	}
+	return nil
+}
+
+func (s *reviews) Get(ctx context.Context, gid string) (*entity.Review, error) {
+	r := &entity.Review{}
+	ok, err := get(s.db, reviewBucket, gid, r)
+	if err != nil {
+		return nil, fmt.Errorf("error get review %v: %v", gid, err)
+	}
+	if !ok {
+		return nil, errcode.New(errcode.ReviewNotReady, errcode.Params{"gid": gid})
+	}
+	return r, nil
+}
+
+// openings 每个规范局面一条记录，值为 "<着法序号>:<结果>" 到对局数的 JSON
+type openings struct {
+	db *bolt.DB
+}
+
+// Add 一局对局的全部记录在一个事务内累加
+func (s *openings) Add(ctx context.Context, openings []store.Opening) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(explorerBucket)
+		for _, o := range openings {
+			counts := make(map[string]int64)
+			if b := bucket.Get([]byte(o.Key)); b != nil {
+				if err := json.Unmarshal(b, &counts); err != nil {
+					return fmt.Errorf("unmarshal: %v", err)
+				}
+			}
+			counts[fmt.Sprintf("%d:%s", o.Move, o.Winner)]++
+			b, err := json.Marshal(counts)
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put([]byte(o.Key), b); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error add openings: %v", err)
+	}
+	return nil
+}
+
+func (s *openings) Get(ctx context.Context, key string) (map[string]int64, error) {
+	counts := make(map[string]int64)
+	if _, err := get(s.db, explorerBucket, key, &counts); err != nil {
+		return nil, fmt.Errorf("error get openings %v: %v", key, err)
+	}
+	return counts, nil
+}
+
+// puzzles puzzle:index 的 key 为 8 字节大端导入时间加题目 id，按字节序即按导入顺序
+type puzzles struct {
+	db *bolt.DB
+}
+
+func (s *puzzles) Add(ctx context.Context, puzzle *entity.Puzzle) error {
+	b, err := json.Marshal(puzzle)
+	if err != nil {
+		return fmt.Errorf("error marshal puzzle: %v", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(puzzleBucket).Put([]byte(puzzle.Id), b); err != nil {
+			return err
+		}
+		key := append(binary.BigEndian.AppendUint64(nil, uint64(time.Now().UnixNano())), puzzle.Id...)
+		return tx.Bucket(puzzleIndexBucket).Put(key, []byte(puzzle.Id))
+	})
+	if err != nil {
+		return fmt.Errorf("error add puzzle %v: %v", puzzle.Id, err)
+	}
+	return nil
+}
+
+func (s *puzzles) Get(ctx context.Context, id string) (*entity.Puzzle, error) {
+	p := &entity.Puzzle{}
+	ok, err := get(s.db, puzzleBucket, id, p)
+	if err != nil {
+		return nil, fmt.Errorf("error get puzzle %v: %v", id, err)
+	}
+	if !ok {
+		return nil, errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": id})
+	}
+	return p, nil
+}
+
+func (s *puzzles) Ids(ctx context.Context, offset int64, limit int64) ([]string, error) {
+	ids := make([]string, 0)
+	if limit <= 0 {
+		return ids, nil
+	}
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(puzzleIndexBucket).Cursor()
+		skip := offset
+		for k, id := c.First(); k != nil && int64(len(ids)) < limit; k, id = c.Next() {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			ids = append(ids, string(id))
+		}
+		return nil
+	})
+	return ids, nil
+}
+
+func (s *puzzles) IdAt(ctx context.Context, n int64) (string, error) {
+	var id string
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		index := tx.Bucket(puzzleIndexBucket)
+		count := int64(index.Stats().KeyN)
+		if count == 0 {
+			return nil
+		}
+		c := index.Cursor()
+		k, v := c.First()
+		for i := int64(0); i < n%count && k != nil; i++ {
+			k, v = c.Next()
+		}
+		id = string(v)
+		return nil
+	})
+	if id == "" {
+		return "", errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": "daily"})
+	}
+	return id, nil
+}
+
+func (s *puzzles) SetAttempt(ctx context.Context, pid string, attempt *entity.PuzzleAttempt) error {
+	if err := put(s.db, puzzleAttemptBucket, pid, attempt); err != nil {
+		return fmt.Errorf("error set puzzle attempt %v: %v", pid, err)
+	}
+	return nil
+}
+
+func (s *puzzles) GetAttempt(ctx context.Context, pid string) (*entity.PuzzleAttempt, error) {
+	a := &entity.PuzzleAttempt{}
+	ok, err := get(s.db, puzzleAttemptBucket, pid, a)
+	if err != nil {
+		return nil, fmt.Errorf("error get puzzle attempt %v: %v", pid, err)
+	}
+	if !ok {
+		return nil, errcode.New(errcode.NoPuzzleAttempt, nil)
+	}
+	return a, nil
+}
+
+func (s *puzzles) DelAttempt(ctx context.Context, pid string) error {
+	if err := del(s.db, puzzleAttemptBucket, pid); err != nil {
+		return fmt.Errorf("error del puzzle attempt %v: %v", pid, err)
+	}
+	return nil
+}
+
+func (s *puzzles) AddSolved(ctx context.Context, name string, score float64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(puzzleSolvedBucket)
+		var total float64
+		if b := bucket.Get([]byte(name)); b != nil {
+			if err := json.Unmarshal(b, &total); err != nil {
+				return fmt.Errorf("unmarshal: %v", err)
+			}
+		}
+		b, err := json.Marshal(total + score)
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), b)
+	})
+	if err != nil {
+		return fmt.Errorf("error add puzzle score %v: %v", name, err)
+	}
+	return nil
+}
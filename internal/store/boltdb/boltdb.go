@@ -0,0 +1,518 @@
+// Package boltdb 基于嵌入式键值库 bbolt 的存储实现，数据保存在单个文件中，适用于不部署 Redis 的单实例运行。
+package boltdb
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/store"
+	bolt "go.etcd.io/bbolt"
+)
+
+// 各类数据所在的 bucket，gameIndex 的 key 为 8 字节大端结束时间加存档 id，按字节序即按结束时间排序
+var (
+	playerBucket      = []byte("player")
+	resumeBucket      = []byte("player:resume")
+	roomBucket        = []byte("room")
+	dialogBucket      = []byte("dialog")
+	gameBucket        = []byte("game")
+	gameIndexBucket   = []byte("game:index")
+	leaderboardBucket = []byte("leaderboard")
+	accountBucket     = []byte("account")
+	accountNameBucket = []byte("account:name")
+	sessionBucket     = []byte("session")
+
+	reviewBucket        = []byte("review")
+	explorerBucket      = []byte("explorer")
+	puzzleBucket        = []byte("puzzle")
+	puzzleIndexBucket   = []byte("puzzle:index")
+	puzzleAttemptBucket = []byte("puzzle:attempt")
+	puzzleSolvedBucket  = []byte("puzzle:solved")
+)
+
+// Open 打开或创建 path 处的数据文件，文件被占用时一秒后返回错误。
+// 返回的 io.Closer 用于关闭数据文件，关闭后不能再使用返回的存储。
+func Open(path string) (store.Stores, io.Closer, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return store.Stores{}, nil, fmt.Errorf("error create data dir: %v", err)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return store.Stores{}, nil, fmt.Errorf("error open %v: %v", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{playerBucket, resumeBucket, roomBucket, dialogBucket, gameBucket, gameIndexBucket, leaderboardBucket, accountBucket, accountNameBucket, sessionBucket,
+			reviewBucket, explorerBucket, puzzleBucket, puzzleIndexBucket, puzzleAttemptBucket, puzzleSolvedBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return store.Stores{}, nil, fmt.Errorf("error create buckets: %v", err)
+	}
+	return store.Stores{
+		Players:  &players{db},
+		Rooms:    &rooms{db},
+		Chat:     &chat{db},
+		Games:    &games{db},
+		Accounts: &accounts{db},
+		Reviews:  &reviews{db},
+		Openings: &openings{db},
+		Puzzles:  &puzzles{db},
+		Limiter:  store.NewLocalLimiter(),
+		Presence: store.NewLocalPresence(),
+		RoomLock: lock.NewLocalLock(),
+	}, db, nil
+}
+
+func put(db *bolt.DB, bucket []byte, key string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error marshal %s: %v", bucket, err)
+	}
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put([]byte(key), b)
+	})
+}
+
+// get 读取 key，不存在时返回 false
+func get(db *bolt.DB, bucket []byte, key string, v interface{}) (bool, error) {
+	var b []byte
+	_ = db.View(func(tx *bolt.Tx) error {
+		// 返回的切片只在事务内有效，需要复制
+		b = append(b, tx.Bucket(bucket).Get([]byte(key))...)
+		return nil
+	})
+	if b == nil {
+		return false, nil
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return false, fmt.Errorf("unmarshal: %v", err)
+	}
+	return true, nil
+}
+
+func del(db *bolt.DB, bucket []byte, key string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete([]byte(key))
+	})
+}
+
+// list 按 key 顺序解码 bucket 中的全部值
+func list[T any](db *bolt.DB, bucket []byte) (*[]T, error) {
+	out := make([]T, 0, 100)
+	err := db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, b []byte) error {
+			var v T
+			if err := json.Unmarshal(b, &v); err != nil {
+				return fmt.Errorf("unmarshal: %v", err)
+			}
+			out = append(out, v)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+type players struct {
+	db *bolt.DB
+}
+
+func (s *players) Set(ctx context.Context, player *entity.Player) error {
+	if err := put(s.db, playerBucket, player.Id, player); err != nil {
+		return fmt.Errorf("error add player: %v", err)
+	}
+	return nil
+}
+
+func (s *players) Get(ctx context.Context, id string) (*entity.Player, error) {
+	p := &entity.Player{}
+	ok, err := get(s.db, playerBucket, id, p)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": id})
+	}
+	return p, nil
+}
+
+func (s *players) List(ctx context.Context) (*[]entity.Player, error) {
+	return list[entity.Player](s.db, playerBucket)
+}
+
+func (s *players) Del(ctx context.Context, id string) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(playerBucket).Delete([]byte(id)); err != nil {
+			return err
+		}
+		return tx.Bucket(resumeBucket).Delete([]byte(id))
+	})
+	if err != nil {
+		return fmt.Errorf("error delete player %v: %v", id, err)
+	}
+	return nil
+}
+
+func (s *players) SetResumeToken(ctx context.Context, id string, token string) error {
+	if err := put(s.db, resumeBucket, id, token); err != nil {
+		return fmt.Errorf("error set resume token of %v: %v", id, err)
+	}
+	return nil
+}
+
+func (s *players) ResumeToken(ctx context.Context, id string) (string, error) {
+	var token string
+	if _, err := get(s.db, resumeBucket, id, &token); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// rooms 只有一个实例使用数据文件，房间锁不会被他人抢占，无需校验 fencing token
+type rooms struct {
+	db *bolt.DB
+}
+
+func (s *rooms) Set(ctx context.Context, room *entity.Room) error {
+	if err := put(s.db, roomBucket, room.Id, room); err != nil {
+		return fmt.Errorf("error set room %v: %v", room.Id, err)
+	}
+	return nil
+}
+
+func (s *rooms) Get(ctx context.Context, id string) (*entity.Room, error) {
+	r := &entity.Room{}
+	ok, err := get(s.db, roomBucket, id, r)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+	}
+	return r, nil
+}
+
+func (s *rooms) List(ctx context.Context) (*[]entity.Room, error) {
+	rooms, err := list[entity.Room](s.db, roomBucket)
+	if err != nil {
+		return nil, err
+	}
+	for k := range *rooms {
+		(*rooms)[k] = store.Brief(&(*rooms)[k])
+	}
+	return rooms, nil
+}
+
+func (s *rooms) Del(ctx context.Context, id string) error {
+	if err := del(s.db, roomBucket, id); err != nil {
+		return fmt.Errorf("error delete room %v: %v", id, err)
+	}
+	return nil
+}
+
+func (s *rooms) AppendMove(ctx context.Context, id string, c entity.Chess) error {
+	return s.update(id, func(r *entity.Room) { r.Steps = append(r.Steps, c) })
+}
+
+func (s *rooms) AppendChat(ctx context.Context, id string, msg *entity.DialogMsg) error {
+	return s.update(id, func(r *entity.Room) { r.Dialog = store.AppendDialog(r.Dialog, *msg) })
+}
+
+// update 在一个事务中读出房间、修改后写回
+func (s *rooms) update(id string, fn func(r *entity.Room)) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(roomBucket).Get([]byte(id))
+		if b == nil {
+			return errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+		}
+		r := &entity.Room{}
+		if err := json.Unmarshal(b, r); err != nil {
+			return fmt.Errorf("unmarshal: %v", err)
+		}
+		fn(r)
+		b, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("error marshal room: %v", err)
+		}
+		return tx.Bucket(roomBucket).Put([]byte(id), b)
+	})
+}
+
+// chat 消息的 key 为 bucket 的自增序号，超过 DialogSize 条时删除最早的消息
+type chat struct {
+	db *bolt.DB
+}
+
+func (s *chat) Add(ctx context.Context, msg *entity.DialogMsg) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return fmt.Errorf("error marshal msg: %v", err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(dialogBucket)
+		seq, err := bucket.NextSequence()
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put(binary.BigEndian.AppendUint64(nil, seq), b); err != nil {
+			return err
+		}
+		var old [][]byte
+		c := bucket.Cursor()
+		n := 0
+		for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+			if n++; n > store.DialogSize {
+				old = append(old, append([]byte(nil), k...))
+			}
+		}
+		for _, k := range old {
+			if err = bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error add dialog msg: %v", err)
+	}
+	return nil
+}
+
+func (s *chat) List(ctx context.Context) (*[]entity.DialogMsg, error) {
+	return list[entity.DialogMsg](s.db, dialogBucket)
+}
+
+type games struct {
+	db *bolt.DB
+}
+
+// Add 存档一局对局并更新胜局排行榜，与 Redis 实现一样在一个事务内完成
+func (s *games) Add(ctx context.Context, game *entity.Game) error {
+	b, err := json.Marshal(game)
+	if err != nil {
+		return fmt.Errorf("error marshal game: %v", err)
+	}
+	end, err := time.ParseInLocation(time.DateTime, game.EndTime, time.Local)
+	if err != nil {
+		end = time.Now()
+	}
+
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket(gameBucket).Put([]byte(game.Id), b); err != nil {
+			return err
+		}
+		key := append(binary.BigEndian.AppendUint64(nil, uint64(end.Unix())), game.Id...)
+		if err := tx.Bucket(gameIndexBucket).Put(key, []byte(game.Id)); err != nil {
+			return err
+		}
+		var winner entity.Player
+		switch game.Winner {
+		case "black":
+			winner = game.Black
+		case "white":
+			winner = game.White
+		default:
+			return nil
+		}
+		board := tx.Bucket(leaderboardBucket)
+		r := entity.Rank{}
+		if b := board.Get([]byte(winner.Id)); b != nil {
+			if err := json.Unmarshal(b, &r); err != nil {
+				return fmt.Errorf("unmarshal: %v", err)
+			}
+		}
+		r.Id, r.Name, r.Wins = winner.Id, winner.Name, r.Wins+1
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		return board.Put([]byte(winner.Id), b)
+	})
+	if err != nil {
+		return fmt.Errorf("error add game %v: %v", game.Id, err)
+	}
+	return nil
+}
+
+func (s *games) Get(ctx context.Context, id string) (*entity.Game, error) {
+	g := &entity.Game{}
+	ok, err := get(s.db, gameBucket, id, g)
+	if err != nil {
+		return nil, fmt.Errorf("error get game %v: %v", id, err)
+	}
+	if !ok {
+		return nil, errcode.New(errcode.GameNotFound, errcode.Params{"gid": id})
+	}
+	return g, nil
+}
+
+// List 从索引末尾向前遍历，按结束时间倒序分页
+func (s *games) List(ctx context.Context, offset int64, limit int64) (*[]entity.Game, error) {
+	games := make([]entity.Game, 0, limit)
+	if limit <= 0 {
+		return &games, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(gameBucket)
+		c := tx.Bucket(gameIndexBucket).Cursor()
+		skip := offset
+		for k, id := c.Last(); k != nil && int64(len(games)) < limit; k, id = c.Prev() {
+			if skip > 0 {
+				skip--
+				continue
+			}
+			b := data.Get(id)
+			if b == nil {
+				continue
+			}
+			g := entity.Game{}
+			if err := json.Unmarshal(b, &g); err != nil {
+				return fmt.Errorf("unmarshal: %v", err)
+			}
+			games = append(games, g)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error get games: %v", err)
+	}
+	return &games, nil
+}
+
+// Leaderboard 胜局数相同时与 Redis 的 ZREVRANGE 一样按玩家 id 倒序
+func (s *games) Leaderboard(ctx context.Context, limit int64) (*[]entity.Rank, error) {
+	ranks := make([]entity.Rank, 0, limit)
+	if limit <= 0 {
+		return &ranks, nil
+	}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(leaderboardBucket).ForEach(func(k, v []byte) error {
+			r := entity.Rank{}
+			if err := json.Unmarshal(v, &r); err != nil {
+				return fmt.Errorf("unmarshal: %v", err)
+			}
+			ranks = append(ranks, r)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error get leaderboard: %v", err)
+	}
+	store.SortRanks(ranks)
+	if int64(len(ranks)) > limit {
+		ranks = ranks[:limit]
+	}
+	return &ranks, nil
+}
+
+// accounts account:name 为名字到账号 id 的索引，session 为登录令牌
+type accounts struct {
+	db *bolt.DB
+}
+
+type session struct {
+	Id     string `json:"id"`
+	Expire int64  `json:"expire"`
+}
+
+func (s *accounts) Add(ctx context.Context, account *entity.Account) error {
+	b, err := json.Marshal(account)
+	if err != nil {
+		return fmt.Errorf("error marshal account: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		names := tx.Bucket(accountNameBucket)
+		if names.Get([]byte(account.Name)) != nil {
+			return errcode.New(errcode.AccountExists, errcode.Params{"name": account.Name})
+		}
+		if err := names.Put([]byte(account.Name), []byte(account.Id)); err != nil {
+			return err
+		}
+		return tx.Bucket(accountBucket).Put([]byte(account.Id), b)
+	})
+}
+
+func (s *accounts) Set(ctx context.Context, account *entity.Account) error {
+	if err := put(s.db, accountBucket, account.Id, account); err != nil {
+		return fmt.Errorf("error set account: %v", err)
+	}
+	return nil
+}
+
+func (s *accounts) Get(ctx context.Context, id string) (*entity.Account, error) {
+	a := &entity.Account{}
+	ok, err := get(s.db, accountBucket, id, a)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": id})
+	}
+	return a, nil
+}
+
+func (s *accounts) GetByName(ctx context.Context, name string) (*entity.Account, error) {
+	var id []byte
+	_ = s.db.View(func(tx *bolt.Tx) error {
+		id = append(id, tx.Bucket(accountNameBucket).Get([]byte(name))...)
+		return nil
+	})
+	if id == nil {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": name})
+	}
+	return s.Get(ctx, string(id))
+}
+
+// AddSession 保存令牌时顺便清理已过期的令牌
+func (s *accounts) AddSession(ctx context.Context, token string, id string, ttl time.Duration) error {
+	now := time.Now()
+	b, err := json.Marshal(session{Id: id, Expire: now.Add(ttl).Unix()})
+	if err != nil {
+		return fmt.Errorf("error marshal session: %v", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(sessionBucket)
+		var expired [][]byte
+		_ = bucket.ForEach(func(k, v []byte) error {
+			sess := session{}
+			if json.Unmarshal(v, &sess) != nil || sess.Expire < now.Unix() {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		for _, k := range expired {
+			if err := bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return bucket.Put([]byte(token), b)
+	})
+}
+
+func (s *accounts) Session(ctx context.Context, token string) (string, error) {
+	sess := session{}
+	ok, err := get(s.db, sessionBucket, token, &sess)
+	if err != nil {
+		return "", err
+	}
+	if !ok || sess.Expire < time.Now().Unix() {
+		return "", errcode.New(errcode.Unauthorized, nil)
+	}
+	return sess.Id, nil
+}
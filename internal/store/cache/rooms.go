@@ -0,0 +1,272 @@
+// Package cache 在共享存储之前保存本实例持有的房间，写操作先改内存再异步落盘。
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/pkg/logger"
+)
+
+// Rooms 本实例持有房间锁期间，房间以内存中的副本为准。
+//
+// 持锁的读写（ctx 携带 fencing token）只访问内存，修改过的房间由 Run 定期写入 backing，写入同样校验 fencing token；
+// 交出房间锁之前需要调用 Evict 把最新状态写回 backing，之后其他实例从 backing 读到的就是最新状态。
+// 实例崩溃时最多丢失最后一个落盘间隔内的修改。
+// 不持锁的读取优先返回内存中的副本，其他实例持有的房间可能比其内存中的状态落后一个落盘间隔。
+type Rooms struct {
+	backing store.RoomStore
+	lock    *lock.Lock // 写入被拒绝时丢弃对应的保留租约
+
+	mu      sync.Mutex
+	entries map[string]*entry
+
+	flushMu sync.Mutex // 保证同一房间的落盘按修改顺序进行
+}
+
+// entry room 为 nil 表示房间已删除，落盘后移除
+type entry struct {
+	room  *entity.Room
+	token int64
+	dirty bool
+}
+
+func NewRooms(backing store.RoomStore, l *lock.Lock) *Rooms {
+	return &Rooms{backing: backing, lock: l, entries: make(map[string]*entry)}
+}
+
+// clone 深拷贝，内存中的副本不会被调用方修改
+func clone(r *entity.Room) (*entity.Room, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal room: %v", err)
+	}
+	out := &entity.Room{}
+	if err = json.Unmarshal(b, out); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return out, nil
+}
+
+func (s *Rooms) Get(ctx context.Context, id string) (*entity.Room, error) {
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	s.mu.Unlock()
+	if ok {
+		if e.room == nil {
+			return nil, errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+		}
+		return clone(e.room)
+	}
+
+	r, err := s.backing.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// 持锁时载入内存，之后的读写不再访问 backing
+	if token, ok := lock.Token(ctx); ok {
+		c, err := clone(r)
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		if _, ok = s.entries[id]; !ok {
+			s.entries[id] = &entry{room: c, token: token}
+		}
+		s.mu.Unlock()
+	}
+	return r, nil
+}
+
+// List 以 backing 中的房间为基础，用内存中的副本覆盖
+func (s *Rooms) List(ctx context.Context) (*[]entity.Room, error) {
+	rooms, err := s.backing.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seen := make(map[string]bool, len(s.entries))
+	out := make([]entity.Room, 0, len(*rooms)+len(s.entries))
+	for _, r := range *rooms {
+		e, ok := s.entries[r.Id]
+		seen[r.Id] = true
+		if !ok {
+			out = append(out, r)
+			continue
+		}
+		if e.room == nil {
+			continue
+		}
+		out = append(out, store.Brief(e.room))
+	}
+	for id, e := range s.entries {
+		if seen[id] || e.room == nil {
+			continue
+		}
+		out = append(out, store.Brief(e.room))
+	}
+	return &out, nil
+}
+
+// Set 持锁时只修改内存，不持锁时直接写入 backing
+func (s *Rooms) Set(ctx context.Context, room *entity.Room) error {
+	token, ok := lock.Token(ctx)
+	if !ok {
+		s.drop(room.Id)
+		return s.backing.Set(ctx, room)
+	}
+	c, err := clone(room)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[room.Id] = &entry{room: c, token: token, dirty: true}
+	return nil
+}
+
+// Del 持锁时在内存中标记删除，不持锁时直接从 backing 删除
+func (s *Rooms) Del(ctx context.Context, id string) error {
+	token, ok := lock.Token(ctx)
+	if !ok {
+		s.drop(id)
+		return s.backing.Del(ctx, id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[id] = &entry{token: token, dirty: true}
+	return nil
+}
+
+// AppendMove 持锁时只修改内存，不持锁时直接写入 backing
+func (s *Rooms) AppendMove(ctx context.Context, id string, c entity.Chess) error {
+	if _, ok := lock.Token(ctx); !ok {
+		s.drop(id)
+		return s.backing.AppendMove(ctx, id, c)
+	}
+	return s.update(ctx, id, func(r *entity.Room) { r.Steps = append(r.Steps, c) })
+}
+
+// AppendChat 持锁时只修改内存，不持锁时直接写入 backing
+func (s *Rooms) AppendChat(ctx context.Context, id string, msg *entity.DialogMsg) error {
+	if _, ok := lock.Token(ctx); !ok {
+		s.drop(id)
+		return s.backing.AppendChat(ctx, id, msg)
+	}
+	return s.update(ctx, id, func(r *entity.Room) { r.Dialog = store.AppendDialog(r.Dialog, *msg) })
+}
+
+// update 持锁时修改内存中的副本，房间不在内存中时先从 backing 载入
+func (s *Rooms) update(ctx context.Context, id string, fn func(r *entity.Room)) error {
+	r, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	fn(r)
+	return s.Set(ctx, r)
+}
+
+func (s *Rooms) drop(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, id)
+}
+
+// Run 每隔 interval 把修改过的房间写入 backing，ctx 结束时写入剩余的修改后退出
+func (s *Rooms) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.Flush(context.Background())
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+		}
+	}
+}
+
+// Flush 把所有修改过的房间写入 backing
+func (s *Rooms) Flush(ctx context.Context) {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+
+	s.mu.Lock()
+	ids := make([]string, 0)
+	for id, e := range s.entries {
+		if e.dirty {
+			ids = append(ids, id)
+		}
+	}
+	s.mu.Unlock()
+	for _, id := range ids {
+		if err := s.flush(ctx, id, false); err != nil {
+			logger.Error(err)
+		}
+	}
+}
+
+// Evict 把房间的修改写入 backing 后移出内存，需要在交出房间锁之前调用。
+// 写入失败时房间留在内存中并返回错误，调用方不应交出房间锁，修改由 Run 重试写入。
+func (s *Rooms) Evict(ctx context.Context, id string) error {
+	s.flushMu.Lock()
+	defer s.flushMu.Unlock()
+	return s.flush(ctx, id, true)
+}
+
+// Forget 房间锁已被其他实例获取，内存中的副本作废
+func (s *Rooms) Forget(id string) {
+	s.drop(id)
+}
+
+// flush 写入一个房间，调用方需持有 flushMu。
+// 写入期间房间可能再次被修改，只有内存中仍是写入的版本时才清除修改标记。
+// 写入失败时保留房间和修改标记；房间锁已被其他实例获取时丢弃房间，不返回错误。
+func (s *Rooms) flush(ctx context.Context, id string, evict bool) error {
+	s.mu.Lock()
+	e, ok := s.entries[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	var err error
+	if e.dirty {
+		wctx := lock.WithToken(ctx, e.token)
+		if e.room == nil {
+			err = s.backing.Del(wctx, id)
+		} else {
+			err = s.backing.Set(wctx, e.room)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case errcode.Is(err, errcode.RoomBusy):
+		// 房间锁已被其他实例获取，内存中的修改作废
+		logger.WithField("rid", id).Warn("stale room dropped")
+		delete(s.entries, id)
+		s.lock.Drop(id, e.token)
+	case err != nil:
+		return err
+	case evict || e.room == nil:
+		if s.entries[id] == e {
+			delete(s.entries, id)
+		}
+	default:
+		if s.entries[id] == e {
+			e.dirty = false
+		}
+	}
+	return nil
+}
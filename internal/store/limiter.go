@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// localLimiter 进程内的固定窗口限流，用于不共享存储的单实例运行
+type localLimiter struct {
+	mu      sync.Mutex
+	windows map[string]*rateWindow
+}
+
+type rateWindow struct {
+	n     int64
+	reset time.Time
+}
+
+// NewLocalLimiter 创建进程内的限流器，计数只在当前进程有效
+func NewLocalLimiter() RateLimiter {
+	return &localLimiter{windows: make(map[string]*rateWindow)}
+}
+
+func (l *localLimiter) Allow(ctx context.Context, key string, limit int64, window time.Duration) (bool, time.Duration, error) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.reset) {
+		// 顺便清理过期的窗口，避免断开的玩家一直占用内存
+		for k, v := range l.windows {
+			if !now.Before(v.reset) {
+				delete(l.windows, k)
+			}
+		}
+		w = &rateWindow{reset: now.Add(window)}
+		l.windows[key] = w
+	}
+	w.n++
+	if w.n <= limit {
+		return true, 0, nil
+	}
+	return false, w.reset.Sub(now), nil
+}
@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/store"
+)
+
+type reviews struct {
+	mu sync.RWMutex
+	m  map[string]*entity.Review
+}
+
+func (s *reviews) Set(ctx context.Context, review *entity.Review) error {
+	r, err := clone(review)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[r.GameId] = r
+	return nil
+}
+
+func (s *reviews) Get(ctx context.Context, gid string) (*entity.Review, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.m[gid]
+	if !ok {
+		return nil, errcode.New(errcode.ReviewNotReady, errcode.Params{"gid": gid})
+	}
+	return clone(r)
+}
+
+type openings struct {
+	mu sync.RWMutex
+	m  map[string]map[string]int64
+}
+
+func (s *openings) Add(ctx context.Context, openings []store.Opening) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range openings {
+		if s.m[o.Key] == nil {
+			s.m[o.Key] = make(map[string]int64)
+		}
+		s.m[o.Key][fmt.Sprintf("%d:%s", o.Move, o.Winner)]++
+	}
+	return nil
+}
+
+func (s *openings) Get(ctx context.Context, key string) (map[string]int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	counts := make(map[string]int64, len(s.m[key]))
+	for field, n := range s.m[key] {
+		counts[field] = n
+	}
+	return counts, nil
+}
+
+// puzzles order 为导入顺序
+type puzzles struct {
+	mu       sync.RWMutex
+	m        map[string]*entity.Puzzle
+	order    []string
+	attempts map[string]*entity.PuzzleAttempt
+	solved   map[string]float64
+}
+
+func (s *puzzles) Add(ctx context.Context, puzzle *entity.Puzzle) error {
+	p, err := clone(puzzle)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[p.Id]; !ok {
+		s.order = append(s.order, p.Id)
+	}
+	s.m[p.Id] = p
+	return nil
+}
+
+func (s *puzzles) Get(ctx context.Context, id string) (*entity.Puzzle, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.m[id]
+	if !ok {
+		return nil, errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": id})
+	}
+	return clone(p)
+}
+
+func (s *puzzles) Ids(ctx context.Context, offset int64, limit int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ids := make([]string, 0)
+	if limit <= 0 || offset >= int64(len(s.order)) {
+		return ids, nil
+	}
+	end := offset + limit
+	if end > int64(len(s.order)) {
+		end = int64(len(s.order))
+	}
+	return append(ids, s.order[offset:end]...), nil
+}
+
+func (s *puzzles) IdAt(ctx context.Context, n int64) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.order) == 0 {
+		return "", errcode.New(errcode.PuzzleNotFound, errcode.Params{"id": "daily"})
+	}
+	return s.order[n%int64(len(s.order))], nil
+}
+
+func (s *puzzles) SetAttempt(ctx context.Context, pid string, attempt *entity.PuzzleAttempt) error {
+	a, err := clone(attempt)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[pid] = a
+	return nil
+}
+
+func (s *puzzles) GetAttempt(ctx context.Context, pid string) (*entity.PuzzleAttempt, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.attempts[pid]
+	if !ok {
+		return nil, errcode.New(errcode.NoPuzzleAttempt, nil)
+	}
+	return clone(a)
+}
+
+func (s *puzzles) DelAttempt(ctx context.Context, pid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, pid)
+	return nil
+}
+
+func (s *puzzles) AddSolved(ctx context.Context, name string, score float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.solved[name] += score
+	return nil
+}
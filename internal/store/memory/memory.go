@@ -0,0 +1,386 @@
+// Package memory 进程内的存储实现，用于测试和不依赖 Redis 的单实例运行，重启后数据丢失。
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/store"
+)
+
+// NewStores 创建一组空的进程内存储，房间使用进程内的锁
+func NewStores() store.Stores {
+	return store.Stores{
+		Players: &players{m: make(map[string]*entity.Player), tokens: make(map[string]string)},
+		Rooms:   &rooms{m: make(map[string]*entity.Room)},
+		Chat:    &chat{},
+		Games:   &games{m: make(map[string]*entity.Game), wins: make(map[string]*entity.Rank)},
+		Accounts: &accounts{
+			m:        make(map[string]*entity.Account),
+			names:    make(map[string]string),
+			sessions: make(map[string]session),
+		},
+		Reviews:  &reviews{m: make(map[string]*entity.Review)},
+		Openings: &openings{m: make(map[string]map[string]int64)},
+		Puzzles: &puzzles{
+			m:        make(map[string]*entity.Puzzle),
+			attempts: make(map[string]*entity.PuzzleAttempt),
+			solved:   make(map[string]float64),
+		},
+		Limiter:  store.NewLocalLimiter(),
+		Presence: store.NewLocalPresence(),
+		RoomLock: lock.NewLocalLock(),
+	}
+}
+
+// clone 深拷贝，保证与 Redis 实现一样读写的都是副本，调用方修改返回值不会影响存储
+func clone[T any](v *T) (*T, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error marshal: %v", err)
+	}
+	out := new(T)
+	if err = json.Unmarshal(b, out); err != nil {
+		return nil, fmt.Errorf("unmarshal: %v", err)
+	}
+	return out, nil
+}
+
+type players struct {
+	mu     sync.RWMutex
+	m      map[string]*entity.Player
+	tokens map[string]string // 重连令牌
+}
+
+func (s *players) Set(ctx context.Context, player *entity.Player) error {
+	p, err := clone(player)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[p.Id] = p
+	return nil
+}
+
+func (s *players) Get(ctx context.Context, id string) (*entity.Player, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	p, ok := s.m[id]
+	if !ok {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": id})
+	}
+	return clone(p)
+}
+
+func (s *players) List(ctx context.Context) (*[]entity.Player, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]entity.Player, 0, len(s.m))
+	for _, p := range s.m {
+		c, err := clone(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *c)
+	}
+	return &out, nil
+}
+
+func (s *players) Del(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, id)
+	delete(s.tokens, id)
+	return nil
+}
+
+func (s *players) SetResumeToken(ctx context.Context, id string, token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[id] = token
+	return nil
+}
+
+func (s *players) ResumeToken(ctx context.Context, id string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tokens[id], nil
+}
+
+// rooms 进程内只有一个实例，房间锁不会被他人抢占，无需校验 fencing token
+type rooms struct {
+	mu sync.RWMutex
+	m  map[string]*entity.Room
+}
+
+func (s *rooms) Set(ctx context.Context, room *entity.Room) error {
+	r, err := clone(room)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[r.Id] = r
+	return nil
+}
+
+func (s *rooms) Get(ctx context.Context, id string) (*entity.Room, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.m[id]
+	if !ok {
+		return nil, errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+	}
+	return clone(r)
+}
+
+func (s *rooms) List(ctx context.Context) (*[]entity.Room, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]entity.Room, 0, len(s.m))
+	for _, r := range s.m {
+		c, err := clone(r)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, store.Brief(c))
+	}
+	return &out, nil
+}
+
+func (s *rooms) Del(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.m, id)
+	return nil
+}
+
+func (s *rooms) AppendMove(ctx context.Context, id string, c entity.Chess) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.m[id]
+	if !ok {
+		return errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+	}
+	r.Steps = append(r.Steps, c)
+	return nil
+}
+
+func (s *rooms) AppendChat(ctx context.Context, id string, msg *entity.DialogMsg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.m[id]
+	if !ok {
+		return errcode.New(errcode.RoomNotFound, errcode.Params{"rid": id})
+	}
+	r.Dialog = store.AppendDialog(r.Dialog, *msg)
+	return nil
+}
+
+type chat struct {
+	mu     sync.RWMutex
+	dialog []entity.DialogMsg
+}
+
+func (s *chat) Add(ctx context.Context, msg *entity.DialogMsg) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dialog = append(s.dialog, *msg)
+	if len(s.dialog) > store.DialogSize {
+		s.dialog = append([]entity.DialogMsg(nil), s.dialog[len(s.dialog)-store.DialogSize:]...)
+	}
+	return nil
+}
+
+func (s *chat) List(ctx context.Context) (*[]entity.DialogMsg, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	dialog := append(make([]entity.DialogMsg, 0, store.DialogSize), s.dialog...)
+	return &dialog, nil
+}
+
+// games index 按结束时间升序，wins 按玩家 id 记录胜局数
+type games struct {
+	mu    sync.RWMutex
+	m     map[string]*entity.Game
+	index []indexed
+	wins  map[string]*entity.Rank
+}
+
+type indexed struct {
+	id  string
+	end int64
+}
+
+func (s *games) Add(ctx context.Context, game *entity.Game) error {
+	g, err := clone(game)
+	if err != nil {
+		return err
+	}
+	end, err := time.ParseInLocation(time.DateTime, g.EndTime, time.Local)
+	if err != nil {
+		end = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.m[g.Id]; !ok {
+		i := sort.Search(len(s.index), func(i int) bool { return s.index[i].end > end.Unix() })
+		s.index = append(s.index, indexed{})
+		copy(s.index[i+1:], s.index[i:])
+		s.index[i] = indexed{id: g.Id, end: end.Unix()}
+	}
+	s.m[g.Id] = g
+	var winner entity.Player
+	switch g.Winner {
+	case "black":
+		winner = g.Black
+	case "white":
+		winner = g.White
+	default:
+		return nil
+	}
+	r, ok := s.wins[winner.Id]
+	if !ok {
+		r = &entity.Rank{Id: winner.Id}
+		s.wins[winner.Id] = r
+	}
+	r.Name = winner.Name
+	r.Wins++
+	return nil
+}
+
+func (s *games) Get(ctx context.Context, id string) (*entity.Game, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	g, ok := s.m[id]
+	if !ok {
+		return nil, errcode.New(errcode.GameNotFound, errcode.Params{"gid": id})
+	}
+	return clone(g)
+}
+
+func (s *games) List(ctx context.Context, offset int64, limit int64) (*[]entity.Game, error) {
+	if limit <= 0 {
+		return &[]entity.Game{}, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]entity.Game, 0, limit)
+	for i := int64(len(s.index)) - 1 - offset; i >= 0 && int64(len(out)) < limit; i-- {
+		g, err := clone(s.m[s.index[i].id])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *g)
+	}
+	return &out, nil
+}
+
+// Leaderboard 胜局数相同时与 Redis 的 ZREVRANGE 一样按玩家 id 倒序
+func (s *games) Leaderboard(ctx context.Context, limit int64) (*[]entity.Rank, error) {
+	if limit <= 0 {
+		return &[]entity.Rank{}, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	ranks := make([]entity.Rank, 0, len(s.wins))
+	for _, r := range s.wins {
+		ranks = append(ranks, *r)
+	}
+	store.SortRanks(ranks)
+	if int64(len(ranks)) > limit {
+		ranks = ranks[:limit]
+	}
+	return &ranks, nil
+}
+
+// accounts names 为名字到账号 id 的索引，sessions 为登录令牌
+type accounts struct {
+	mu       sync.RWMutex
+	m        map[string]*entity.Account
+	names    map[string]string
+	sessions map[string]session
+}
+
+type session struct {
+	id     string
+	expire time.Time
+}
+
+func (s *accounts) Add(ctx context.Context, account *entity.Account) error {
+	a, err := clone(account)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.names[a.Name]; ok {
+		return errcode.New(errcode.AccountExists, errcode.Params{"name": a.Name})
+	}
+	s.names[a.Name] = a.Id
+	s.m[a.Id] = a
+	return nil
+}
+
+func (s *accounts) Set(ctx context.Context, account *entity.Account) error {
+	a, err := clone(account)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.m[a.Id] = a
+	return nil
+}
+
+func (s *accounts) Get(ctx context.Context, id string) (*entity.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a, ok := s.m[id]
+	if !ok {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": id})
+	}
+	return clone(a)
+}
+
+func (s *accounts) GetByName(ctx context.Context, name string) (*entity.Account, error) {
+	s.mu.RLock()
+	id, ok := s.names[name]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, errcode.New(errcode.PlayerNotFound, errcode.Params{"pid": name})
+	}
+	return s.Get(ctx, id)
+}
+
+func (s *accounts) AddSession(ctx context.Context, token string, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for t, sess := range s.sessions {
+		if now.After(sess.expire) {
+			delete(s.sessions, t)
+		}
+	}
+	s.sessions[token] = session{id: id, expire: now.Add(ttl)}
+	return nil
+}
+
+func (s *accounts) Session(ctx context.Context, token string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sess, ok := s.sessions[token]
+	if !ok || time.Now().After(sess.expire) {
+		return "", errcode.New(errcode.Unauthorized, nil)
+	}
+	return sess.id, nil
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// localPresence 进程内的在线状态，用于不共享存储的单实例运行
+type localPresence struct {
+	mu        sync.Mutex
+	players   map[string]string              // 玩家 id 到实例
+	instances map[string]map[string]struct{} // 实例上的玩家
+	beats     map[string]time.Time           // 实例心跳的过期时间
+}
+
+// NewLocalPresence 创建进程内的在线状态，只在当前进程有效
+func NewLocalPresence() PresenceStore {
+	return &localPresence{
+		players:   make(map[string]string),
+		instances: make(map[string]map[string]struct{}),
+		beats:     make(map[string]time.Time),
+	}
+}
+
+func (p *localPresence) SetOnline(ctx context.Context, pid string, instance string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.players[pid] = instance
+	if p.instances[instance] == nil {
+		p.instances[instance] = make(map[string]struct{})
+	}
+	p.instances[instance][pid] = struct{}{}
+	return nil
+}
+
+func (p *localPresence) SetOffline(ctx context.Context, pid string, instance string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.instances[instance], pid)
+	if p.players[pid] == instance {
+		delete(p.players, pid)
+	}
+	return nil
+}
+
+func (p *localPresence) Get(ctx context.Context, pid string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.players[pid], nil
+}
+
+func (p *localPresence) InstancePlayers(ctx context.Context, instance string) ([]string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pids := make([]string, 0, len(p.instances[instance]))
+	for pid := range p.instances[instance] {
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+func (p *localPresence) DelInstance(ctx context.Context, instance string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.instances, instance)
+	return nil
+}
+
+func (p *localPresence) Heartbeat(ctx context.Context, instance string, ttl time.Duration) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.beats[instance] = time.Now().Add(ttl)
+	return nil
+}
+
+func (p *localPresence) IsAlive(ctx context.Context, instance string) (bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	deadline, ok := p.beats[instance]
+	return ok && time.Now().Before(deadline), nil
+}
@@ -0,0 +1,171 @@
+// Package store 定义服务层使用的存储接口，具体实现见 internal/redis 和 internal/store/memory。
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/lock"
+)
+
+// PlayerStore 在线玩家，不存在时返回 PlayerNotFound。
+// 重连令牌与玩家分开保存，不会随玩家信息发给其他连接，Del 时一并删除
+type PlayerStore interface {
+	Set(ctx context.Context, player *entity.Player) error
+	Get(ctx context.Context, id string) (*entity.Player, error)
+	List(ctx context.Context) (*[]entity.Player, error)
+	Del(ctx context.Context, id string) error
+	SetResumeToken(ctx context.Context, id string, token string) error
+	// ResumeToken 没有重连令牌时返回空字符串
+	ResumeToken(ctx context.Context, id string) (string, error)
+}
+
+// RoomStore 房间，不存在时返回 RoomNotFound。
+// ctx 持有房间锁时，写操作需要校验 fencing token，锁已失效返回 RoomBusy。
+type RoomStore interface {
+	Set(ctx context.Context, room *entity.Room) error
+	Get(ctx context.Context, id string) (*entity.Room, error)
+	// List 返回全部房间的 Brief，需要着法或聊天时用 Get 读取单个房间
+	List(ctx context.Context) (*[]entity.Room, error)
+	Del(ctx context.Context, id string) error
+	// AppendMove 在房间的着法末尾追加一步，不改写房间的其他部分
+	AppendMove(ctx context.Context, id string, c entity.Chess) error
+	// AppendChat 追加一条房间聊天，只保留最近的 DialogSize 条
+	AppendChat(ctx context.Context, id string, msg *entity.DialogMsg) error
+}
+
+// ChatStore 大厅聊天，只保留最近的 DialogSize 条
+type ChatStore interface {
+	Add(ctx context.Context, msg *entity.DialogMsg) error
+	List(ctx context.Context) (*[]entity.DialogMsg, error)
+}
+
+// GameStore 对局存档和按玩家 id 统计的胜局排行榜，不存在时返回 GameNotFound
+type GameStore interface {
+	Add(ctx context.Context, game *entity.Game) error
+	Get(ctx context.Context, id string) (*entity.Game, error)
+	// List 按结束时间倒序分页
+	List(ctx context.Context, offset int64, limit int64) (*[]entity.Game, error)
+	Leaderboard(ctx context.Context, limit int64) (*[]entity.Rank, error)
+}
+
+// AccountStore 注册账号，不存在时返回 PlayerNotFound
+type AccountStore interface {
+	// Add 新建账号，名字已被注册时返回 AccountExists
+	Add(ctx context.Context, account *entity.Account) error
+	Set(ctx context.Context, account *entity.Account) error
+	Get(ctx context.Context, id string) (*entity.Account, error)
+	GetByName(ctx context.Context, name string) (*entity.Account, error)
+	// AddSession 保存登录令牌，ttl 后过期
+	AddSession(ctx context.Context, token string, id string, ttl time.Duration) error
+	// Session 返回令牌对应的账号 id，令牌不存在或已过期时返回 Unauthorized
+	Session(ctx context.Context, token string) (string, error)
+}
+
+// ReviewStore 对局复盘，key 为存档 id，尚未生成时返回 ReviewNotReady
+type ReviewStore interface {
+	Set(ctx context.Context, review *entity.Review) error
+	Get(ctx context.Context, gid string) (*entity.Review, error)
+}
+
+// Opening 开局库中的一条记录：在规范局面 Key 下走了 Move（规范坐标的序号），对局结果为 Winner
+type Opening struct {
+	Key    string
+	Move   int
+	Winner string
+}
+
+// OpeningStore 开局库，按规范局面统计各着法和结果的对局数
+type OpeningStore interface {
+	// Add 累加一局对局的全部开局记录
+	Add(ctx context.Context, openings []Opening) error
+	// Get 返回规范局面 key 下的计数，map 的 key 为 "<着法序号>:<结果>"
+	Get(ctx context.Context, key string) (map[string]int64, error)
+}
+
+// PuzzleStore 题目、玩家正在进行的解题和解题积分。
+// 题目不存在时返回 PuzzleNotFound，没有进行中的解题时返回 NoPuzzleAttempt
+type PuzzleStore interface {
+	Add(ctx context.Context, puzzle *entity.Puzzle) error
+	Get(ctx context.Context, id string) (*entity.Puzzle, error)
+	// Ids 按导入顺序分页返回题目 id
+	Ids(ctx context.Context, offset int64, limit int64) ([]string, error)
+	// IdAt 返回导入顺序中第 n 个（对总数取模）题目的 id，用于每日一题轮换
+	IdAt(ctx context.Context, n int64) (string, error)
+	SetAttempt(ctx context.Context, pid string, attempt *entity.PuzzleAttempt) error
+	GetAttempt(ctx context.Context, pid string) (*entity.PuzzleAttempt, error)
+	DelAttempt(ctx context.Context, pid string) error
+	// AddSolved 累加玩家的解题积分
+	AddSolved(ctx context.Context, name string, score float64) error
+}
+
+// RateLimiter 固定窗口限流：key 在 window 内最多允许 limit 次，超出时返回 false 和窗口剩余时间
+type RateLimiter interface {
+	Allow(ctx context.Context, key string, limit int64, window time.Duration) (bool, time.Duration, error)
+}
+
+// PresenceStore 跨实例的在线状态：玩家连接在哪个实例上，以及各实例的心跳
+type PresenceStore interface {
+	SetOnline(ctx context.Context, pid string, instance string) error
+	// SetOffline 只有玩家仍记录在 instance 上时才清除，不会覆盖玩家在其他实例上的新连接
+	SetOffline(ctx context.Context, pid string, instance string) error
+	// Get 返回玩家所在的实例，玩家不在线时返回空字符串
+	Get(ctx context.Context, pid string) (string, error)
+	InstancePlayers(ctx context.Context, instance string) ([]string, error)
+	// DelInstance 删除已下线实例的玩家集合
+	DelInstance(ctx context.Context, instance string) error
+	// Heartbeat 刷新实例的心跳，超过 ttl 没有刷新视为下线
+	Heartbeat(ctx context.Context, instance string, ttl time.Duration) error
+	IsAlive(ctx context.Context, instance string) (bool, error)
+}
+
+// SortRanks 按胜局数倒序排列，胜局数相同时与 Redis 的 ZREVRANGE 一样按玩家 id 倒序
+func SortRanks(ranks []entity.Rank) {
+	sort.Slice(ranks, func(x, y int) bool {
+		if ranks[x].Wins != ranks[y].Wins {
+			return ranks[x].Wins > ranks[y].Wins
+		}
+		return ranks[x].Id > ranks[y].Id
+	})
+}
+
+// DialogSize 大厅和房间聊天保留的消息数
+const DialogSize = 10
+
+// AppendDialog 追加一条聊天，超过 DialogSize 条时丢弃最早的消息
+func AppendDialog(dialog []entity.DialogMsg, msg entity.DialogMsg) []entity.DialogMsg {
+	dialog = append(dialog, msg)
+	if len(dialog) > DialogSize {
+		dialog = append(dialog[:0:0], dialog[len(dialog)-DialogSize:]...)
+	}
+	return dialog
+}
+
+// Brief 不含着法、聊天和变化树的房间副本，用于房间列表
+func Brief(room *entity.Room) entity.Room {
+	r := *room
+	r.Dialog = make([]entity.DialogMsg, 0)
+	r.Steps = make([]entity.Chess, 0)
+	r.Tree = nil
+	r.Spectators = append(make([]entity.Player, 0, len(room.Spectators)), room.Spectators...)
+	return r
+}
+
+// Stores 一个存储后端提供的全部存储，RoomLock 为与之配套的房间锁。
+// Shared 表示存储由多个实例共享，此时玩家是否在线以跨实例的在线状态为准。
+type Stores struct {
+	Players  PlayerStore
+	Rooms    RoomStore
+	Chat     ChatStore
+	Games    GameStore
+	Accounts AccountStore
+	Reviews  ReviewStore
+	Openings OpeningStore
+	Puzzles  PuzzleStore
+	Limiter  RateLimiter
+	Presence PresenceStore
+	RoomLock *lock.Lock
+	Shared   bool
+}
@@ -1,6 +1,9 @@
 package util
 
-import "github.com/toujourser/gomoku/internal/entity"
+import (
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/entity"
+)
 
 func HasStep(i int8, j int8, color int8, steps *[]entity.Chess) bool {
 	for k := int(color); k < len(*steps); k += 2 {
@@ -18,14 +21,14 @@ func HasStep(i int8, j int8, color int8, steps *[]entity.Chess) bool {
 // 如果连续棋子数量达到 5 或更多，则返回 true，表示达成了五子连珠的条件。
 func checkFiveInDirection(i int8, j int8, color int8, x int8, y int8, steps *[]entity.Chess) bool {
 	count := 1
-	for m, n := i-x, j-y; m >= 0 && n >= 0 && m < 15 && n < 15; m, n = m-x, n-y {
+	for m, n := i-x, j-y; m >= 0 && n >= 0 && m < constants.BoardSize && n < constants.BoardSize; m, n = m-x, n-y {
 		if HasStep(m, n, color, steps) {
 			count++
 		} else {
 			break
 		}
 	}
-	for m, n := i+x, j+y; m >= 0 && n >= 0 && m < 15 && n < 15; m, n = m+x, n+y {
+	for m, n := i+x, j+y; m >= 0 && n >= 0 && m < constants.BoardSize && n < constants.BoardSize; m, n = m+x, n+y {
 		if HasStep(m, n, color, steps) {
 			count++
 		} else {
@@ -2,28 +2,26 @@ package websocket
 
 import (
 	"context"
-	"fmt"
+	"encoding/json"
 	"github.com/olahol/melody"
 	"github.com/toujourser/gomoku/internal/constants"
 	"github.com/toujourser/gomoku/internal/dto"
 	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
 	"github.com/toujourser/gomoku/internal/service"
 	"time"
 )
 
-func (ms *MelodySocket) HallChat(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) HallChat(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	id, _ := s.Get("id")
 	content, ok := msg.Data.(string)
 	if !ok {
-		err := fmt.Errorf("interface conversion: data is not string")
-		SendErr(s, err)
-		return
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
 	}
 
 	p, err := service.GetPlayer(ctx, id.(string))
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	dialogMsg := &entity.DialogMsg{
@@ -32,111 +30,156 @@ func (ms *MelodySocket) HallChat(ctx context.Context, s *melody.Session, msg *dt
 		Content: content,
 	}
 	if err := service.HallChat(ctx, dialogMsg); err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 	msg = &dto.Message{
 		Code: constants.HallChat,
 		Data: *dialogMsg,
 	}
-	ms.Broadcast(msg)
+	ms.Publish(constants.TopicHall, msg)
+	return nil
 }
 
-func (ms *MelodySocket) GetHallDialog(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) GetHallDialog(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	dialog, err := service.GetHallDialog(ctx)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 	msg.Data = dialog
 	Send(s, msg)
+	return nil
 }
 
-func (ms *MelodySocket) GetRooms(ctx context.Context, s *melody.Session, msg *dto.Message) {
-	rooms, err := service.GetRooms(ctx)
+// GetRooms 获取大厅房间。协议版本 2 起 data 为可选的查询条件 dto.LobbyQuery，返回一页房间摘要；
+// 版本 1 返回完整的房间列表
+func (ms *MelodySocket) GetRooms(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	if GetVersion(s) < constants.LobbyVersion {
+		rooms, err := service.GetRooms(ctx)
+		if err != nil {
+			return err
+		}
+		msg.Data = rooms
+		Send(s, msg)
+		return nil
+	}
+
+	q := &dto.LobbyQuery{}
+	if msg.Data != nil {
+		if err := decodeData(msg.Data, q); err != nil {
+			return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+		}
+	}
+	lobby, err := service.Lobby(ctx, q)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
-	msg.Data = rooms
+	// 记住查询条件，版本 2 的连接推送大厅更新、之后的版本订阅大厅时按该条件取页
+	s.Set("lobby", q)
+	msg.Data = lobby
 	Send(s, msg)
+	return nil
 }
 
-func (ms *MelodySocket) CreateRoom(ctx context.Context, s *melody.Session, msg *dto.Message) {
+// CreateRoom 创建房间，data 为房主的颜色，或 {"color": 0, "rated": false, "hints": true, "analysis": false}
+func (ms *MelodySocket) CreateRoom(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
-	color, ok := msg.Data.(float64)
-	if !ok {
-		err := fmt.Errorf("interface conversion: data is not a number")
-		SendErr(s, err)
-		return
-	}
-
-	room, err := service.CreateRoom(ctx, pid, int8(color))
+	var color float64
+	settings := entity.RoomSettings{Hints: true}
+	switch data := msg.Data.(type) {
+	case float64:
+		color = data
+	case map[string]interface{}:
+		var ok bool
+		if color, ok = data["color"].(float64); !ok {
+			return errcode.New(errcode.InvalidParam, errcode.Params{"field": "color"})
+		}
+		if rated, ok := data["rated"].(bool); ok {
+			settings.Rated = rated
+			settings.Hints = !rated
+		}
+		if hints, ok := data["hints"].(bool); ok {
+			settings.Hints = hints
+		}
+		settings.Analysis, _ = data["analysis"].(bool)
+	default:
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+
+	room, err := service.CreateRoom(ctx, pid, int8(color), settings)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	msg.Data = room
 	Send(s, msg)
-
-	rooms, err := service.GetRooms(ctx)
-	if err != nil {
-		SendErr(s, err)
-		return
-	}
-	ms.Broadcast(&dto.Message{
-		Code: constants.GetRooms,
-		Data: rooms,
-	})
+	ms.LobbyChanged(dto.DeltaAdd, room)
+	return nil
 }
 
-func (ms *MelodySocket) EnterRoom(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) EnterRoom(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
 
-	data := msg.Data.(map[string]interface{})
-	rid := data["rid"].(string)
-	role := data["role"].(string)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	role, okRole := data["role"].(string)
+	if !okR || !okRole {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
 
 	room, err := service.EnterRoom(ctx, pid, rid, role)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	msg.Data = room
 	ms.Send2Room(room, msg)
+	ms.LobbyChanged(dto.DeltaUpdate, room)
+	if role == "spectator" {
+		ms.playersUpdated(ctx, pid)
+	}
+	return nil
 }
 
-func (ms *MelodySocket) LeaveRoom(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) LeaveRoom(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
 
 	rid, ok := msg.Data.(string)
 	if !ok {
-		err := fmt.Errorf("error: data is not string")
-		SendErr(s, err)
-		return
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
 	}
 
-	ms.SendLeaveRoom(ctx, s, pid, rid)
+	return ms.SendLeaveRoom(ctx, s, pid, rid)
 }
 
-func (ms *MelodySocket) RoomChat(ctx context.Context, s *melody.Session, msg *dto.Message) {
-	data := msg.Data.(map[string]interface{})
-	from := data["from"].(string)
-	content := data["content"].(string)
-	rid := data["rid"].(string)
+// RoomChat 房间聊天，data 为 {"rid": "...", "content": "..."}，发送者取当前连接的玩家
+func (ms *MelodySocket) RoomChat(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	content, okC := data["content"].(string)
+	rid, okR := data["rid"].(string)
+	if !okC || !okR {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
 
+	p, err := service.GetPlayer(ctx, pid)
+	if err != nil {
+		return err
+	}
 	dialogMsg := &entity.DialogMsg{
 		Time:    time.Now().Format("2006-01-02 15:04:05"),
-		From:    from,
+		From:    p.Name,
 		Content: content,
 	}
 
 	room, err := service.RoomChat(ctx, rid, dialogMsg)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	roomChatDTO := struct {
@@ -149,109 +192,104 @@ func (ms *MelodySocket) RoomChat(ctx context.Context, s *melody.Session, msg *dt
 
 	msg.Data = roomChatDTO
 	ms.Send2Room(room, msg)
+	return nil
 }
 
-func (ms *MelodySocket) GetPlayer(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) GetPlayer(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
 	player, err := service.GetPlayer(ctx, pid)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	msg.Data = player
 	Send(s, msg)
+	return nil
 }
 
-func (ms *MelodySocket) GetPlayers(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) GetPlayers(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	players, err := service.GetPlayers(ctx)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	msg.Data = players
 	Send(s, msg)
+	return nil
 }
 
-func (ms *MelodySocket) PlayerRename(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) PlayerRename(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
 	name, ok := msg.Data.(string)
 	if !ok {
-		err := fmt.Errorf("interface conversion: data is not string")
-		SendErr(s, err)
-		return
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
 	}
 
 	if err := service.PlayerRename(ctx, pid, name); err != nil {
-		SendErr(s, err)
-		return
-	}
-
-	msg.Code = constants.GetPlayers
-	players, err := service.GetPlayers(ctx)
-	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
-
-	msg.Data = players
-	ms.Broadcast(msg)
+	ms.playersUpdated(ctx, pid)
+	return nil
 }
 
-func (ms *MelodySocket) SetPlayerStatus(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) SetPlayerStatus(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
 	status, ok := msg.Data.(string)
 	if !ok {
-		err := fmt.Errorf("interface conversion: data is not string")
-		SendErr(s, err)
-		return
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
 	}
 
 	if err := service.SetPlayerStatus(ctx, pid, status); err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
-
-	players, err := service.GetPlayers(ctx)
-	if err != nil {
-		SendErr(s, err)
-		return
-	}
-	msg.Code = constants.GetPlayers
-	msg.Data = players
-	ms.Broadcast(msg)
+	ms.playersUpdated(ctx, pid)
+	return nil
 }
 
-func (ms *MelodySocket) SetReady(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) SetReady(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
-	data := msg.Data.(map[string]interface{})
-	rid := data["rid"].(string)
-	ready := data["ready"].(bool)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	ready, okReady := data["ready"].(bool)
+	if !okR || !okReady {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
 
 	room, err := service.SetReady(ctx, rid, pid, ready)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	msg.Data = room
 	ms.Send2Room(room, msg)
+	if room.Started {
+		ms.LobbyChanged(dto.DeltaUpdate, room)
+	}
+	return nil
 }
 
-func (ms *MelodySocket) MakeStep(ctx context.Context, s *melody.Session, msg *dto.Message) {
-	data := msg.Data.(map[string]interface{})
-	rid := data["rid"].(string)
-	i := data["i"].(float64)
-	j := data["j"].(float64)
+func (ms *MelodySocket) MakeStep(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	i, okI := data["i"].(float64)
+	j, okJ := data["j"].(float64)
+	if !okR || !okI || !okJ {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
 	c := entity.Chess{
 		I: int8(i),
 		J: int8(j),
 	}
-	over, gameOverDTO, room, err := service.MakeStep(ctx, rid, c)
+	over, gameOverDTO, room, err := service.MakeStep(ctx, pid, rid, c)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 	ms.Send2Room(room, msg)
 	if over {
@@ -261,16 +299,24 @@ func (ms *MelodySocket) MakeStep(ctx context.Context, s *melody.Session, msg *dt
 			Data: *room,
 		})
 	}
+	return nil
 }
 
-func (ms *MelodySocket) RetractStep(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) RetractStep(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
-	data := msg.Data.(map[string]interface{})
-	rid := data["rid"].(string)
-	consent := int(data["consent"].(float64))
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	value, okC := data["consent"].(float64)
+	if !okR || !okC {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	consent := int(value)
 	opponentId, room, count, err := service.RetractStep(ctx, pid, rid, consent)
 	if err != nil {
-		SendErr(s, err)
+		return err
 	}
 	if consent == 2 {
 		data["count"] = count
@@ -279,22 +325,20 @@ func (ms *MelodySocket) RetractStep(ctx context.Context, s *melody.Session, msg
 	} else {
 		ms.Send2PId(opponentId, msg)
 	}
+	return nil
 }
 
-func (ms *MelodySocket) Surrender(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) Surrender(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
 
 	rid, ok := msg.Data.(string)
 	if !ok {
-		err := fmt.Errorf("error: data is not string")
-		SendErr(s, err)
-		return
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
 	}
 
 	gameOverDTO, room, err := service.Surrender(ctx, pid, rid)
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	ms.SendGameOver(room, gameOverDTO)
@@ -302,19 +346,27 @@ func (ms *MelodySocket) Surrender(ctx context.Context, s *melody.Session, msg *d
 		Code: constants.SetReady,
 		Data: *room,
 	})
+	return nil
 }
 
 // AskDraw 平局请求
 // 从消息中获取房间 ID（rid）和玩家是否同意平局请求的选项（consent）。
-func (ms *MelodySocket) AskDraw(ctx context.Context, s *melody.Session, msg *dto.Message) {
+func (ms *MelodySocket) AskDraw(ctx context.Context, s *melody.Session, msg *dto.Message) error {
 	pid, _ := GetPId(s)
-	data := msg.Data.(map[string]interface{})
-	rid := data["rid"].(string)
-	consent := int(data["consent"].(float64))
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	value, okC := data["consent"].(float64)
+	if !okR || !okC {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	consent := int(value)
 	// 调用 service.Draw() 方法处理平局请求，并将返回的对手 ID（opponentId）、房间对象（room）和错误（err）保存到相应的变量中。
 	opponentId, room, err := service.Draw(ctx, pid, rid, consent)
 	if err != nil {
-		SendErr(s, err)
+		return err
 	}
 
 	// 如果玩家同意平局请求，则发送游戏结束消息（GameOverDTO），其中包含房间 ID（RId）和结束原因（Cause），并通过 Send2Room() 方法将房间对象发送给房间内的所有玩家。
@@ -331,4 +383,262 @@ func (ms *MelodySocket) AskDraw(ctx context.Context, s *melody.Session, msg *dto
 		// 如果玩家拒绝平局请求，则通过 Send2PId() 方法将消息发送给对手玩家。
 		ms.Send2PId(opponentId, msg)
 	}
+	return nil
+}
+
+// Hello 客户端在连接后重新协商协议版本
+func (ms *MelodySocket) Hello(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	version, ok := msg.Data.(float64)
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	v, err := negotiateVersion(int(version))
+	if err != nil {
+		return err
+	}
+	s.Set("version", v)
+	msg.Data = dto.HelloDTO{
+		Version:    v,
+		MinVersion: constants.MinProtocolVersion,
+		MaxVersion: constants.ProtocolVersion,
+	}
+	Send(s, msg)
+	return nil
+}
+
+// Hint 请求提示，data 为 {"rid": "..."} 分析房间当前局面，或 {"steps": [{"i": 7, "j": 7}]} 分析任意局面，
+// 可选的 k 指定返回的候选着法数量。结果只回复给请求方。
+func (ms *MelodySocket) Hint(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, _ := data["rid"].(string)
+	k, _ := data["k"].(float64)
+
+	var steps []entity.Chess
+	if rid == "" {
+		var err error
+		if steps, err = parseSteps(data["steps"]); err != nil {
+			return err
+		}
+	}
+
+	hint, err := service.Hint(ctx, pid, rid, steps, int(k))
+	if err != nil {
+		return err
+	}
+	msg.Data = hint
+	Send(s, msg)
+	return nil
+}
+
+// GetReview 获取对局复盘，data 为对局 id
+func (ms *MelodySocket) GetReview(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	gid, ok := msg.Data.(string)
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	review, err := service.GetReview(ctx, gid)
+	if err != nil {
+		return err
+	}
+	msg.Data = review
+	Send(s, msg)
+	return nil
+}
+
+// TreeMove 分析房间落子，data 为 {"rid": "...", "i": 7, "j": 7}，变化树推送给房间内所有人
+func (ms *MelodySocket) TreeMove(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	i, okI := data["i"].(float64)
+	j, okJ := data["j"].(float64)
+	if !okR || !okI || !okJ {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+
+	room, err := service.TreeMove(ctx, pid, rid, entity.Chess{I: int8(i), J: int8(j)})
+	if err != nil {
+		return err
+	}
+	ms.sendTree(room, msg)
+	return nil
+}
+
+// TreeGoto 分析房间跳转，data 为 {"rid": "...", "node": 3}
+func (ms *MelodySocket) TreeGoto(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	rid, node, err := treeNodeParam(msg)
+	if err != nil {
+		return err
+	}
+	room, err := service.TreeGoto(ctx, pid, rid, node)
+	if err != nil {
+		return err
+	}
+	ms.sendTree(room, msg)
+	return nil
+}
+
+// TreeDelete 分析房间删除变化，data 为 {"rid": "...", "node": 3}
+func (ms *MelodySocket) TreeDelete(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	rid, node, err := treeNodeParam(msg)
+	if err != nil {
+		return err
+	}
+	room, err := service.TreeDelete(ctx, pid, rid, node)
+	if err != nil {
+		return err
+	}
+	ms.sendTree(room, msg)
+	return nil
+}
+
+// TreeLoad 分析房间载入存档对局，data 为 {"rid": "...", "gid": "..."}
+func (ms *MelodySocket) TreeLoad(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	gid, okG := data["gid"].(string)
+	if !okR || !okG {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+
+	room, err := service.TreeLoad(ctx, pid, rid, gid)
+	if err != nil {
+		return err
+	}
+	ms.sendTree(room, msg)
+	return nil
+}
+
+func treeNodeParam(msg *dto.Message) (string, int, error) {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return "", 0, errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	rid, okR := data["rid"].(string)
+	node, okN := data["node"].(float64)
+	if !okR || !okN {
+		return "", 0, errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	return rid, int(node), nil
+}
+
+// sendTree 将变化树推送给房间内所有人
+func (ms *MelodySocket) sendTree(room *entity.Room, msg *dto.Message) {
+	msg.Data = dto.TreeDTO{RId: room.Id, Tree: room.Tree, Steps: room.Steps}
+	ms.Send2Room(room, msg)
+}
+
+// StartPuzzle 开始解题，data 为题目 id，daily 为每日一题
+func (ms *MelodySocket) StartPuzzle(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	id, ok := msg.Data.(string)
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	p, err := service.StartPuzzle(ctx, pid, id)
+	if err != nil {
+		return err
+	}
+	msg.Data = p
+	Send(s, msg)
+	return nil
+}
+
+// PuzzleMove 解题落子，data 为 {"i": 7, "j": 7}，回复中包含防守方的应对
+func (ms *MelodySocket) PuzzleMove(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	pid, _ := GetPId(s)
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	i, okI := data["i"].(float64)
+	j, okJ := data["j"].(float64)
+	if !okI || !okJ {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	result, err := service.PuzzleMove(ctx, pid, entity.Chess{I: int8(i), J: int8(j)})
+	if err != nil {
+		return err
+	}
+	msg.Data = result
+	Send(s, msg)
+	return nil
+}
+
+// ImportPuzzle 导入题目，data 为题目文本
+func (ms *MelodySocket) ImportPuzzle(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	text, ok := msg.Data.(string)
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	p, err := service.ImportPuzzle(ctx, text)
+	if err != nil {
+		return err
+	}
+	msg.Data = dto.NewPuzzleDTO(p, nil, false)
+	Send(s, msg)
+	return nil
+}
+
+// Explore 开局浏览器，data 为 {"steps": [{"i": 7, "j": 7}]}，返回该局面之后各着法的统计
+func (ms *MelodySocket) Explore(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	data, ok := msg.Data.(map[string]interface{})
+	if !ok {
+		return errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+	steps, err := parseSteps(data["steps"])
+	if err != nil {
+		return err
+	}
+	result, err := service.Explore(ctx, steps)
+	if err != nil {
+		return err
+	}
+	msg.Data = result
+	Send(s, msg)
+	return nil
+}
+
+// parseSteps 解析 [{"i": 7, "j": 7}] 形式的着法序列
+func parseSteps(v interface{}) ([]entity.Chess, error) {
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "steps"})
+	}
+	steps := make([]entity.Chess, 0, len(list))
+	for _, item := range list {
+		step, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "steps"})
+		}
+		i, okI := step["i"].(float64)
+		j, okJ := step["j"].(float64)
+		if !okI || !okJ {
+			return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "steps"})
+		}
+		steps = append(steps, entity.Chess{I: int8(i), J: int8(j)})
+	}
+	return steps, nil
+}
+
+// decodeData 把消息中已解码为通用结构的 data 转换为 v 指向的结构
+func decodeData(data interface{}, v interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
 }
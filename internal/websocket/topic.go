@@ -0,0 +1,311 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/olahol/melody"
+	"github.com/toujourser/gomoku/internal/bus"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/pkg/logger"
+	"sort"
+	"sync"
+)
+
+// subscriptions 本实例上订阅了各主题的连接
+type subscriptions struct {
+	mu     sync.RWMutex
+	topics map[string]map[*melody.Session]struct{}
+}
+
+func (t *subscriptions) add(s *melody.Session, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.topics == nil {
+		t.topics = make(map[string]map[*melody.Session]struct{})
+	}
+	if t.topics[topic] == nil {
+		t.topics[topic] = make(map[*melody.Session]struct{})
+	}
+	t.topics[topic][s] = struct{}{}
+}
+
+func (t *subscriptions) remove(s *melody.Session, topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.topics[topic], s)
+	if len(t.topics[topic]) == 0 {
+		delete(t.topics, topic)
+	}
+}
+
+// removeAll 连接断开时取消它的所有订阅
+func (t *subscriptions) removeAll(s *melody.Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for topic, sessions := range t.topics {
+		delete(sessions, s)
+		if len(sessions) == 0 {
+			delete(t.topics, topic)
+		}
+	}
+}
+
+// drop 主题不再有消息时（例如房间已删除）取消所有连接对它的订阅
+func (t *subscriptions) drop(topic string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.topics, topic)
+}
+
+func (t *subscriptions) sessions(topic string) []*melody.Session {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	sessions := make([]*melody.Session, 0, len(t.topics[topic]))
+	for s := range t.topics[topic] {
+		sessions = append(sessions, s)
+	}
+	return sessions
+}
+
+// list 返回连接订阅的主题，按名称排序
+func (t *subscriptions) list(s *melody.Session) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	topics := make([]string, 0)
+	for topic, sessions := range t.topics {
+		if _, ok := sessions[s]; ok {
+			topics = append(topics, topic)
+		}
+	}
+	sort.Strings(topics)
+	return topics
+}
+
+// legacy 旧版本协议的连接不订阅主题，视为订阅了大厅、大厅聊天和玩家列表
+func legacy(s *melody.Session) bool {
+	return GetVersion(s) < constants.TopicVersion
+}
+
+// Subscribe 订阅主题，data 为一个主题或主题列表：lobby、hall、players 或 room:<rid>。
+// 订阅大厅和玩家列表时先推送一次完整数据，之后只推送增量事件。回复当前订阅的所有主题
+func (ms *MelodySocket) Subscribe(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	topics, err := parseTopics(ctx, msg.Data)
+	if err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		// 先订阅再推送完整数据，期间发生的变化不会丢失
+		ms.subs.add(s, topic)
+		if err = sendSnapshot(ctx, s, topic); err != nil {
+			return err
+		}
+	}
+	msg.Data = ms.subs.list(s)
+	Send(s, msg)
+	return nil
+}
+
+// Unsubscribe 取消订阅主题，data 与 Subscribe 相同。回复当前订阅的所有主题
+func (ms *MelodySocket) Unsubscribe(ctx context.Context, s *melody.Session, msg *dto.Message) error {
+	topics, err := parseTopics(ctx, msg.Data)
+	if err != nil {
+		return err
+	}
+	for _, topic := range topics {
+		ms.subs.remove(s, topic)
+	}
+	msg.Data = ms.subs.list(s)
+	Send(s, msg)
+	return nil
+}
+
+// parseTopics 解析并检查主题，房间主题要求房间存在
+func parseTopics(ctx context.Context, data interface{}) ([]string, error) {
+	var topics []string
+	switch v := data.(type) {
+	case string:
+		topics = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			topic, ok := item.(string)
+			if !ok {
+				return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "topic"})
+			}
+			topics = append(topics, topic)
+		}
+	default:
+		return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "data"})
+	}
+
+	for _, topic := range topics {
+		switch topic {
+		case constants.TopicLobby, constants.TopicHall, constants.TopicPlayers:
+			continue
+		}
+		rid, ok := constants.ParseRoomTopic(topic)
+		if !ok {
+			return nil, errcode.New(errcode.InvalidParam, errcode.Params{"field": "topic"})
+		}
+		if _, err := service.GetRoom(ctx, rid); err != nil {
+			return nil, err
+		}
+	}
+	return topics, nil
+}
+
+// sendSnapshot 推送增量主题的完整数据：大厅按连接最近一次 GetRooms 的查询条件取页
+func sendSnapshot(ctx context.Context, s *melody.Session, topic string) error {
+	switch topic {
+	case constants.TopicLobby:
+		lobby, err := service.Lobby(ctx, lobbyQuery(s))
+		if err != nil {
+			return err
+		}
+		Send(s, &dto.Message{Code: constants.GetRooms, Data: lobby})
+	case constants.TopicPlayers:
+		players, err := service.GetPlayers(ctx)
+		if err != nil {
+			return err
+		}
+		Send(s, &dto.Message{Code: constants.GetPlayers, Data: players})
+	}
+	return nil
+}
+
+func lobbyQuery(s *melody.Session) *dto.LobbyQuery {
+	if v, ok := s.Get("lobby"); ok {
+		return v.(*dto.LobbyQuery)
+	}
+	return &dto.LobbyQuery{}
+}
+
+// Publish 推送消息给所有实例上订阅了 topic 的连接
+func (ms *MelodySocket) Publish(topic string, msg *dto.Message) {
+	msgByte, _ := json.Marshal(*msg.WithoutId())
+	ms.publishLocal(topic, msgByte)
+	if _, err := bus.Publish(context.Background(), &bus.Envelope{Kind: bus.KindTopic, Target: topic, Msg: msgByte}); err != nil {
+		logger.Error(err)
+	}
+}
+
+// publishLocal 投递给本实例的订阅者，旧版本的连接按主题转换为原来的完整推送
+func (ms *MelodySocket) publishLocal(topic string, msgByte []byte) {
+	for _, s := range ms.subs.sessions(topic) {
+		if !legacy(s) {
+			write(s, msgByte)
+		}
+	}
+	sessions := ms.legacySessions()
+	if len(sessions) == 0 {
+		return
+	}
+	ctx := context.Background()
+	switch topic {
+	case constants.TopicHall:
+		for _, s := range sessions {
+			write(s, msgByte)
+		}
+	case constants.TopicLobby:
+		pushLegacyLobby(ctx, sessions)
+	case constants.TopicPlayers:
+		players, err := service.GetPlayers(ctx)
+		if err != nil {
+			logger.Error(err)
+			return
+		}
+		b, _ := json.Marshal(&dto.Message{Code: constants.GetPlayers, Data: players})
+		for _, s := range sessions {
+			write(s, b)
+		}
+	}
+}
+
+func (ms *MelodySocket) legacySessions() []*melody.Session {
+	sessions, err := ms.M.Sessions()
+	if err != nil {
+		logger.Error(err)
+		return nil
+	}
+	n := 0
+	for _, s := range sessions {
+		if legacy(s) {
+			sessions[n] = s
+			n++
+		}
+	}
+	return sessions[:n]
+}
+
+// pushLegacyLobby 版本 1 的连接推送完整的房间列表，版本 2 的连接按最近一次 GetRooms 的查询条件推送一页房间摘要
+func pushLegacyLobby(ctx context.Context, sessions []*melody.Session) {
+	rooms, err := service.GetRooms(ctx)
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	var full []byte
+	for _, s := range sessions {
+		if GetVersion(s) < constants.LobbyVersion {
+			if full == nil {
+				full, _ = json.Marshal(&dto.Message{Code: constants.GetRooms, Data: rooms})
+			}
+			write(s, full)
+			continue
+		}
+		Send(s, &dto.Message{Code: constants.GetRooms, Data: service.LobbyPage(rooms, lobbyQuery(s))})
+	}
+}
+
+// writeRoomSubscribers 把房间消息写给本实例上订阅了该房间的连接，已经在房间中的玩家 pids 不会重复收到
+func (ms *MelodySocket) writeRoomSubscribers(rid string, pids []string, msgByte []byte) {
+	for _, s := range ms.subs.sessions(constants.RoomTopic(rid)) {
+		pid, _ := GetPId(s)
+		if !contains(pids, pid) {
+			write(s, msgByte)
+		}
+	}
+}
+
+func contains(ids []string, id string) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// LobbyChanged 大厅中的房间有变化，推送增量事件；op 为 remove 时只用到房间 id
+func (ms *MelodySocket) LobbyChanged(op string, room *entity.Room) {
+	delta := dto.LobbyDeltaDTO{Op: op, RId: room.Id}
+	if op != dto.DeltaRemove {
+		summary := dto.NewRoomSummary(room)
+		delta.Room = &summary
+	}
+	ms.Publish(constants.TopicLobby, &dto.Message{Code: constants.LobbyDelta, Data: delta})
+}
+
+// PlayerChanged 玩家列表有变化，推送增量事件；op 为 remove 时只用到玩家 id
+func (ms *MelodySocket) PlayerChanged(op string, p *entity.Player) {
+	delta := dto.PlayerDeltaDTO{Op: op, PId: p.Id}
+	if op != dto.DeltaRemove {
+		delta.Player = p
+	}
+	ms.Publish(constants.TopicPlayers, &dto.Message{Code: constants.PlayerDelta, Data: delta})
+}
+
+// playersUpdated 玩家状态或积分由其他操作间接修改后，读取最新的玩家推送增量事件
+func (ms *MelodySocket) playersUpdated(ctx context.Context, pids ...string) {
+	for _, pid := range pids {
+		p, err := service.GetPlayer(ctx, pid)
+		if err != nil {
+			// 玩家可能已经断开连接
+			continue
+		}
+		ms.PlayerChanged(dto.DeltaUpdate, p)
+	}
+}
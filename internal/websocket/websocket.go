@@ -5,12 +5,16 @@ import (
 	"encoding/json"
 	"github.com/olahol/melody"
 	uuid "github.com/satori/go.uuid"
+	"github.com/toujourser/gomoku/internal/bus"
 	"github.com/toujourser/gomoku/internal/constants"
 	"github.com/toujourser/gomoku/internal/dto"
 	"github.com/toujourser/gomoku/internal/entity"
-	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/feed"
 	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store"
 	"github.com/toujourser/gomoku/pkg/logger"
+	"strconv"
 	"sync"
 )
 
@@ -24,47 +28,90 @@ type MelodySocket struct {
 	M            *melody.Melody
 	idSessionMap sync.Map
 	lock         sync.Mutex
+	subs         subscriptions
 }
 
+// Receive 分发客户端请求。
+// 处理失败时向请求方回复带有请求 id 的错误；请求携带 id 且处理成功时额外回复一条确认消息。
 func (ms *MelodySocket) Receive(s *melody.Session, msgByte []byte) {
 	msg := &dto.Message{}
 	if err := json.Unmarshal(msgByte, msg); err != nil {
-		Send(s, dto.NewErrMsg(err))
+		SendErr(s, errcode.New(errcode.InvalidParam, errcode.Params{"field": "message"}))
+		return
 	}
+	// 处理函数可能会改写 msg 的 Code 和 Data，先保存请求本身用于回复
+	req := &dto.Message{Id: msg.Id, Code: msg.Code}
 	ctx := context.Background()
+	var err error
 	switch msg.Code {
 	case constants.HallChat:
-		ms.HallChat(ctx, s, msg)
+		err = ms.HallChat(ctx, s, msg)
 	case constants.GetHallDialog:
-		ms.GetHallDialog(ctx, s, msg)
+		err = ms.GetHallDialog(ctx, s, msg)
 	case constants.GetRooms:
-		ms.GetRooms(ctx, s, msg)
+		err = ms.GetRooms(ctx, s, msg)
 	case constants.CreateRoom:
-		ms.CreateRoom(ctx, s, msg)
+		err = ms.CreateRoom(ctx, s, msg)
 	case constants.EnterRoom:
-		ms.EnterRoom(ctx, s, msg)
+		err = ms.EnterRoom(ctx, s, msg)
 	case constants.LeaveRoom:
-		ms.LeaveRoom(ctx, s, msg)
+		err = ms.LeaveRoom(ctx, s, msg)
 	case constants.RoomChat:
-		ms.RoomChat(ctx, s, msg)
+		err = ms.RoomChat(ctx, s, msg)
 	case constants.GetPlayer:
-		ms.GetPlayer(ctx, s, msg)
+		err = ms.GetPlayer(ctx, s, msg)
 	case constants.GetPlayers:
-		ms.GetPlayers(ctx, s, msg)
+		err = ms.GetPlayers(ctx, s, msg)
 	case constants.PlayerRename:
-		ms.PlayerRename(ctx, s, msg)
+		err = ms.PlayerRename(ctx, s, msg)
 	case constants.SetPlayerStatus:
-		ms.SetPlayerStatus(ctx, s, msg)
+		err = ms.SetPlayerStatus(ctx, s, msg)
 	case constants.SetReady:
-		ms.SetReady(ctx, s, msg)
+		err = ms.SetReady(ctx, s, msg)
 	case constants.MakeStep:
-		ms.MakeStep(ctx, s, msg)
+		err = ms.MakeStep(ctx, s, msg)
 	case constants.RetractStep:
-		ms.RetractStep(ctx, s, msg)
+		err = ms.RetractStep(ctx, s, msg)
 	case constants.Surrender:
-		ms.Surrender(ctx, s, msg)
+		err = ms.Surrender(ctx, s, msg)
 	case constants.AskDraw:
-		ms.AskDraw(ctx, s, msg)
+		err = ms.AskDraw(ctx, s, msg)
+	case constants.Hello:
+		err = ms.Hello(ctx, s, msg)
+	case constants.Hint:
+		err = ms.Hint(ctx, s, msg)
+	case constants.GetReview:
+		err = ms.GetReview(ctx, s, msg)
+	case constants.TreeMove:
+		err = ms.TreeMove(ctx, s, msg)
+	case constants.TreeGoto:
+		err = ms.TreeGoto(ctx, s, msg)
+	case constants.TreeDelete:
+		err = ms.TreeDelete(ctx, s, msg)
+	case constants.TreeLoad:
+		err = ms.TreeLoad(ctx, s, msg)
+	case constants.StartPuzzle:
+		err = ms.StartPuzzle(ctx, s, msg)
+	case constants.PuzzleMove:
+		err = ms.PuzzleMove(ctx, s, msg)
+	case constants.ImportPuzzle:
+		err = ms.ImportPuzzle(ctx, s, msg)
+	case constants.Explore:
+		err = ms.Explore(ctx, s, msg)
+	case constants.Subscribe:
+		err = ms.Subscribe(ctx, s, msg)
+	case constants.Unsubscribe:
+		err = ms.Unsubscribe(ctx, s, msg)
+	default:
+		err = errcode.New(errcode.UnknownMessage, errcode.Params{"code": msg.Code})
+	}
+
+	if err != nil {
+		Send(s, dto.NewErrReplyMsg(req, err, GetLang(s)))
+		return
+	}
+	if req.Id != "" {
+		Send(s, dto.NewAckMsg(req))
 	}
 }
 
@@ -84,48 +131,217 @@ func SendSuccess(s *melody.Session) {
 }
 
 func SendErr(s *melody.Session, err error) {
-	Send(s, dto.NewErrMsg(err))
+	Send(s, dto.NewErrMsg(err, GetLang(s)))
 }
 
-func (ms *MelodySocket) Send2PId(pid string, msg *dto.Message) {
+// StartBus 订阅跨实例消息总线，把其他实例发来的消息投递给本实例的会话
+func (ms *MelodySocket) StartBus(ctx context.Context, presence store.PresenceStore) {
+	bus.Start(ctx, presence, ms.deliver)
+}
+
+// deliver 投递总线消息
+func (ms *MelodySocket) deliver(env *bus.Envelope) {
+	switch env.Kind {
+	case bus.KindPlayer:
+		if s, ok := ms.session(env.Target); ok {
+			write(s, env.Msg)
+		}
+	case bus.KindRoom:
+		msg := &dto.Message{}
+		if err := json.Unmarshal(env.Msg, msg); err == nil {
+			feed.RoomFeed.Publish(env.Target, msg)
+		}
+		ms.writeLocal(env.PIds, env.Msg)
+		ms.writeRoomSubscribers(env.Target, env.PIds, env.Msg)
+		if msg.Code == constants.DelRoom {
+			ms.subs.drop(constants.RoomTopic(env.Target))
+		}
+	case bus.KindTopic:
+		ms.publishLocal(env.Target, env.Msg)
+	case bus.KindRelease:
+		// 交出房间锁需要等待进行中的操作结束，不能阻塞总线
+		go service.ReleaseRoom(context.Background(), env.Target)
+	}
+}
+
+// session 返回连接在本实例上的玩家会话
+func (ms *MelodySocket) session(pid string) (*melody.Session, bool) {
 	sObj, ok := ms.idSessionMap.Load(pid)
 	if !ok {
-		logger.WithField("pid", pid).Error("can not load the value of %v from idSessionMap")
-		return
+		return nil, false
 	}
 	s, ok := sObj.(*melody.Session)
-	if !ok {
-		logger.Error("sObj is not type of *melody.Session")
+	return s, ok
+}
+
+// writeLocal 把已编码的消息写给 pids 中连接在本实例上的玩家
+func (ms *MelodySocket) writeLocal(pids []string, msgByte []byte) {
+	for _, pid := range pids {
+		if s, ok := ms.session(pid); ok {
+			write(s, msgByte)
+		}
+	}
+}
+
+func write(s *melody.Session, msgByte []byte) {
+	logger.Debugf("[send msg]: %v", string(msgByte))
+	if err := s.Write(msgByte); err != nil {
+		logger.Error(err)
+	}
+}
+
+// Send2PId 推送消息给指定玩家，玩家不在本实例时经消息总线转发。请求 id 只属于请求方，推送时会被去掉
+func (ms *MelodySocket) Send2PId(pid string, msg *dto.Message) {
+	msg = msg.WithoutId()
+	if s, ok := ms.session(pid); ok {
+		Send(s, msg)
 		return
 	}
-	Send(s, msg)
+	msgByte, _ := json.Marshal(msg)
+	ok, err := bus.Publish(context.Background(), &bus.Envelope{Kind: bus.KindPlayer, Target: pid, Msg: msgByte})
+	if err != nil {
+		logger.Error(err)
+		return
+	}
+	if !ok {
+		logger.WithField("pid", pid).Error("player is not online on any instance")
+	}
 }
 
+// Send2Room 推送消息给房间内所有玩家，同时转发给订阅了该房间的连接和匿名旁观订阅者。
+// 本实例的会话直接投递，其他实例上的玩家和订阅者经消息总线投递。
 func (ms *MelodySocket) Send2Room(r *entity.Room, msg *dto.Message) {
+	msg = msg.WithoutId()
+	feed.RoomFeed.Publish(r.Id, msg)
+
+	pids := make([]string, 0, len(r.Spectators)+2)
 	if r.Host.Id != "" {
-		ms.Send2PId(r.Host.Id, msg)
+		pids = append(pids, r.Host.Id)
 	}
 	if r.Challenger.Id != "" {
-		ms.Send2PId(r.Challenger.Id, msg)
+		pids = append(pids, r.Challenger.Id)
 	}
 	for _, spectator := range r.Spectators {
-		ms.Send2PId(spectator.Id, msg)
+		pids = append(pids, spectator.Id)
 	}
+	msgByte, _ := json.Marshal(msg)
+	ms.writeLocal(pids, msgByte)
+	ms.writeRoomSubscribers(r.Id, pids, msgByte)
+	if msg.Code == constants.DelRoom {
+		ms.subs.drop(constants.RoomTopic(r.Id))
+	}
+	if _, err := bus.Publish(context.Background(), &bus.Envelope{Kind: bus.KindRoom, Target: r.Id, PIds: pids, Msg: msgByte}); err != nil {
+		logger.Error(err)
+	}
+}
+
+// negotiateVersion 取客户端与服务端都支持的最高协议版本，未声明版本的客户端按最低版本处理
+func negotiateVersion(clientVersion int) (int, error) {
+	if clientVersion == 0 {
+		return constants.MinProtocolVersion, nil
+	}
+	if clientVersion < constants.MinProtocolVersion {
+		return 0, errcode.New(errcode.UnsupportedVersion, errcode.Params{
+			"version": clientVersion,
+			"min":     constants.MinProtocolVersion,
+		})
+	}
+	if clientVersion > constants.ProtocolVersion {
+		return constants.ProtocolVersion, nil
+	}
+	return clientVersion, nil
 }
 
 func (ms *MelodySocket) Connect(s *melody.Session) {
 	ctx := context.Background()
-	id := uuid.NewV4().String()
-	player, err := service.NewPlayerConnect(ctx, id)
+
+	query := s.Request.URL.Query()
+	s.Set("lang", errcode.ParseLang(query.Get("lang"), s.Request.Header.Get("Accept-Language")))
+	clientVersion, _ := strconv.Atoi(query.Get("version"))
+	version, err := negotiateVersion(clientVersion)
 	if err != nil {
+		SendErr(s, err)
+		_ = s.Close()
 		return
 	}
+	s.Set("version", version)
+
+	var (
+		player      *entity.Player
+		room        *entity.Room
+		resumed     bool
+		resumeToken string
+	)
+	if token := query.Get("token"); token != "" {
+		// 登录的连接使用账号 id 作为玩家 id
+		player, room, resumed, err = ms.accountConnect(ctx, token)
+		if err != nil {
+			SendErr(s, err)
+			_ = s.Close()
+			return
+		}
+	} else {
+		// 服务端重启后客户端带着原来的 id 和重连令牌重连，继续未结束的对局
+		resumeToken = query.Get("resume")
+		player, room, resumed = service.ResumePlayer(ctx, query.Get("pid"), resumeToken)
+		if !resumed {
+			player, resumeToken, err = ms.newPlayer(ctx)
+			if err != nil {
+				logger.Error(err)
+				SendErr(s, err)
+				_ = s.Close()
+				return
+			}
+		}
+	}
+	id := player.Id
 	ms.idSessionMap.Store(id, s)
 	s.Set("id", id)
+	if err = bus.Online(ctx, id); err != nil {
+		logger.Error(err)
+	}
+	Send(s, &dto.Message{
+		Code: constants.Hello,
+		Data: dto.HelloDTO{
+			Version:     version,
+			MinVersion:  constants.MinProtocolVersion,
+			MaxVersion:  constants.ProtocolVersion,
+			ResumeToken: resumeToken,
+		},
+	})
 	Send(s, &dto.Message{
 		Code: constants.GetPlayer,
 		Data: player,
 	})
+	if resumed {
+		ms.PlayerChanged(dto.DeltaUpdate, player)
+	} else {
+		ms.PlayerChanged(dto.DeltaAdd, player)
+	}
+	if room != nil {
+		ms.Send2Room(room, &dto.Message{Code: constants.EnterRoom, Data: room})
+	}
+}
+
+// newPlayer 为匿名连接创建玩家并生成重连令牌
+func (ms *MelodySocket) newPlayer(ctx context.Context) (*entity.Player, string, error) {
+	player, err := service.NewPlayerConnect(ctx, uuid.NewV4().String())
+	if err != nil {
+		return nil, "", err
+	}
+	token, err := service.IssueResumeToken(ctx, player.Id)
+	if err != nil {
+		return nil, "", err
+	}
+	return player, token, nil
+}
+
+func (ms *MelodySocket) accountConnect(ctx context.Context, token string) (*entity.Player, *entity.Room, bool, error) {
+	a, err := service.Authenticate(ctx, token)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return service.AccountConnect(ctx, a)
 }
 
 func (ms *MelodySocket) Disconnect(s *melody.Session) {
@@ -141,12 +357,20 @@ func (ms *MelodySocket) Disconnect(s *melody.Session) {
 	defer ms.lock.Unlock()
 
 	ms.idSessionMap.Delete(id)
+	ms.subs.removeAll(s)
+	if err := bus.Offline(ctx, id); err != nil {
+		logger.Error(err)
+	}
 	rooms, err := service.PlayerDisconnect(ctx, id)
 	if err != nil {
-		SendErr(s, err)
+		logger.Error(err)
+		return
 	}
+	ms.PlayerChanged(dto.DeltaRemove, &entity.Player{Id: id})
 	for _, room := range *rooms {
-		ms.SendLeaveRoom(ctx, s, id, room.Id)
+		if err := ms.SendLeaveRoom(ctx, s, id, room.Id); err != nil {
+			logger.Error(err)
+		}
 	}
 }
 
@@ -157,16 +381,19 @@ func (ms *MelodySocket) SendGameOver(room *entity.Room, gameOverDTO *dto.GameOve
 	}
 
 	ms.Send2Room(room, msg)
+	ms.LobbyChanged(dto.DeltaUpdate, room)
+	if room.Settings.Rated {
+		ms.playersUpdated(context.Background(), room.Host.Id, room.Challenger.Id)
+	}
 }
 
-func (ms *MelodySocket) SendLeaveRoom(ctx context.Context, s *melody.Session, pid string, rid string) {
-	if !lock.RoomLock.IsExist(rid) {
-		return
-	}
+func (ms *MelodySocket) SendLeaveRoom(ctx context.Context, s *melody.Session, pid string, rid string) error {
 	room, gameOverDTO, err := service.LeaveRoom(ctx, pid, rid)
+	if errcode.Is(err, errcode.RoomNotFound) {
+		return nil
+	}
 	if err != nil {
-		SendErr(s, err)
-		return
+		return err
 	}
 
 	msg := &dto.Message{
@@ -181,21 +408,21 @@ func (ms *MelodySocket) SendLeaveRoom(ctx context.Context, s *melody.Session, pi
 		if !s.IsClosed() {
 			Send(s, msg)
 		}
-
-		players, err := service.GetPlayers(ctx)
-		if err != nil {
-			SendErr(s, err)
-		}
-		ms.Broadcast(&dto.Message{
-			Code: constants.GetPlayers,
-			Data: players,
-		})
 	}
 	ms.Send2Room(room, msg)
 
-	if gameOverDTO != nil {
+	if room.Host.Id == "" {
+		ms.LobbyChanged(dto.DeltaRemove, room)
+		// 房间删除后旁观者回到空闲状态
+		for _, p := range room.Spectators {
+			ms.playersUpdated(ctx, p.Id)
+		}
+	} else if gameOverDTO != nil {
 		ms.SendGameOver(room, gameOverDTO)
+	} else {
+		ms.LobbyChanged(dto.DeltaUpdate, room)
 	}
+	return nil
 }
 
 func GetPId(s *melody.Session) (pid string, ok bool) {
@@ -207,9 +434,20 @@ func GetPId(s *melody.Session) (pid string, ok bool) {
 	return
 }
 
-func (ms *MelodySocket) Broadcast(msg *dto.Message) {
-	msgByte, _ := json.Marshal(*msg)
-	if err := ms.M.Broadcast(msgByte); err != nil {
-		logger.Error(err)
+// GetLang 返回会话在连接时选定的语言
+func GetLang(s *melody.Session) string {
+	lang, ok := s.Get("lang")
+	if !ok {
+		return errcode.ZH
+	}
+	return lang.(string)
+}
+
+// GetVersion 返回会话协商的协议版本
+func GetVersion(s *melody.Session) int {
+	version, ok := s.Get("version")
+	if !ok {
+		return constants.MinProtocolVersion
 	}
+	return version.(int)
 }
@@ -0,0 +1,118 @@
+// Package arena 让两个引擎按给定的开局和时限对弈，并统计胜负与 Elo 差。
+package arena
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Clock 时限设置：每局基础时间加每手加秒，Move 为单手上限，0 表示不限
+type Clock struct {
+	Base      time.Duration
+	Increment time.Duration
+	Move      time.Duration
+}
+
+// Game 一局对弈的记录，Black、White 为引擎名
+type Game struct {
+	Id        string
+	Black     string
+	White     string
+	Steps     []protocol.Chess
+	Winner    string // black, white 或 draw
+	Cause     string // five、full、crash、timeout 或 illegal
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// budget 计算本手可用的思考时间
+func (c Clock) budget(remaining time.Duration) time.Duration {
+	var d time.Duration
+	if c.Base > 0 {
+		d = remaining
+	}
+	if c.Move > 0 && (d == 0 || c.Move < d) {
+		d = c.Move
+	}
+	return d
+}
+
+// Play 对弈一局，opening 中的着法按顺序预先摆好。
+// 引擎出错、超时或走出非法着法都判负，返回的存档 Cause 分别为 crash、timeout、illegal。
+func Play(ctx context.Context, black engine.Engine, white engine.Engine, opening []protocol.Chess, rules engine.Rules, clock Clock) (*Game, error) {
+	b, err := engine.NewBoardWithRules(opening, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	game := &Game{
+		Id:        uuid.NewV4().String(),
+		Black:     black.Name(),
+		White:     white.Name(),
+		StartTime: time.Now(),
+	}
+	remaining := [2]time.Duration{clock.Base, clock.Base}
+	engines := [2]engine.Engine{black, white}
+
+	finish := func(winner engine.Stone, cause string) (*Game, error) {
+		game.Steps = b.Steps()
+		game.Winner = "draw"
+		if winner != engine.Empty {
+			game.Winner = winner.String()
+		}
+		game.Cause = cause
+		game.EndTime = time.Now()
+		return game, nil
+	}
+
+	if w := b.Winner(); w != engine.Empty {
+		return finish(w, "five")
+	}
+	for !b.Full() {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		side := b.ToMove()
+		k := side.Color()
+
+		// 引擎按 limits.Time 自行控制思考时间，上下文截止时间留出少量余量用于返回结果
+		budget := clock.budget(remaining[k])
+		moveCtx, cancel := ctx, context.CancelFunc(func() {})
+		if budget > 0 {
+			moveCtx, cancel = context.WithTimeout(ctx, budget+budget/10+50*time.Millisecond)
+		}
+		start := time.Now()
+		res, err := engines[k].Search(moveCtx, engine.Position{Steps: b.Steps(), Rules: rules}, engine.Limits{Time: budget})
+		cancel()
+		used := time.Since(start)
+
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return finish(side.Opponent(), "timeout")
+			}
+			return finish(side.Opponent(), "crash")
+		}
+		if clock.Base > 0 {
+			remaining[k] -= used
+			if remaining[k] < 0 {
+				return finish(side.Opponent(), "timeout")
+			}
+			remaining[k] += clock.Increment
+		}
+		if err = b.Play(res.Move); err != nil {
+			return finish(side.Opponent(), "illegal")
+		}
+		if b.Winner() == side {
+			return finish(side, "five")
+		}
+	}
+	return finish(engine.Empty, "full")
+}
@@ -0,0 +1,61 @@
+package arena
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Match 引擎 A 与 B 的多局对弈。A 在偶数局执黑，每个开局双方各执黑一次
+type Match struct {
+	A        engine.Factory
+	B        engine.Factory
+	Games    int
+	Openings [][]protocol.Chess // 为空时从空棋盘开始
+	Rules    engine.Rules
+	Clock    Clock
+	OnGame   func(n int, aBlack bool, game *Game) // 每局结束后调用，n 从 0 开始
+}
+
+// Run 按顺序下完所有对局并返回 A 视角的统计，ctx 结束时返回已完成对局的统计和 ctx 的错误
+func (m *Match) Run(ctx context.Context) (*Stats, error) {
+	stats := &Stats{}
+	for n := 0; n < m.Games; n++ {
+		var opening []protocol.Chess
+		if len(m.Openings) > 0 {
+			opening = m.Openings[(n/2)%len(m.Openings)]
+		}
+		aBlack := n%2 == 0
+
+		game, err := m.playOne(ctx, aBlack, opening)
+		if err != nil {
+			return stats, err
+		}
+		stats.Record(game, aBlack)
+		if m.OnGame != nil {
+			m.OnGame(n, aBlack, game)
+		}
+	}
+	return stats, nil
+}
+
+// playOne 为每局新建引擎，避免上一局超时或崩溃的引擎影响后续对局
+func (m *Match) playOne(ctx context.Context, aBlack bool, opening []protocol.Chess) (*Game, error) {
+	a, err := m.A(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("engine A: %v", err)
+	}
+	defer a.Close()
+	b, err := m.B(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("engine B: %v", err)
+	}
+	defer b.Close()
+
+	if aBlack {
+		return Play(ctx, a, b, opening, m.Rules, m.Clock)
+	}
+	return Play(ctx, b, a, opening, m.Rules, m.Clock)
+}
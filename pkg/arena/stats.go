@@ -0,0 +1,74 @@
+package arena
+
+import (
+	"fmt"
+	"math"
+)
+
+// Stats 从引擎 A 的视角统计的对局结果
+type Stats struct {
+	Wins   int
+	Draws  int
+	Losses int
+}
+
+// Record 按 A 的执子颜色把一局的结果计入统计
+func (s *Stats) Record(game *Game, aBlack bool) {
+	aColor := "white"
+	if aBlack {
+		aColor = "black"
+	}
+	switch game.Winner {
+	case "draw":
+		s.Draws++
+	case aColor:
+		s.Wins++
+	default:
+		s.Losses++
+	}
+}
+
+func (s *Stats) Games() int {
+	return s.Wins + s.Draws + s.Losses
+}
+
+// Score 返回 A 的得分率，平局记半分
+func (s *Stats) Score() float64 {
+	if s.Games() == 0 {
+		return 0.5
+	}
+	return (float64(s.Wins) + float64(s.Draws)/2) / float64(s.Games())
+}
+
+// Elo 返回 A 相对 B 的 Elo 差及 95% 置信区间
+func (s *Stats) Elo() (diff float64, low float64, high float64) {
+	n := float64(s.Games())
+	score := s.Score()
+	if n == 0 {
+		return 0, math.Inf(-1), math.Inf(1)
+	}
+	// 每局得分的方差
+	w, d, l := float64(s.Wins)/n, float64(s.Draws)/n, float64(s.Losses)/n
+	variance := w*math.Pow(1-score, 2) + d*math.Pow(0.5-score, 2) + l*math.Pow(0-score, 2)
+	margin := 1.96 * math.Sqrt(variance/n)
+	return eloDiff(score), eloDiff(score - margin), eloDiff(score + margin)
+}
+
+func eloDiff(score float64) float64 {
+	if score <= 0 {
+		return math.Inf(-1)
+	}
+	if score >= 1 {
+		return math.Inf(1)
+	}
+	if score == 0.5 {
+		return 0
+	}
+	return -400 * math.Log10(1/score-1)
+}
+
+func (s *Stats) String() string {
+	diff, low, high := s.Elo()
+	return fmt.Sprintf("games %d  +%d =%d -%d  score %.1f%%  elo %+.1f [%+.1f, %+.1f]",
+		s.Games(), s.Wins, s.Draws, s.Losses, 100*s.Score(), diff, low, high)
+}
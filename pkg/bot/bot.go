@@ -0,0 +1,242 @@
+// Package bot 让一个引擎以玩家身份连接服务端，在房间内自动对局。
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/toujourser/gomoku/pkg/client"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+type Options struct {
+	URL         string
+	Name        string         // 机器人的玩家名称，默认使用引擎名称
+	RoomId      string         // 以挑战者身份进入的房间，为空时自己创建房间
+	Color       int8           // 自己创建房间时执子的颜色
+	Rematch     bool           // 对局结束后是否自动准备下一局
+	MaxRestarts int            // 引擎崩溃后最多重启的次数
+	NewEngine   engine.Factory // 创建引擎
+	Limits      engine.Limits  // 每手的搜索预算
+	Logger      *log.Logger
+}
+
+// Bot 一个坐在房间里的引擎玩家，所有回调在客户端的事件 goroutine 中顺序执行
+type Bot struct {
+	opts     Options
+	c        *client.Client
+	engine   engine.Engine
+	restarts int
+
+	pid string // 上一次连接的玩家 id
+
+	mu      sync.Mutex
+	rid     string
+	color   int8
+	started bool
+	steps   []protocol.Chess
+
+	done chan error
+	once sync.Once
+}
+
+// Run 连接服务端并持续对局，直到 ctx 结束、对局结束且不再续局，或引擎无法恢复
+func Run(ctx context.Context, opts Options) error {
+	if opts.Logger == nil {
+		opts.Logger = log.Default()
+	}
+	e, err := opts.NewEngine(ctx)
+	if err != nil {
+		return err
+	}
+	b := &Bot{opts: opts, engine: e, rid: opts.RoomId, done: make(chan error, 1)}
+	defer func() { _ = b.engine.Close() }()
+
+	b.c, err = client.Dial(ctx, client.Options{URL: opts.URL, Lang: "en"}, client.Handlers{
+		OnConnect:  b.onConnect,
+		OnRoom:     b.onRoom,
+		OnStep:     b.onStep,
+		OnRetract:  b.onRetract,
+		OnDraw:     b.onDraw,
+		OnGameOver: b.onGameOver,
+		OnRoomClosed: func(rid string) {
+			if rid == b.rid {
+				b.finish(fmt.Errorf("bot: room %v closed", rid))
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer b.c.Close()
+
+	select {
+	case <-ctx.Done():
+		if rid := b.roomId(); rid != "" {
+			_ = b.c.LeaveRoom(context.Background(), rid)
+		}
+		return ctx.Err()
+	case err = <-b.done:
+		return err
+	}
+}
+
+func (b *Bot) roomId() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.rid
+}
+
+func (b *Bot) finish(err error) {
+	b.once.Do(func() { b.done <- err })
+}
+
+func (b *Bot) logf(format string, args ...interface{}) {
+	b.opts.Logger.Printf("[%v] "+format, append([]interface{}{b.engine.Name()}, args...)...)
+}
+
+// onConnect 连接或重连后进入房间并准备。
+// 重连后玩家 id 不变说明服务端保留了座位，房间由服务端推送，无需重新入座
+func (b *Bot) onConnect(p protocol.Player) {
+	resumed := b.pid == p.Id && b.roomId() != ""
+	b.pid = p.Id
+	if resumed {
+		b.logf("resumed in room %v", b.roomId())
+		return
+	}
+	ctx := context.Background()
+	name := b.opts.Name
+	if name == "" {
+		name = b.engine.Name()
+	}
+	if err := b.c.Rename(ctx, name); err != nil {
+		b.logf("rename: %v", err)
+	}
+
+	b.started = false
+	if b.opts.RoomId != "" {
+		if err := b.c.EnterRoom(ctx, b.opts.RoomId, "challenger"); err != nil {
+			b.finish(err)
+			return
+		}
+	} else {
+		room, err := b.c.CreateRoom(ctx, b.opts.Color)
+		if err != nil {
+			b.finish(err)
+			return
+		}
+		b.mu.Lock()
+		b.rid = room.Id
+		b.mu.Unlock()
+		b.color = room.Host.Color
+		b.logf("created room %v", room.Id)
+	}
+	if err := b.c.SetReady(ctx, b.rid, true); err != nil {
+		b.finish(err)
+	}
+}
+
+func (b *Bot) onRoom(code int, room protocol.Room) {
+	if room.Id != b.rid {
+		return
+	}
+	me := b.c.Player().Id
+	switch me {
+	case room.Host.Id:
+		b.color = room.Host.Color
+	case room.Challenger.Id:
+		b.color = room.Challenger.Color
+	default:
+		return
+	}
+
+	if room.Started && !b.started {
+		b.started = true
+		b.steps = append([]protocol.Chess(nil), room.Steps...)
+		b.play()
+	}
+	if !room.Started {
+		b.started = false
+	}
+}
+
+func (b *Bot) onStep(step client.Step) {
+	if step.RId != b.rid || !b.started {
+		return
+	}
+	b.steps = append(b.steps, protocol.Chess{I: step.I, J: step.J})
+	b.play()
+}
+
+// play 轮到自己时让引擎思考并落子，引擎出错时认输并尝试重启引擎
+func (b *Bot) play() {
+	if int8(len(b.steps)%2) != b.color {
+		return
+	}
+	ctx := context.Background()
+	res, err := b.engine.Search(ctx, engine.Position{Steps: b.steps}, b.opts.Limits)
+	if err == nil {
+		c := res.Move
+		if err = b.c.MakeStep(ctx, b.rid, c.I, c.J); err == nil {
+			return
+		}
+		b.logf("move %v rejected: %v", c, err)
+	} else {
+		b.logf("engine failed: %v", err)
+	}
+
+	if err := b.c.Surrender(ctx, b.rid); err != nil {
+		b.logf("surrender: %v", err)
+	}
+	if err := b.restart(ctx); err != nil {
+		b.finish(err)
+	}
+}
+
+func (b *Bot) restart(ctx context.Context) error {
+	if b.restarts >= b.opts.MaxRestarts {
+		return errors.New("bot: engine failed too many times")
+	}
+	b.restarts++
+	_ = b.engine.Close()
+	e, err := b.opts.NewEngine(ctx)
+	if err != nil {
+		return err
+	}
+	b.engine = e
+	b.logf("engine restarted (%d/%d)", b.restarts, b.opts.MaxRestarts)
+	return nil
+}
+
+// onRetract 机器人不接受悔棋
+func (b *Bot) onRetract(p client.Proposal) {
+	if p.RId == b.rid && p.Consent == client.ConsentAsk {
+		_ = b.c.RetractStep(context.Background(), b.rid, client.ConsentReject)
+	}
+}
+
+// onDraw 机器人不接受求和
+func (b *Bot) onDraw(p client.Proposal) {
+	if p.RId == b.rid && p.Consent == client.ConsentAsk {
+		_ = b.c.AskDraw(context.Background(), b.rid, client.ConsentReject)
+	}
+}
+
+func (b *Bot) onGameOver(r protocol.GameOverDTO) {
+	if r.RId != b.rid {
+		return
+	}
+	b.started = false
+	b.logf("game over: %v", r.Cause)
+	if !b.opts.Rematch {
+		b.finish(nil)
+		return
+	}
+	if err := b.c.SetReady(context.Background(), b.rid, true); err != nil {
+		b.finish(err)
+	}
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+func (c *Client) HallChat(ctx context.Context, content string) error {
+	return c.do(ctx, protocol.HallChat, content, nil)
+}
+
+func (c *Client) GetHallDialog(ctx context.Context) ([]protocol.DialogMsg, error) {
+	var dialog []protocol.DialogMsg
+	err := c.do(ctx, protocol.GetHallDialog, nil, &dialog)
+	return dialog, err
+}
+
+// GetRooms 按条件分页获取大厅房间摘要，q 为 nil 时使用默认条件。
+// 服务端会记住查询条件，之后的大厅更新按该条件通过 OnRooms 推送
+func (c *Client) GetRooms(ctx context.Context, q *protocol.LobbyQuery) (*protocol.LobbyDTO, error) {
+	var data interface{}
+	if q != nil {
+		data = q
+	}
+	lobby := &protocol.LobbyDTO{}
+	if err := c.do(ctx, protocol.GetRooms, data, lobby); err != nil {
+		return nil, err
+	}
+	return lobby, nil
+}
+
+// CreateRoom 创建房间，color 为房主执子颜色
+func (c *Client) CreateRoom(ctx context.Context, color int8) (*protocol.Room, error) {
+	room := &protocol.Room{}
+	if err := c.do(ctx, protocol.CreateRoom, color, room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// EnterRoom 以 challenger 或 spectator 身份进入房间，进入后的房间状态通过 OnRoom 推送
+func (c *Client) EnterRoom(ctx context.Context, rid string, role string) error {
+	return c.do(ctx, protocol.EnterRoom, map[string]interface{}{"rid": rid, "role": role}, nil)
+}
+
+func (c *Client) LeaveRoom(ctx context.Context, rid string) error {
+	return c.do(ctx, protocol.LeaveRoom, rid, nil)
+}
+
+func (c *Client) RoomChat(ctx context.Context, rid string, content string) error {
+	return c.do(ctx, protocol.RoomChat, map[string]interface{}{
+		"rid":     rid,
+		"content": content,
+	}, nil)
+}
+
+func (c *Client) GetPlayer(ctx context.Context) (*protocol.Player, error) {
+	p := &protocol.Player{}
+	if err := c.do(ctx, protocol.GetPlayer, nil, p); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.player = *p
+	c.mu.Unlock()
+	return p, nil
+}
+
+func (c *Client) GetPlayers(ctx context.Context) ([]protocol.Player, error) {
+	var players []protocol.Player
+	err := c.do(ctx, protocol.GetPlayers, nil, &players)
+	return players, err
+}
+
+// Rename 修改玩家名称，名称重复时服务端会自动追加后缀并返回 NAME_TAKEN 错误
+func (c *Client) Rename(ctx context.Context, name string) error {
+	err := c.do(ctx, protocol.PlayerRename, name, nil)
+	if _, e := c.GetPlayer(ctx); e != nil && err == nil {
+		err = e
+	}
+	return err
+}
+
+func (c *Client) SetStatus(ctx context.Context, status string) error {
+	return c.do(ctx, protocol.SetPlayerStatus, status, nil)
+}
+
+func (c *Client) SetReady(ctx context.Context, rid string, ready bool) error {
+	return c.do(ctx, protocol.SetReady, map[string]interface{}{"rid": rid, "ready": ready}, nil)
+}
+
+func (c *Client) MakeStep(ctx context.Context, rid string, i int8, j int8) error {
+	return c.do(ctx, protocol.MakeStep, map[string]interface{}{"rid": rid, "i": i, "j": j}, nil)
+}
+
+// RetractStep 发起悔棋（ConsentAsk）或应答对方的悔棋请求
+func (c *Client) RetractStep(ctx context.Context, rid string, consent int) error {
+	return c.do(ctx, protocol.RetractStep, map[string]interface{}{"rid": rid, "consent": consent}, nil)
+}
+
+func (c *Client) Surrender(ctx context.Context, rid string) error {
+	return c.do(ctx, protocol.Surrender, rid, nil)
+}
+
+// AskDraw 发起求和（ConsentAsk）或应答对方的求和请求
+func (c *Client) AskDraw(ctx context.Context, rid string, consent int) error {
+	return c.do(ctx, protocol.AskDraw, map[string]interface{}{"rid": rid, "consent": consent}, nil)
+}
+
+// Hint 请求房间 rid 当前局面的前 k 个候选着法
+func (c *Client) Hint(ctx context.Context, rid string, k int) (*protocol.HintDTO, error) {
+	hint := &protocol.HintDTO{}
+	if err := c.do(ctx, protocol.Hint, map[string]interface{}{"rid": rid, "k": k}, hint); err != nil {
+		return nil, err
+	}
+	return hint, nil
+}
+
+// GetReview 获取对局复盘，复盘尚未生成时返回 REVIEW_NOT_READY 错误
+func (c *Client) GetReview(ctx context.Context, gid string) (*protocol.Review, error) {
+	review := &protocol.Review{}
+	if err := c.do(ctx, protocol.GetReview, gid, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// CreateAnalysisRoom 创建分析房间
+func (c *Client) CreateAnalysisRoom(ctx context.Context) (*protocol.Room, error) {
+	room := &protocol.Room{}
+	if err := c.do(ctx, protocol.CreateRoom, map[string]interface{}{"color": protocol.BLACK, "analysis": true}, room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// TreeMove 在分析房间的当前节点下落子，更新后的变化树通过 OnTree 推送
+func (c *Client) TreeMove(ctx context.Context, rid string, i int8, j int8) error {
+	return c.do(ctx, protocol.TreeMove, map[string]interface{}{"rid": rid, "i": i, "j": j}, nil)
+}
+
+func (c *Client) TreeGoto(ctx context.Context, rid string, node int) error {
+	return c.do(ctx, protocol.TreeGoto, map[string]interface{}{"rid": rid, "node": node}, nil)
+}
+
+func (c *Client) TreeDelete(ctx context.Context, rid string, node int) error {
+	return c.do(ctx, protocol.TreeDelete, map[string]interface{}{"rid": rid, "node": node}, nil)
+}
+
+// TreeLoad 将存档对局载入分析房间
+func (c *Client) TreeLoad(ctx context.Context, rid string, gid string) error {
+	return c.do(ctx, protocol.TreeLoad, map[string]interface{}{"rid": rid, "gid": gid}, nil)
+}
+
+// StartPuzzle 开始解题，id 为 daily 时为每日一题
+func (c *Client) StartPuzzle(ctx context.Context, id string) (*protocol.PuzzleDTO, error) {
+	p := &protocol.PuzzleDTO{}
+	if err := c.do(ctx, protocol.StartPuzzle, id, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// PuzzleMove 解题落子，返回结果和防守方的应对
+func (c *Client) PuzzleMove(ctx context.Context, i int8, j int8) (*protocol.PuzzleMoveDTO, error) {
+	result := &protocol.PuzzleMoveDTO{}
+	if err := c.do(ctx, protocol.PuzzleMove, map[string]interface{}{"i": i, "j": j}, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ImportPuzzle 导入文本格式的题目
+func (c *Client) ImportPuzzle(ctx context.Context, text string) (*protocol.PuzzleDTO, error) {
+	p := &protocol.PuzzleDTO{}
+	if err := c.do(ctx, protocol.ImportPuzzle, text, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Explore 查询开局库中 steps 之后各着法的统计
+func (c *Client) Explore(ctx context.Context, steps []protocol.Chess) (*protocol.ExplorerDTO, error) {
+	result := &protocol.ExplorerDTO{}
+	if err := c.do(ctx, protocol.Explore, map[string]interface{}{"steps": steps}, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Subscribe 订阅主题：protocol.TopicLobby、TopicHall、TopicPlayers 或 protocol.RoomTopic(rid)。
+// 订阅大厅和玩家列表时服务端先通过 OnRooms、OnPlayers 推送完整数据，之后推送增量事件。
+// 返回当前订阅的所有主题，重连后客户端会自动恢复订阅
+func (c *Client) Subscribe(ctx context.Context, topics ...string) ([]string, error) {
+	var current []string
+	if err := c.do(ctx, protocol.Subscribe, topics, &current); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	for _, topic := range topics {
+		c.topics[topic] = struct{}{}
+	}
+	c.mu.Unlock()
+	return current, nil
+}
+
+// Unsubscribe 取消订阅主题，返回当前订阅的所有主题
+func (c *Client) Unsubscribe(ctx context.Context, topics ...string) ([]string, error) {
+	c.mu.Lock()
+	for _, topic := range topics {
+		delete(c.topics, topic)
+	}
+	c.mu.Unlock()
+	var current []string
+	if err := c.do(ctx, protocol.Unsubscribe, topics, &current); err != nil {
+		return nil, err
+	}
+	return current, nil
+}
+
+// resubscribe 连接建立后恢复订阅。逐个订阅，已删除房间的主题订阅失败不影响其他主题
+func (c *Client) resubscribe() {
+	c.mu.Lock()
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	c.mu.Unlock()
+	for _, topic := range topics {
+		if err := c.do(context.Background(), protocol.Subscribe, []string{topic}, nil); err != nil {
+			if errors.Is(err, ErrDisconnected) || errors.Is(err, ErrClosed) {
+				return
+			}
+			c.mu.Lock()
+			delete(c.topics, topic)
+			c.mu.Unlock()
+			c.emit(func() {
+				if c.handlers.OnError != nil {
+					c.handlers.OnError(err)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,407 @@
+// Package client 是 gomoku websocket 协议的 Go 客户端。
+//
+// 客户端为每个请求生成请求 id，并等待服务端的确认或错误回复；服务端主动推送的消息
+// 通过 Handlers 中的回调分发。连接断开后客户端会按退避策略自动重连，
+// 重连时带上原来的玩家 id 和重连令牌：服务端重启后玩家保留原来的 id 和座位，服务端会重新推送所在的房间；
+// 服务端仍在运行时断开的玩家已被移除，重连后分配新的玩家 id，需要在 OnConnect 中重新进入房间。
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+var (
+	ErrClosed       = errors.New("client: closed")
+	ErrDisconnected = errors.New("client: disconnected")
+)
+
+// Error 服务端返回的错误
+type Error struct {
+	protocol.ErrDTO
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+type Options struct {
+	URL            string        // 服务端地址，例如 ws://127.0.0.1:9950/ws
+	Lang           string        // 错误消息语言，zh 或 en
+	RequestTimeout time.Duration // 单个请求等待回复的最长时间，默认 10 秒
+	ReconnectMin   time.Duration // 重连的最小间隔，默认 500 毫秒
+	ReconnectMax   time.Duration // 重连的最大间隔，默认 30 秒
+	NoReconnect    bool          // 为 true 时断开后不再重连
+	Topics         []string      // 连接后订阅的主题，之后通过 Subscribe 订阅的主题同样会在重连后恢复
+	Dialer         *websocket.Dialer
+}
+
+// envelope 与 protocol.Message 相同，但延迟解析 Data
+type envelope struct {
+	Id   string          `json:"id,omitempty"`
+	Code int             `json:"code"`
+	Data json.RawMessage `json:"data"`
+}
+
+// call 一个等待回复的请求，reply 为服务端带有相同请求 id 的数据回复
+type call struct {
+	reply *envelope
+	done  chan error
+}
+
+type Client struct {
+	opts     Options
+	handlers Handlers
+
+	writeMu sync.Mutex
+	conn    *websocket.Conn
+
+	mu      sync.Mutex
+	player  protocol.Player
+	resume  string // 重连令牌
+	version int
+	pending map[string]*call
+	topics  map[string]struct{}
+
+	seq    uint64
+	closed chan struct{}
+	once   sync.Once
+
+	// 事件队列不限长度，读循环放入回调时从不阻塞，回调中发起的请求总能收到回复；
+	// run 退出后 resubscribe 等 goroutine 的回调直接丢弃
+	emitMu  sync.Mutex
+	events  []func()
+	wake    chan struct{} // 容量为 1，队列中有新回调或已停止
+	stopped bool
+}
+
+// Dial 连接服务端，在收到服务端分配的玩家信息后返回
+func Dial(ctx context.Context, opts Options, handlers Handlers) (*Client, error) {
+	if opts.RequestTimeout == 0 {
+		opts.RequestTimeout = 10 * time.Second
+	}
+	if opts.ReconnectMin == 0 {
+		opts.ReconnectMin = 500 * time.Millisecond
+	}
+	if opts.ReconnectMax == 0 {
+		opts.ReconnectMax = 30 * time.Second
+	}
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+
+	c := &Client{
+		opts:     opts,
+		handlers: handlers,
+		pending:  make(map[string]*call),
+		topics:   make(map[string]struct{}),
+		wake:     make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+	for _, topic := range opts.Topics {
+		c.topics[topic] = struct{}{}
+	}
+	if err := c.connect(ctx); err != nil {
+		return nil, err
+	}
+	go c.dispatch()
+	go c.run()
+	return c, nil
+}
+
+// Player 返回服务端为当前连接分配的玩家
+func (c *Client) Player() protocol.Player {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.player
+}
+
+// Version 返回协商后的协议版本
+func (c *Client) Version() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.version
+}
+
+func (c *Client) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	return c.conn.Close()
+}
+
+// connect 建立连接并读取握手阶段的 Hello 和 GetPlayer 消息
+func (c *Client) connect(ctx context.Context) error {
+	u, err := url.Parse(c.opts.URL)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("version", strconv.Itoa(protocol.ProtocolVersion))
+	if c.opts.Lang != "" {
+		q.Set("lang", c.opts.Lang)
+	}
+	// 重连时带上之前的玩家 id 和重连令牌，服务端重启后可以回到未结束的对局
+	c.mu.Lock()
+	if c.player.Id != "" && c.resume != "" {
+		q.Set("pid", c.player.Id)
+		q.Set("resume", c.resume)
+	}
+	c.mu.Unlock()
+	u.RawQuery = q.Encode()
+
+	conn, _, err := c.opts.Dialer.DialContext(ctx, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetReadDeadline(deadline)
+	}
+	for {
+		env := &envelope{}
+		if err = conn.ReadJSON(env); err != nil {
+			_ = conn.Close()
+			return err
+		}
+		switch env.Code {
+		case protocol.Fail:
+			_ = conn.Close()
+			return decodeErr(env)
+		case protocol.Hello:
+			hello := protocol.HelloDTO{}
+			if err = json.Unmarshal(env.Data, &hello); err == nil {
+				c.mu.Lock()
+				c.version = hello.Version
+				c.resume = hello.ResumeToken
+				c.mu.Unlock()
+			}
+		case protocol.GetPlayer:
+			p := protocol.Player{}
+			if err = json.Unmarshal(env.Data, &p); err != nil {
+				_ = conn.Close()
+				return err
+			}
+			_ = conn.SetReadDeadline(time.Time{})
+			c.mu.Lock()
+			c.player = p
+			c.mu.Unlock()
+			c.writeMu.Lock()
+			c.conn = conn
+			c.writeMu.Unlock()
+			return nil
+		}
+	}
+}
+
+// run 读取消息直到连接断开，然后按退避策略重连
+func (c *Client) run() {
+	defer c.stopEvents()
+	for {
+		c.emit(func() {
+			if c.handlers.OnConnect != nil {
+				c.handlers.OnConnect(c.Player())
+			}
+		})
+		// 订阅请求需要读循环处理回复，不能在这里等待
+		go c.resubscribe()
+		err := c.readLoop()
+		c.failPending(ErrDisconnected)
+		select {
+		case <-c.closed:
+			return
+		default:
+		}
+		c.emit(func() {
+			if c.handlers.OnDisconnect != nil {
+				c.handlers.OnDisconnect(err)
+			}
+		})
+		if c.opts.NoReconnect || !c.reconnect() {
+			return
+		}
+	}
+}
+
+func (c *Client) reconnect() bool {
+	backoff := c.opts.ReconnectMin
+	for {
+		select {
+		case <-c.closed:
+			return false
+		case <-time.After(backoff):
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.RequestTimeout)
+		err := c.connect(ctx)
+		cancel()
+		if err == nil {
+			return true
+		}
+		backoff *= 2
+		if backoff > c.opts.ReconnectMax {
+			backoff = c.opts.ReconnectMax
+		}
+	}
+}
+
+func (c *Client) readLoop() error {
+	c.writeMu.Lock()
+	conn := c.conn
+	c.writeMu.Unlock()
+	for {
+		env := &envelope{}
+		if err := conn.ReadJSON(env); err != nil {
+			return err
+		}
+		if env.Id != "" && c.resolve(env) {
+			continue
+		}
+		c.handle(env)
+	}
+}
+
+// resolve 将带请求 id 的消息交给等待中的请求，返回 false 表示没有对应的请求
+func (c *Client) resolve(env *envelope) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pc, ok := c.pending[env.Id]
+	if !ok {
+		return false
+	}
+	switch env.Code {
+	case protocol.Success:
+		delete(c.pending, env.Id)
+		pc.done <- nil
+	case protocol.Fail:
+		delete(c.pending, env.Id)
+		pc.done <- decodeErr(env)
+	default:
+		pc.reply = env
+	}
+	return true
+}
+
+func (c *Client) failPending(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, pc := range c.pending {
+		delete(c.pending, id)
+		pc.done <- err
+	}
+}
+
+// do 发送请求并等待确认，out 非空时将数据回复解析到 out
+func (c *Client) do(ctx context.Context, code int, data interface{}, out interface{}) error {
+	select {
+	case <-c.closed:
+		return ErrClosed
+	default:
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.opts.RequestTimeout)
+		defer cancel()
+	}
+
+	id := strconv.FormatUint(atomic.AddUint64(&c.seq, 1), 10)
+	pc := &call{done: make(chan error, 1)}
+	c.mu.Lock()
+	c.pending[id] = pc
+	c.mu.Unlock()
+
+	if err := c.write(&protocol.Message{Id: id, Code: code, Data: data}); err != nil {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return err
+	}
+
+	select {
+	case err := <-pc.done:
+		if err != nil || out == nil {
+			return err
+		}
+		if pc.reply == nil {
+			return fmt.Errorf("client: no reply for message code %v", code)
+		}
+		return json.Unmarshal(pc.reply.Data, out)
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (c *Client) write(msg *protocol.Message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.conn == nil {
+		return ErrDisconnected
+	}
+	return c.conn.WriteJSON(msg)
+}
+
+// emit 将回调放入事件队列，回调在独立的 goroutine 中按顺序执行，因此可以在回调中发起请求
+func (c *Client) emit(fn func()) {
+	c.emitMu.Lock()
+	defer c.emitMu.Unlock()
+	if c.stopped {
+		return
+	}
+	c.events = append(c.events, fn)
+	c.signal()
+}
+
+// stopEvents 停止事件队列，dispatch 执行完已排队的回调后退出
+func (c *Client) stopEvents() {
+	c.emitMu.Lock()
+	defer c.emitMu.Unlock()
+	c.stopped = true
+	c.signal()
+}
+
+// signal 唤醒 dispatch，调用方需持有 emitMu
+func (c *Client) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (c *Client) dispatch() {
+	for {
+		<-c.wake
+		c.emitMu.Lock()
+		events, stopped := c.events, c.stopped
+		c.events = nil
+		c.emitMu.Unlock()
+		for _, fn := range events {
+			fn()
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+func decodeErr(env *envelope) error {
+	e := &Error{}
+	if err := json.Unmarshal(env.Data, &e.ErrDTO); err != nil {
+		return fmt.Errorf("client: malformed error reply: %s", env.Data)
+	}
+	return e
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"encoding/json"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// RoomChat 房间聊天消息
+type RoomChat struct {
+	RId string `json:"rid"`
+	protocol.DialogMsg
+}
+
+// Step 落子，I、J 为棋盘坐标
+type Step struct {
+	RId string `json:"rid"`
+	I   int8   `json:"i"`
+	J   int8   `json:"j"`
+}
+
+// Consent 悔棋和求和请求中的 consent 取值
+const (
+	ConsentAsk    = 0 // 发起请求
+	ConsentReject = 1 // 拒绝对方的请求
+	ConsentAgree  = 2 // 同意对方的请求
+)
+
+// Proposal 悔棋或求和的请求与应答，Count 为同意悔棋后撤回的步数
+type Proposal struct {
+	RId     string `json:"rid"`
+	Consent int    `json:"consent"`
+	Count   int    `json:"count,omitempty"`
+}
+
+// Handlers 服务端推送消息的回调，回调在同一个 goroutine 中按消息顺序执行
+type Handlers struct {
+	OnConnect     func(player protocol.Player) // 连接或重连成功
+	OnDisconnect  func(err error)              // 连接断开，之后客户端会尝试重连
+	OnHallChat    func(msg protocol.DialogMsg)
+	OnRooms       func(lobby protocol.LobbyDTO)       // 订阅大厅时的完整数据，按最近一次 GetRooms 的条件取页
+	OnLobbyDelta  func(delta protocol.LobbyDeltaDTO)  // 订阅大厅后房间的增量更新
+	OnPlayers     func(players []protocol.Player)     // 订阅玩家列表时的完整数据
+	OnPlayerDelta func(delta protocol.PlayerDeltaDTO) // 订阅玩家列表后玩家的增量更新
+	OnRoom        func(code int, room protocol.Room)  // EnterRoom、LeaveRoom、SetReady 时房间状态变化
+	OnRoomClosed  func(rid string)
+	OnRoomChat    func(msg RoomChat)
+	OnStep        func(step Step)
+	OnRetract     func(p Proposal)
+	OnDraw        func(p Proposal)
+	OnGameOver    func(result protocol.GameOverDTO)
+	OnTree        func(tree protocol.TreeDTO) // 分析房间的变化树更新
+	OnPlayer      func(player protocol.Player)
+	OnError       func(err error) // 不属于任何请求的错误
+	OnUnknown     func(code int, data json.RawMessage)
+}
+
+// handle 解析服务端主动推送的消息并分发到对应的回调
+func (c *Client) handle(env *envelope) {
+	h := c.handlers
+	switch env.Code {
+	case protocol.Fail:
+		err := decodeErr(env)
+		c.emit(func() {
+			if h.OnError != nil {
+				h.OnError(err)
+			}
+		})
+	case protocol.HallChat:
+		emitDecoded(c, env, h.OnHallChat)
+	case protocol.GetRooms:
+		emitDecoded(c, env, h.OnRooms)
+	case protocol.GetPlayers:
+		emitDecoded(c, env, h.OnPlayers)
+	case protocol.LobbyDelta:
+		emitDecoded(c, env, h.OnLobbyDelta)
+	case protocol.PlayerDelta:
+		emitDecoded(c, env, h.OnPlayerDelta)
+	case protocol.GetPlayer:
+		emitDecoded(c, env, h.OnPlayer)
+	case protocol.EnterRoom, protocol.LeaveRoom, protocol.SetReady:
+		if h.OnRoom == nil {
+			return
+		}
+		code := env.Code
+		emitDecoded(c, env, func(room protocol.Room) { h.OnRoom(code, room) })
+	case protocol.DelRoom:
+		// 房间删除后服务端取消了对它的订阅，重连时不再恢复
+		var rid string
+		if err := json.Unmarshal(env.Data, &rid); err == nil {
+			c.mu.Lock()
+			delete(c.topics, protocol.RoomTopic(rid))
+			c.mu.Unlock()
+		}
+		emitDecoded(c, env, h.OnRoomClosed)
+	case protocol.RoomChat:
+		emitDecoded(c, env, h.OnRoomChat)
+	case protocol.MakeStep:
+		emitDecoded(c, env, h.OnStep)
+	case protocol.RetractStep:
+		emitDecoded(c, env, h.OnRetract)
+	case protocol.AskDraw:
+		emitDecoded(c, env, h.OnDraw)
+	case protocol.GameOver:
+		emitDecoded(c, env, h.OnGameOver)
+	case protocol.TreeMove, protocol.TreeGoto, protocol.TreeDelete, protocol.TreeLoad:
+		emitDecoded(c, env, h.OnTree)
+	default:
+		if h.OnUnknown != nil {
+			c.emit(func() { h.OnUnknown(env.Code, env.Data) })
+		}
+	}
+}
+
+func emitDecoded[T any](c *Client, env *envelope, fn func(T)) {
+	if fn == nil {
+		return
+	}
+	var v T
+	if err := json.Unmarshal(env.Data, &v); err != nil {
+		if c.handlers.OnError != nil {
+			c.emit(func() { c.handlers.OnError(err) })
+		}
+		return
+	}
+	c.emit(func() { fn(v) })
+}
@@ -0,0 +1,221 @@
+// Package alphabeta 基于迭代加深的 alpha-beta（negamax）搜索引擎。
+// 每层只展开攻守评分最高的若干候选点，并优先搜索上一轮主要变化中的着法。
+package alphabeta
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+const (
+	// Win 获胜局面的分数，实际返回值会减去到达胜局所需的步数
+	Win = engine.ScoreFive * 10
+
+	defaultDepth = 4
+	maxDepth     = 32
+	maxPly       = 64
+)
+
+var errStop = errors.New("alphabeta: stop")
+
+type Options struct {
+	Width int // 每层展开的候选点数量，默认 12
+}
+
+type Engine struct {
+	opts Options
+}
+
+func New(opts Options) *Engine {
+	if opts.Width == 0 {
+		opts.Width = 12
+	}
+	return &Engine{opts: opts}
+}
+
+func (e *Engine) Name() string {
+	return "alphabeta"
+}
+
+func (e *Engine) Close() error {
+	return nil
+}
+
+// searcher 一次搜索的状态
+type searcher struct {
+	b        *engine.Board
+	width    int
+	ctx      context.Context
+	deadline time.Time
+	maxNodes int64
+	nodes    int64
+
+	pv    [maxPly][maxPly]protocol.Chess
+	pvLen [maxPly]int
+	prev  []protocol.Chess // 上一轮迭代的主要变化
+}
+
+// Search 迭代加深直到达到深度、节点数或时间限制，返回最后一轮完整搜索的结果。
+// 未给出任何限制时搜索 4 层。
+func (e *Engine) Search(ctx context.Context, pos engine.Position, limits engine.Limits) (*engine.Result, error) {
+	b, err := engine.NewPositionBoard(pos)
+	if err != nil {
+		return nil, err
+	}
+	if b.Full() {
+		return nil, errors.New("alphabeta: board is full")
+	}
+	if b.Winner() != engine.Empty {
+		return nil, errors.New("alphabeta: game is already over")
+	}
+
+	depth := limits.Depth
+	if depth == 0 {
+		depth = maxDepth
+		if limits.Time == 0 && limits.Nodes == 0 {
+			if _, ok := ctx.Deadline(); !ok {
+				depth = defaultDepth
+			}
+		}
+	}
+
+	s := &searcher{
+		b:        b,
+		width:    e.opts.Width,
+		ctx:      ctx,
+		deadline: engine.Deadline(ctx, time.Now(), limits),
+		maxNodes: limits.Nodes,
+	}
+
+	var result *engine.Result
+	for d := 1; d <= depth; d++ {
+		score, err := s.negamax(d, -Win-1, Win+1, 0)
+		if err != nil || s.pvLen[0] == 0 {
+			break
+		}
+		pv := append([]protocol.Chess(nil), s.pv[0][:s.pvLen[0]]...)
+		result = &engine.Result{Move: pv[0], Score: score, PV: pv, Depth: d, Nodes: s.nodes}
+		s.prev = pv
+		// 已经找到必胜或必败，继续加深没有意义
+		if score > Win-maxPly || score < -Win+maxPly {
+			break
+		}
+	}
+	if result == nil {
+		// 连一层都没搜完，退回到一步评估
+		c, _ := e.fallback(b)
+		result = &engine.Result{Move: c, PV: []protocol.Chess{c}, Nodes: s.nodes}
+	}
+	result.Nodes = s.nodes
+	return result, nil
+}
+
+func (e *Engine) fallback(b *engine.Board) (protocol.Chess, int) {
+	moves := orderMoves(b, 1, nil)
+	return moves[0].c, moves[0].score
+}
+
+func (s *searcher) stop() bool {
+	if s.maxNodes > 0 && s.nodes >= s.maxNodes {
+		return true
+	}
+	if s.nodes&1023 == 0 {
+		if s.ctx.Err() != nil {
+			return true
+		}
+		if !s.deadline.IsZero() && time.Now().After(s.deadline) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *searcher) negamax(depth int, alpha int, beta int, ply int) (int, error) {
+	s.nodes++
+	s.pvLen[ply] = 0
+	if ply > 0 && s.stop() {
+		return 0, errStop
+	}
+	if s.b.Winner() != engine.Empty {
+		// 上一手已经连成五子，行棋方输棋；越早输分数越低
+		return -Win + ply, nil
+	}
+	if s.b.Full() {
+		return 0, nil
+	}
+	if depth == 0 || ply >= maxPly-1 {
+		return s.b.Evaluate(), nil
+	}
+
+	var hint *protocol.Chess
+	if ply < len(s.prev) {
+		hint = &s.prev[ply]
+	}
+	moves := orderMoves(s.b, s.width, hint)
+
+	best := -Win - 1
+	for _, m := range moves {
+		_ = s.b.Play(m.c)
+		score, err := s.negamax(depth-1, -beta, -alpha, ply+1)
+		s.b.Undo()
+		if err != nil {
+			return 0, err
+		}
+		score = -score
+		if score > best {
+			best = score
+			s.pv[ply][0] = m.c
+			copy(s.pv[ply][1:], s.pv[ply+1][:s.pvLen[ply+1]])
+			s.pvLen[ply] = s.pvLen[ply+1] + 1
+		}
+		if score > alpha {
+			alpha = score
+		}
+		if alpha >= beta {
+			break
+		}
+	}
+	return best, nil
+}
+
+type scored struct {
+	c     protocol.Chess
+	score int
+}
+
+// orderMoves 按攻守评分排序候选点，只保留前 width 个。
+// 有一手成五时只返回该点；对方有成五点时只返回防守点。
+func orderMoves(b *engine.Board, width int, hint *protocol.Chess) []scored {
+	me := b.ToMove()
+	cands := b.Candidates(2)
+	moves := make([]scored, 0, len(cands))
+	var blocks []scored
+	for _, c := range cands {
+		attack := b.PointScore(c, me)
+		defend := b.PointScore(c, me.Opponent())
+		if attack >= engine.ScoreFive {
+			return []scored{{c, attack}}
+		}
+		if defend >= engine.ScoreFive {
+			blocks = append(blocks, scored{c, defend})
+		}
+		score := attack + defend*9/10
+		if hint != nil && c == *hint {
+			score = Win
+		}
+		moves = append(moves, scored{c, score})
+	}
+	if len(blocks) > 0 {
+		return blocks
+	}
+	sort.Slice(moves, func(x, y int) bool { return moves[x].score > moves[y].score })
+	if len(moves) > width {
+		moves = moves[:width]
+	}
+	return moves
+}
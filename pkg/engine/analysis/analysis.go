@@ -0,0 +1,90 @@
+// Package analysis 在任意引擎之上提供多候选着法分析。
+package analysis
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Candidate 一个候选着法及其评估，Score 为行棋方视角
+type Candidate struct {
+	Move  protocol.Chess   `json:"move"`
+	Score int              `json:"score"`
+	PV    []protocol.Chess `json:"pv,omitempty"`
+}
+
+// TopMoves 返回行棋方最好的 k 个候选着法。
+// 先按攻守评分选出 2k 个候选点，再用引擎分别搜索每个候选点之后的局面，预算平均分配。
+func TopMoves(ctx context.Context, e engine.Engine, pos engine.Position, k int, limits engine.Limits) ([]Candidate, error) {
+	b, err := engine.NewPositionBoard(pos)
+	if err != nil {
+		return nil, err
+	}
+	if b.Winner() != engine.Empty || b.Full() || k <= 0 {
+		return []Candidate{}, nil
+	}
+
+	moves := Shortlist(b, 2*k)
+	per := engine.Limits{Nodes: limits.Nodes / int64(len(moves)), Depth: limits.Depth}
+	if limits.Time > 0 {
+		per.Time = limits.Time / time.Duration(len(moves))
+	}
+	if per.Depth > 1 {
+		per.Depth--
+	}
+
+	cands := make([]Candidate, 0, len(moves))
+	for _, c := range moves {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		cand := Candidate{Move: c, PV: []protocol.Chess{c}}
+		if b.IsFive(c, b.ToMove()) {
+			cand.Score = engine.ScoreFive * 10
+			cands = append(cands, cand)
+			continue
+		}
+		_ = b.Play(c)
+		res, err := e.Search(ctx, engine.Position{Steps: b.Steps(), Rules: pos.Rules}, per)
+		b.Undo()
+		if err != nil {
+			return nil, err
+		}
+		cand.Score = -res.Score
+		cand.PV = append(cand.PV, res.PV...)
+		cands = append(cands, cand)
+	}
+
+	sort.SliceStable(cands, func(x, y int) bool { return cands[x].Score > cands[y].Score })
+	if len(cands) > k {
+		cands = cands[:k]
+	}
+	return cands, nil
+}
+
+// Shortlist 按攻守评分返回最多 n 个候选点
+func Shortlist(b *engine.Board, n int) []protocol.Chess {
+	me := b.ToMove()
+	type scored struct {
+		c     protocol.Chess
+		score int
+	}
+	cands := b.Candidates(2)
+	moves := make([]scored, 0, len(cands))
+	for _, c := range cands {
+		moves = append(moves, scored{c, b.PointScore(c, me) + b.PointScore(c, me.Opponent())*9/10})
+	}
+	sort.SliceStable(moves, func(x, y int) bool { return moves[x].score > moves[y].score })
+	if len(moves) > n {
+		moves = moves[:n]
+	}
+	out := make([]protocol.Chess, len(moves))
+	for k, m := range moves {
+		out[k] = m.c
+	}
+	return out
+}
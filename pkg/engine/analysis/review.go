@@ -0,0 +1,108 @@
+package analysis
+
+import (
+	"context"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/solver"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// WinScore 评估分达到该值视为必胜
+const WinScore = engine.ScoreFive
+
+// ReviewOptions 复盘参数
+type ReviewOptions struct {
+	Limits   engine.Limits // 每个局面的搜索预算
+	VCFDepth int           // 连续冲四求解的最大冲四次数，默认 8
+	Blunder  int           // 相对推荐着法损失超过该分数记为败着，默认 3000
+}
+
+// Review 逐手复盘 pos 中的着法：每手记录之后的评估、引擎推荐的着法和损失，
+// 标记败着（损失过大，或让对方获得连续冲四胜）和漏掉的胜利（原本有连续冲四胜或必胜评估却没有走出来）。
+func Review(ctx context.Context, e engine.Engine, pos engine.Position, opts ReviewOptions) ([]protocol.MoveReview, error) {
+	if opts.VCFDepth == 0 {
+		opts.VCFDepth = 8
+	}
+	if opts.Blunder == 0 {
+		opts.Blunder = 3000
+	}
+	b, err := engine.NewBoardWithRules(nil, pos.Rules)
+	if err != nil {
+		return nil, err
+	}
+
+	reviews := make([]protocol.MoveReview, 0, len(pos.Steps))
+	// before 为当前局面行棋方视角的搜索结果，复用上一手之后的搜索
+	var before *engine.Result
+	for k, c := range pos.Steps {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		if before == nil {
+			if before, err = e.Search(ctx, engine.Position{Steps: b.Steps(), Rules: pos.Rules}, opts.Limits); err != nil {
+				return nil, err
+			}
+		}
+		me := b.ToMove()
+		r := protocol.MoveReview{Ply: k + 1, Move: c, Best: before.Move, PV: before.PV}
+
+		var s solver.Solver
+		vcf := s.VCF(ctx, b, opts.VCFDepth)
+		if vcf != nil && !s.Wins(ctx, b, c, opts.VCFDepth) {
+			r.MissedWin = true
+			r.VCF = vcf
+		}
+
+		if err = b.Play(c); err != nil {
+			return nil, err
+		}
+		// actual 为这手棋之后行棋方（me）视角的评估
+		var actual int
+		var after *engine.Result
+		switch {
+		case b.Winner() != engine.Empty:
+			actual = WinScore * 10
+		case b.Full():
+			actual = 0
+		default:
+			if after, err = e.Search(ctx, engine.Position{Steps: b.Steps(), Rules: pos.Rules}, opts.Limits); err != nil {
+				return nil, err
+			}
+			actual = -after.Score
+		}
+
+		best := before.Score
+		if c == before.Move || actual > best {
+			best = actual
+		}
+		r.Loss = best - actual
+		if best >= WinScore && actual < WinScore {
+			r.MissedWin = true
+		}
+		if r.Loss >= opts.Blunder || best > -WinScore && actual <= -WinScore {
+			r.Blunder = true
+		}
+		// 这手棋之后对方有连续冲四胜，而推荐着法可以避免
+		if after != nil && !r.MissedWin {
+			if line := s.VCF(ctx, b, opts.VCFDepth); line != nil && c != before.Move {
+				b.Undo()
+				safe := b.Play(before.Move) == nil && s.VCF(ctx, b, opts.VCFDepth) == nil
+				b.Undo()
+				_ = b.Play(c)
+				if safe {
+					r.Blunder = true
+					r.VCF = line
+				}
+			}
+		}
+
+		r.Eval, r.BestEval = actual, best
+		if me == engine.White {
+			r.Eval, r.BestEval = -actual, -best
+		}
+		reviews = append(reviews, r)
+		before = after
+	}
+	return reviews, nil
+}
@@ -0,0 +1,47 @@
+// Package basic 一个只看一步的启发式引擎，在攻击分与防守分之和最高的位置落子。
+package basic
+
+import (
+	"context"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+type Engine struct{}
+
+func New() *Engine {
+	return &Engine{}
+}
+
+func (e *Engine) Name() string {
+	return "basic"
+}
+
+// Search 只看一步，忽略搜索预算
+func (e *Engine) Search(ctx context.Context, pos engine.Position, limits engine.Limits) (*engine.Result, error) {
+	b, err := engine.NewPositionBoard(pos)
+	if err != nil {
+		return nil, err
+	}
+	c, score := BestMove(b)
+	return &engine.Result{Move: c, Score: score, PV: []protocol.Chess{c}, Depth: 1}, nil
+}
+
+// BestMove 返回攻守评分最高的空点及其评分，防守分打九折以便在同等情况下优先进攻
+func BestMove(b *engine.Board) (protocol.Chess, int) {
+	me := b.ToMove()
+	var best protocol.Chess
+	bestScore := -1
+	for _, c := range b.Candidates(2) {
+		score := b.PointScore(c, me) + b.PointScore(c, me.Opponent())*9/10
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best, bestScore
+}
+
+func (e *Engine) Close() error {
+	return nil
+}
@@ -0,0 +1,203 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Size 棋盘边长
+const Size = int(protocol.BoardSize)
+
+// Stone 棋盘上一个交叉点的状态
+type Stone int8
+
+const (
+	Empty Stone = iota
+	Black
+	White
+)
+
+func (s Stone) Opponent() Stone {
+	return 3 - s
+}
+
+func (s Stone) String() string {
+	switch s {
+	case Black:
+		return "black"
+	case White:
+		return "white"
+	}
+	return "empty"
+}
+
+// Color 返回对应的 protocol.BLACK 或 protocol.WHITE
+func (s Stone) Color() int8 {
+	if s == White {
+		return protocol.WHITE
+	}
+	return protocol.BLACK
+}
+
+// 四个方向：横、竖、正斜、反斜
+var directions = [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}}
+
+// Board 由着法序列还原的棋盘，黑方先行
+type Board struct {
+	cells [Size][Size]Stone
+	steps []protocol.Chess
+	rules Rules
+}
+
+// NewBoard 按默认规则落下 steps，非法着法返回错误
+func NewBoard(steps []protocol.Chess) (*Board, error) {
+	return NewBoardWithRules(steps, Rules{})
+}
+
+// NewPositionBoard 按局面的规则还原棋盘
+func NewPositionBoard(pos Position) (*Board, error) {
+	return NewBoardWithRules(pos.Steps, pos.Rules)
+}
+
+func NewBoardWithRules(steps []protocol.Chess, rules Rules) (*Board, error) {
+	b := &Board{steps: make([]protocol.Chess, 0, len(steps)+8), rules: rules}
+	for _, c := range steps {
+		if err := b.Play(c); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+func InBoard(i int, j int) bool {
+	return i >= 0 && j >= 0 && i < Size && j < Size
+}
+
+// At 返回 (i, j) 上的棋子，调用方需保证坐标在棋盘内
+func (b *Board) At(i int, j int) Stone {
+	return b.cells[i][j]
+}
+
+// Play 为行棋方落子
+func (b *Board) Play(c protocol.Chess) error {
+	if !InBoard(int(c.I), int(c.J)) {
+		return fmt.Errorf("engine: move %v is outside the board", FormatMove(c))
+	}
+	if b.cells[c.I][c.J] != Empty {
+		return fmt.Errorf("engine: %v is already taken", FormatMove(c))
+	}
+	b.cells[c.I][c.J] = b.ToMove()
+	b.steps = append(b.steps, c)
+	return nil
+}
+
+// Undo 撤回最后一手
+func (b *Board) Undo() {
+	last := b.steps[len(b.steps)-1]
+	b.cells[last.I][last.J] = Empty
+	b.steps = b.steps[:len(b.steps)-1]
+}
+
+// ToMove 返回行棋方
+func (b *Board) ToMove() Stone {
+	if len(b.steps)%2 == 0 {
+		return Black
+	}
+	return White
+}
+
+func (b *Board) Len() int {
+	return len(b.steps)
+}
+
+// Steps 返回着法序列的副本
+func (b *Board) Steps() []protocol.Chess {
+	return append([]protocol.Chess(nil), b.steps...)
+}
+
+// Last 返回最后一手，空棋盘返回 false
+func (b *Board) Last() (protocol.Chess, bool) {
+	if len(b.steps) == 0 {
+		return protocol.Chess{}, false
+	}
+	return b.steps[len(b.steps)-1], true
+}
+
+func (b *Board) Full() bool {
+	return len(b.steps) == Size*Size
+}
+
+// Clone 复制棋盘，用于并行搜索
+func (b *Board) Clone() *Board {
+	nb := &Board{cells: b.cells, steps: make([]protocol.Chess, len(b.steps), cap(b.steps)), rules: b.rules}
+	copy(nb.steps, b.steps)
+	return nb
+}
+
+// lineLength 返回在 (i, j) 放置 s 后经过该点在方向 d 上的连子数
+func (b *Board) lineLength(i int, j int, s Stone, d [2]int) int {
+	n := 1
+	for k := 1; InBoard(i+k*d[0], j+k*d[1]) && b.cells[i+k*d[0]][j+k*d[1]] == s; k++ {
+		n++
+	}
+	for k := 1; InBoard(i-k*d[0], j-k*d[1]) && b.cells[i-k*d[0]][j-k*d[1]] == s; k++ {
+		n++
+	}
+	return n
+}
+
+func (b *Board) Rules() Rules {
+	return b.rules
+}
+
+// IsFive 判断在 c 落下 s 是否形成获胜的连珠，ExactFive 规则下长连不算
+func (b *Board) IsFive(c protocol.Chess, s Stone) bool {
+	for _, d := range directions {
+		n := b.lineLength(int(c.I), int(c.J), s, d)
+		if n == 5 || (n > 5 && !b.rules.ExactFive) {
+			return true
+		}
+	}
+	return false
+}
+
+// Winner 判断最后一手是否形成连珠，返回获胜方，否则返回 Empty
+func (b *Board) Winner() Stone {
+	last, ok := b.Last()
+	if !ok {
+		return Empty
+	}
+	s := b.cells[last.I][last.J]
+	if b.IsFive(last, s) {
+		return s
+	}
+	return Empty
+}
+
+// Candidates 返回距离已有棋子 radius 以内的空点，空棋盘返回天元
+func (b *Board) Candidates(radius int) []protocol.Chess {
+	if len(b.steps) == 0 {
+		return []protocol.Chess{{I: int8(Size / 2), J: int8(Size / 2)}}
+	}
+	var near [Size][Size]bool
+	for _, c := range b.steps {
+		for di := -radius; di <= radius; di++ {
+			for dj := -radius; dj <= radius; dj++ {
+				i, j := int(c.I)+di, int(c.J)+dj
+				if InBoard(i, j) {
+					near[i][j] = true
+				}
+			}
+		}
+	}
+	cands := make([]protocol.Chess, 0, 64)
+	for i := 0; i < Size; i++ {
+		for j := 0; j < Size; j++ {
+			if near[i][j] && b.cells[i][j] == Empty {
+				cands = append(cands, protocol.Chess{I: int8(i), J: int8(j)})
+			}
+		}
+	}
+	return cands
+}
@@ -0,0 +1,59 @@
+// Package engine 定义五子棋引擎的公共接口，机器人和对战工具通过它使用不同的引擎实现。
+package engine
+
+import (
+	"context"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Rules 对局规则
+type Rules struct {
+	// ExactFive 为 true 时只有恰好五连才获胜；默认长连也算获胜，与服务端判定一致
+	ExactFive bool `json:"exact_five"`
+}
+
+// Position 待搜索的局面：黑方先行的着法序列和规则
+type Position struct {
+	Steps []protocol.Chess
+	Rules Rules
+}
+
+// Limits 搜索预算，零值表示不限制该项；全部为零时由引擎自行决定
+type Limits struct {
+	Time  time.Duration
+	Nodes int64
+	Depth int
+}
+
+// Result 搜索结果，Score 为行棋方视角的评估分，PV 为主要变化（第一手即 Move），不支持的引擎可以留空
+type Result struct {
+	Move  protocol.Chess   `json:"move"`
+	Score int              `json:"score"`
+	PV    []protocol.Chess `json:"pv,omitempty"`
+	Depth int              `json:"depth,omitempty"`
+	Nodes int64            `json:"nodes,omitempty"`
+}
+
+// Engine 为行棋方搜索下一手
+type Engine interface {
+	Name() string
+	Search(ctx context.Context, pos Position, limits Limits) (*Result, error)
+	Close() error
+}
+
+// Factory 创建一个新的引擎实例，引擎崩溃后用于重新启动
+type Factory func(ctx context.Context) (Engine, error)
+
+// Deadline 计算搜索应当结束的时间，取 limits.Time 与 ctx 截止时间中较早的一个，不限时返回零值
+func Deadline(ctx context.Context, start time.Time, limits Limits) time.Time {
+	var deadline time.Time
+	if limits.Time > 0 {
+		deadline = start.Add(limits.Time)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+	return deadline
+}
@@ -0,0 +1,51 @@
+// Package engines 按名称创建引擎，供机器人、对战工具和服务端统一选择引擎。
+//
+// 支持的写法：
+//
+//	basic                       一步启发式
+//	alphabeta                   alpha-beta 搜索
+//	mcts                        蒙特卡洛树搜索
+//	pbrain:<可执行文件> [参数...]  外部 pbrain 引擎，也可以直接写可执行文件路径
+package engines
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/alphabeta"
+	"github.com/toujourser/gomoku/pkg/engine/basic"
+	"github.com/toujourser/gomoku/pkg/engine/mcts"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+)
+
+// Builtin 内置引擎的名称
+var Builtin = []string{"basic", "alphabeta", "mcts"}
+
+// New 根据 spec 创建引擎，pbrain 引擎使用 opts 启动
+func New(ctx context.Context, spec string, opts pbrain.Options) (engine.Engine, error) {
+	kind, rest, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "basic":
+		return basic.New(), nil
+	case "alphabeta":
+		return alphabeta.New(alphabeta.Options{}), nil
+	case "mcts":
+		return mcts.New(mcts.Options{}), nil
+	case "pbrain":
+		spec = rest
+	}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("engines: empty engine spec")
+	}
+	return pbrain.Start(ctx, fields[0], fields[1:], opts)
+}
+
+// Factory 返回按 spec 创建引擎的工厂函数
+func Factory(spec string, opts pbrain.Options) engine.Factory {
+	return func(ctx context.Context) (engine.Engine, error) {
+		return New(ctx, spec, opts)
+	}
+}
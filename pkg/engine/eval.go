@@ -0,0 +1,180 @@
+package engine
+
+import "github.com/toujourser/gomoku/pkg/protocol"
+
+// 棋型分数
+const (
+	ScoreFive      = 10000000
+	ScoreOpenFour  = 1000000
+	ScoreDouble    = 500000 // 双四、四三等一手无法同时防住的组合
+	ScoreFour      = 10000
+	ScoreOpenThree = 5000
+	ScoreThree     = 500
+	ScoreOpenTwo   = 300
+	ScoreTwo       = 50
+	ScoreOne       = 1
+)
+
+// Shape 一个方向上的棋型
+type Shape int8
+
+const (
+	ShapeNone Shape = iota
+	ShapeOne
+	ShapeTwo
+	ShapeOpenTwo
+	ShapeThree
+	ShapeOpenThree
+	ShapeFour
+	ShapeOpenFour
+	ShapeFive
+)
+
+var shapeScores = [...]int{0, ScoreOne, ScoreTwo, ScoreOpenTwo, ScoreThree, ScoreOpenThree, ScoreFour, ScoreOpenFour, ScoreFive}
+
+// shapeAt 判断在空点 (i, j) 放置 s 后方向 d 上形成的棋型
+func (b *Board) shapeAt(i int, j int, s Stone, d [2]int) Shape {
+	// 取以 (i, j) 为中心、两侧各 4 格的一条线，越界视为对方棋子
+	var line [9]Stone
+	for k := -4; k <= 4; k++ {
+		m, n := i+k*d[0], j+k*d[1]
+		switch {
+		case k == 0:
+			line[4] = s
+		case !InBoard(m, n):
+			line[k+4] = s.Opponent()
+		default:
+			line[k+4] = b.cells[m][n]
+		}
+	}
+
+	// 连续的同色棋子及两端是否为空
+	lo, hi := 4, 4
+	for lo > 0 && line[lo-1] == s {
+		lo--
+	}
+	for hi < 8 && line[hi+1] == s {
+		hi++
+	}
+	count := hi - lo + 1
+	open := 0
+	if lo > 0 && line[lo-1] == Empty {
+		open++
+	}
+	if hi < 8 && line[hi+1] == Empty {
+		open++
+	}
+
+	// 包含中心点、没有对方棋子的五格窗口中己方棋子最多的数量，用于识别跳四、跳三
+	window := 0
+	for start := 0; start <= 4; start++ {
+		n, blocked := 0, false
+		for k := start; k < start+5; k++ {
+			if line[k] == s {
+				n++
+			} else if line[k] != Empty {
+				blocked = true
+				break
+			}
+		}
+		if !blocked && n > window {
+			window = n
+		}
+	}
+
+	switch {
+	case count == 5 || count > 5 && !b.rules.ExactFive:
+		return ShapeFive
+	case count > 5:
+		return ShapeNone
+	case count == 4 && open == 2:
+		return ShapeOpenFour
+	case count == 4 && open == 1, window == 4:
+		return ShapeFour
+	case count == 3 && open == 2 && window >= 3:
+		return ShapeOpenThree
+	case window == 3:
+		return ShapeThree
+	case count == 2 && open == 2 && window >= 2:
+		return ShapeOpenTwo
+	case window == 2:
+		return ShapeTwo
+	case window == 1:
+		return ShapeOne
+	}
+	return ShapeNone
+}
+
+// PointScore 评估在空点 c 落下 s 后形成的棋型总分
+func (b *Board) PointScore(c protocol.Chess, s Stone) int {
+	score, threats := 0, 0
+	for _, d := range directions {
+		shape := b.shapeAt(int(c.I), int(c.J), s, d)
+		score += shapeScores[shape]
+		if shape == ShapeFour || shape == ShapeOpenThree {
+			threats++
+		}
+	}
+	if threats >= 2 && score < ScoreOpenFour {
+		score += ScoreDouble
+	}
+	return score
+}
+
+// Shapes 返回在空点 c 落下 s 后四个方向的棋型
+func (b *Board) Shapes(c protocol.Chess, s Stone) [4]Shape {
+	var shapes [4]Shape
+	for k, d := range directions {
+		shapes[k] = b.shapeAt(int(c.I), int(c.J), s, d)
+	}
+	return shapes
+}
+
+// 五格窗口内己方棋子数对应的分数，窗口内有对方棋子时不计分
+var windowScores = [6]int{0, 1, 10, 100, 1000, ScoreFive}
+
+// windows 棋盘上所有五格窗口的坐标
+var windows = func() [][5][2]int {
+	var ws [][5][2]int
+	for i := 0; i < Size; i++ {
+		for j := 0; j < Size; j++ {
+			for _, d := range directions {
+				if !InBoard(i+4*d[0], j+4*d[1]) {
+					continue
+				}
+				var w [5][2]int
+				for k := 0; k < 5; k++ {
+					w[k] = [2]int{i + k*d[0], j + k*d[1]}
+				}
+				ws = append(ws, w)
+			}
+		}
+	}
+	return ws
+}()
+
+// Evaluate 静态评估局面，返回行棋方视角的分数。
+// 统计所有只含一方棋子的五格窗口，行棋方的窗口额外加权以体现先手优势。
+func (b *Board) Evaluate() int {
+	me := b.ToMove()
+	mine, theirs := 0, 0
+	for _, w := range windows {
+		nm, nt := 0, 0
+		for _, p := range w {
+			switch b.cells[p[0]][p[1]] {
+			case me:
+				nm++
+			case Empty:
+			default:
+				nt++
+			}
+		}
+		switch {
+		case nm > 0 && nt == 0:
+			mine += windowScores[nm]
+		case nt > 0 && nm == 0:
+			theirs += windowScores[nt]
+		}
+	}
+	return mine*6/5 - theirs
+}
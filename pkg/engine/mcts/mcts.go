@@ -0,0 +1,271 @@
+// Package mcts 基于蒙特卡洛树搜索（UCT）的引擎。
+// 树中每个节点只展开攻守评分最高的若干候选点，模拟阶段使用"能赢就赢、该堵就堵、否则随机"的快速走子。
+package mcts
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+const (
+	defaultIterations = 20000
+	exploration       = 1.0
+)
+
+type Options struct {
+	Width        int   // 每个节点展开的候选点数量，默认 10
+	PlayoutDepth int   // 模拟的最大步数，超过后按静态评估判定胜负，默认 30
+	Seed         int64 // 随机种子，0 表示使用当前时间
+}
+
+type Engine struct {
+	opts Options
+	rnd  *rand.Rand
+}
+
+func New(opts Options) *Engine {
+	if opts.Width == 0 {
+		opts.Width = 10
+	}
+	if opts.PlayoutDepth == 0 {
+		opts.PlayoutDepth = 30
+	}
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &Engine{opts: opts, rnd: rand.New(rand.NewSource(seed))}
+}
+
+func (e *Engine) Name() string {
+	return "mcts"
+}
+
+func (e *Engine) Close() error {
+	return nil
+}
+
+// node 搜索树节点，wins 为走到该节点的一方（即 move 的落子方）的累计得分
+type node struct {
+	move     protocol.Chess
+	parent   *node
+	children []*node
+	untried  []protocol.Chess
+	visits   float64
+	wins     float64
+	terminal bool
+}
+
+// Search 在预算内重复"选择-扩展-模拟-回传"，返回访问次数最多的着法。
+// Nodes 限制模拟次数，Depth 被忽略；未给出任何限制时模拟 20000 次。
+func (e *Engine) Search(ctx context.Context, pos engine.Position, limits engine.Limits) (*engine.Result, error) {
+	b, err := engine.NewPositionBoard(pos)
+	if err != nil {
+		return nil, err
+	}
+	if b.Full() {
+		return nil, errors.New("mcts: board is full")
+	}
+
+	deadline := engine.Deadline(ctx, time.Now(), limits)
+	iterations := limits.Nodes
+	if iterations == 0 && deadline.IsZero() {
+		iterations = defaultIterations
+	}
+
+	root := &node{untried: e.expand(b)}
+	var n int64
+	for ; iterations == 0 || n < iterations; n++ {
+		if n&255 == 0 && (ctx.Err() != nil || (!deadline.IsZero() && time.Now().After(deadline))) {
+			break
+		}
+		e.iterate(b, root)
+		// 根节点只有一个选择（成五或必须防守）时无需继续
+		if len(root.children) == 1 && len(root.untried) == 0 && n > 0 {
+			break
+		}
+	}
+
+	if len(root.children) == 0 {
+		c := root.untried[0]
+		return &engine.Result{Move: c, PV: []protocol.Chess{c}, Nodes: n}, nil
+	}
+
+	var pv []protocol.Chess
+	for cur := root; len(cur.children) > 0; {
+		cur = mostVisited(cur)
+		pv = append(pv, cur.move)
+	}
+	best := mostVisited(root)
+	// 胜率映射到 [-1000, 1000]
+	score := int(math.Round((2*best.wins/best.visits - 1) * 1000))
+	return &engine.Result{Move: best.move, Score: score, PV: pv, Depth: len(pv), Nodes: n}, nil
+}
+
+func mostVisited(n *node) *node {
+	best := n.children[0]
+	for _, c := range n.children[1:] {
+		if c.visits > best.visits {
+			best = c
+		}
+	}
+	return best
+}
+
+// iterate 完成一次选择、扩展、模拟和回传，结束后棋盘恢复原状
+func (e *Engine) iterate(b *engine.Board, root *node) {
+	played := 0
+	cur := root
+
+	// 选择
+	for len(cur.untried) == 0 && len(cur.children) > 0 && !cur.terminal {
+		cur = selectChild(cur)
+		_ = b.Play(cur.move)
+		played++
+	}
+
+	// 扩展
+	if !cur.terminal && len(cur.untried) > 0 {
+		c := cur.untried[0]
+		cur.untried = cur.untried[1:]
+		_ = b.Play(c)
+		played++
+		child := &node{move: c, parent: cur}
+		if b.Winner() != engine.Empty || b.Full() {
+			child.terminal = true
+		} else {
+			child.untried = e.expand(b)
+		}
+		cur.children = append(cur.children, child)
+		cur = child
+	}
+
+	// 模拟：result 为 cur 落子方的得分
+	result := e.playout(b, cur)
+
+	// 回传
+	for n := cur; n != nil; n = n.parent {
+		n.visits++
+		n.wins += result
+		result = 1 - result
+	}
+	for ; played > 0; played-- {
+		b.Undo()
+	}
+}
+
+func selectChild(n *node) *node {
+	var best *node
+	bestValue := math.Inf(-1)
+	logN := math.Log(n.visits)
+	for _, c := range n.children {
+		value := c.wins/c.visits + exploration*math.Sqrt(logN/c.visits)
+		if value > bestValue {
+			best, bestValue = c, value
+		}
+	}
+	return best
+}
+
+// expand 按攻守评分返回待展开的候选点
+func (e *Engine) expand(b *engine.Board) []protocol.Chess {
+	me := b.ToMove()
+	type scored struct {
+		c     protocol.Chess
+		score int
+	}
+	cands := b.Candidates(1)
+	moves := make([]scored, 0, len(cands))
+	var blocks []protocol.Chess
+	for _, c := range cands {
+		attack := b.PointScore(c, me)
+		defend := b.PointScore(c, me.Opponent())
+		if attack >= engine.ScoreFive {
+			return []protocol.Chess{c}
+		}
+		if defend >= engine.ScoreFive {
+			blocks = append(blocks, c)
+		}
+		moves = append(moves, scored{c, attack + defend*9/10})
+	}
+	if len(blocks) > 0 {
+		return blocks
+	}
+	sort.Slice(moves, func(x, y int) bool { return moves[x].score > moves[y].score })
+	if len(moves) > e.opts.Width {
+		moves = moves[:e.opts.Width]
+	}
+	out := make([]protocol.Chess, len(moves))
+	for k, m := range moves {
+		out[k] = m.c
+	}
+	return out
+}
+
+// playout 从当前局面快速走子到终局或最大步数，返回 n 的落子方的得分（胜 1、和 0.5、负 0）
+func (e *Engine) playout(b *engine.Board, n *node) float64 {
+	if n.parent == nil {
+		return 0.5
+	}
+	mover := b.ToMove().Opponent()
+	if n.terminal {
+		if b.Winner() == mover {
+			return 1
+		}
+		return 0.5
+	}
+
+	played := 0
+	defer func() {
+		for ; played > 0; played-- {
+			b.Undo()
+		}
+	}()
+	for k := 0; k < e.opts.PlayoutDepth; k++ {
+		c := e.rolloutMove(b)
+		_ = b.Play(c)
+		played++
+		if w := b.Winner(); w != engine.Empty {
+			if w == mover {
+				return 1
+			}
+			return 0
+		}
+		if b.Full() {
+			return 0.5
+		}
+	}
+
+	// 未分胜负时按静态评估估计胜率
+	score := float64(b.Evaluate())
+	if b.ToMove() != mover {
+		score = -score
+	}
+	return 1 / (1 + math.Exp(-score/2000))
+}
+
+// rolloutMove 能成五则成五，需要防守则防守，否则在邻近空点中随机选择
+func (e *Engine) rolloutMove(b *engine.Board) protocol.Chess {
+	me := b.ToMove()
+	cands := b.Candidates(1)
+	var block *protocol.Chess
+	for k, c := range cands {
+		if b.IsFive(c, me) {
+			return c
+		}
+		if block == nil && b.IsFive(c, me.Opponent()) {
+			block = &cands[k]
+		}
+	}
+	if block != nil {
+		return *block
+	}
+	return cands[e.rnd.Intn(len(cands))]
+}
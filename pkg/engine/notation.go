@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// ParseMove 解析 h8 形式的坐标，字母为列（J），数字为行（I+1）
+func ParseMove(s string) (protocol.Chess, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 || s[0] < 'a' || s[0] >= 'a'+byte(Size) {
+		return protocol.Chess{}, fmt.Errorf("engine: invalid move %q", s)
+	}
+	row, err := strconv.Atoi(s[1:])
+	if err != nil || row < 1 || row > Size {
+		return protocol.Chess{}, fmt.Errorf("engine: invalid move %q", s)
+	}
+	return protocol.Chess{I: int8(row - 1), J: int8(s[0] - 'a')}, nil
+}
+
+// ParseMoves 解析以空白或逗号分隔的着法序列，例如 "h8 i9 j10"
+func ParseMoves(s string) ([]protocol.Chess, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+	moves := make([]protocol.Chess, 0, len(fields))
+	for _, f := range fields {
+		c, err := ParseMove(f)
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, c)
+	}
+	return moves, nil
+}
+
+func FormatMove(c protocol.Chess) string {
+	return fmt.Sprintf("%c%d", 'a'+c.J, c.I+1)
+}
+
+func FormatMoves(steps []protocol.Chess) string {
+	parts := make([]string, len(steps))
+	for k, c := range steps {
+		parts[k] = FormatMove(c)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,361 @@
+// Package pbrain 通过 Piskvork/Gomocup 的 stdin/stdout 协议驱动外部引擎进程。
+//
+// 坐标约定：协议中的 x 对应 protocol.Chess 的列 J，y 对应行 I。
+package pbrain
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+var (
+	ErrCrashed = errors.New("pbrain: engine exited")
+	ErrTimeout = errors.New("pbrain: engine did not answer in time")
+)
+
+type Options struct {
+	TurnTimeout  time.Duration // 每手的思考时间，通过 INFO timeout_turn 告知引擎
+	MatchTimeout time.Duration // 整局的思考时间，0 表示不限
+	MaxMemory    int64         // 引擎可用内存字节数，0 表示不限
+	Grace        time.Duration // 在思考时间之外等待回复的余量，默认 1 秒
+	StartTimeout time.Duration // 等待 START 回复的时间，默认 5 秒
+	Dir          string        // 引擎进程的工作目录
+}
+
+// Engine 一个 pbrain 引擎进程，实现 engine.Engine
+type Engine struct {
+	opts Options
+	name string
+	cmd  *exec.Cmd
+
+	mu    sync.Mutex
+	stdin io.WriteCloser
+	lines chan string
+	exit  chan struct{}
+	err   error
+	known []protocol.Chess // 引擎当前所知的着法序列
+	begun bool
+	turn  time.Duration // 最近一次告知引擎的单手时间
+	rules engine.Rules
+}
+
+// Start 启动引擎进程并完成 START 握手
+func Start(ctx context.Context, path string, args []string, opts Options) (*Engine, error) {
+	if opts.Grace == 0 {
+		opts.Grace = time.Second
+	}
+	if opts.StartTimeout == 0 {
+		opts.StartTimeout = 5 * time.Second
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Dir = opts.Dir
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e := &Engine{
+		opts:  opts,
+		name:  filepath.Base(path),
+		cmd:   cmd,
+		stdin: stdin,
+		lines: make(chan string, 16),
+		exit:  make(chan struct{}),
+	}
+	go e.read(stdout)
+
+	if err = e.handshake(ctx); err != nil {
+		_ = e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Engine) handshake(ctx context.Context) error {
+	if err := e.send(fmt.Sprintf("START %d", protocol.BoardSize)); err != nil {
+		return err
+	}
+	if err := e.expectOK(ctx, e.opts.StartTimeout); err != nil {
+		return fmt.Errorf("pbrain: START: %w", err)
+	}
+	// rule 0：五子或以上连珠获胜，与服务端判定一致
+	infos := []string{"INFO rule 0"}
+	if e.opts.TurnTimeout > 0 {
+		infos = append(infos, fmt.Sprintf("INFO timeout_turn %d", e.opts.TurnTimeout.Milliseconds()))
+		e.turn = e.opts.TurnTimeout
+	}
+	infos = append(infos, fmt.Sprintf("INFO timeout_match %d", e.opts.MatchTimeout.Milliseconds()))
+	if e.opts.MaxMemory > 0 {
+		infos = append(infos, fmt.Sprintf("INFO max_memory %d", e.opts.MaxMemory))
+	}
+	for _, info := range infos {
+		if err := e.send(info); err != nil {
+			return err
+		}
+	}
+
+	if err := e.send("ABOUT"); err != nil {
+		return err
+	}
+	if line, err := e.next(ctx, e.opts.StartTimeout); err == nil {
+		if name := aboutField(line, "name"); name != "" {
+			e.name = name
+		}
+	}
+	return nil
+}
+
+func (e *Engine) Name() string {
+	return e.name
+}
+
+// Search 将局面同步给引擎并返回引擎的下一手，pbrain 协议不提供评估和主要变化。
+// limits.Time 通过 INFO timeout_turn 告知引擎，节点数和深度限制被忽略。
+func (e *Engine) Search(ctx context.Context, pos engine.Position, limits engine.Limits) (*engine.Result, error) {
+	c, err := e.move(ctx, pos, limits.Time)
+	if err != nil {
+		return nil, err
+	}
+	return &engine.Result{Move: c, PV: []protocol.Chess{c}}, nil
+}
+
+// move 若 steps 只比引擎已知的局面多一手对方着法则发送 TURN，空棋盘发送 BEGIN，否则发送 BOARD
+func (e *Engine) move(ctx context.Context, pos engine.Position, turn time.Duration) (protocol.Chess, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	steps := pos.Steps
+	if err := e.sync(pos.Rules, turn); err != nil {
+		return protocol.Chess{}, err
+	}
+	if turn == 0 {
+		turn = e.opts.TurnTimeout
+	}
+
+	var err error
+	switch {
+	case len(steps) == 0:
+		if e.begun {
+			if err = e.send("RESTART"); err == nil {
+				err = e.expectOK(ctx, e.opts.StartTimeout)
+			}
+		}
+		if err == nil {
+			err = e.send("BEGIN")
+		}
+	case len(steps) == len(e.known)+1 && hasPrefix(steps, e.known):
+		last := steps[len(steps)-1]
+		err = e.send(fmt.Sprintf("TURN %d,%d", last.J, last.I))
+	default:
+		err = e.sendBoard(steps)
+	}
+	if err != nil {
+		return protocol.Chess{}, err
+	}
+	e.begun = true
+
+	timeout := time.Duration(0)
+	if turn > 0 {
+		timeout = turn + e.opts.Grace
+	}
+	line, err := e.next(ctx, timeout)
+	if err != nil {
+		if errors.Is(err, ErrTimeout) || ctx.Err() != nil {
+			// 超时或被取消的引擎随时可能输出过期的回复，直接结束进程
+			_ = e.kill()
+		}
+		return protocol.Chess{}, err
+	}
+	c, err := parseMove(line)
+	if err != nil {
+		return protocol.Chess{}, err
+	}
+	e.known = append(append(e.known[:0:0], steps...), c)
+	return c, nil
+}
+
+// sync 在规则或单手时间变化时通过 INFO 告知引擎
+func (e *Engine) sync(rules engine.Rules, turn time.Duration) error {
+	if rules != e.rules {
+		rule := 0
+		if rules.ExactFive {
+			rule = 1
+		}
+		if err := e.send(fmt.Sprintf("INFO rule %d", rule)); err != nil {
+			return err
+		}
+		e.rules = rules
+	}
+	if turn > 0 && turn != e.turn {
+		if err := e.send(fmt.Sprintf("INFO timeout_turn %d", turn.Milliseconds())); err != nil {
+			return err
+		}
+		e.turn = turn
+	}
+	return nil
+}
+
+// sendBoard 发送完整局面，行棋方的棋子标记为 1，对方为 2
+func (e *Engine) sendBoard(steps []protocol.Chess) error {
+	b := &strings.Builder{}
+	b.WriteString("BOARD\n")
+	me := len(steps) % 2
+	for k, c := range steps {
+		owner := 2
+		if k%2 == me {
+			owner = 1
+		}
+		fmt.Fprintf(b, "%d,%d,%d\n", c.J, c.I, owner)
+	}
+	b.WriteString("DONE")
+	return e.send(b.String())
+}
+
+func (e *Engine) Close() error {
+	_ = e.send("END")
+	select {
+	case <-e.exit:
+	case <-time.After(time.Second):
+		_ = e.kill()
+		<-e.exit
+	}
+	return nil
+}
+
+func (e *Engine) kill() error {
+	if e.cmd.Process == nil {
+		return nil
+	}
+	return e.cmd.Process.Kill()
+}
+
+func (e *Engine) send(line string) error {
+	select {
+	case <-e.exit:
+		return e.crashErr()
+	default:
+	}
+	if _, err := io.WriteString(e.stdin, line+"\n"); err != nil {
+		return fmt.Errorf("%w: %v", ErrCrashed, err)
+	}
+	return nil
+}
+
+// read 读取引擎输出，忽略 MESSAGE、DEBUG 等提示信息，进程退出后关闭 exit
+func (e *Engine) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		cmd, _, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "", "MESSAGE", "DEBUG", "SUGGEST":
+			continue
+		}
+		select {
+		case e.lines <- line:
+		default:
+			// 没有人在等待的输出说明协议已经错位，丢弃以免阻塞进程退出
+		}
+	}
+	e.err = e.cmd.Wait()
+	close(e.exit)
+}
+
+func (e *Engine) crashErr() error {
+	if e.err != nil {
+		return fmt.Errorf("%w: %v", ErrCrashed, e.err)
+	}
+	return ErrCrashed
+}
+
+// next 等待引擎的下一行有效输出，timeout 为 0 时只受 ctx 限制
+func (e *Engine) next(ctx context.Context, timeout time.Duration) (string, error) {
+	var timer <-chan time.Time
+	if timeout > 0 {
+		t := time.NewTimer(timeout)
+		defer t.Stop()
+		timer = t.C
+	}
+	select {
+	case line := <-e.lines:
+		cmd, rest, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "ERROR", "UNKNOWN":
+			return "", fmt.Errorf("pbrain: engine error: %v", rest)
+		}
+		return line, nil
+	case <-e.exit:
+		return "", e.crashErr()
+	case <-timer:
+		return "", ErrTimeout
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (e *Engine) expectOK(ctx context.Context, timeout time.Duration) error {
+	line, err := e.next(ctx, timeout)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(line, "OK") {
+		return fmt.Errorf("pbrain: expected OK, got %q", line)
+	}
+	return nil
+}
+
+// parseMove 解析 x,y 形式的着法
+func parseMove(line string) (protocol.Chess, error) {
+	xs, ys, ok := strings.Cut(line, ",")
+	x, errX := strconv.Atoi(strings.TrimSpace(xs))
+	y, errY := strconv.Atoi(strings.TrimSpace(ys))
+	if !ok || errX != nil || errY != nil || x < 0 || y < 0 || x >= int(protocol.BoardSize) || y >= int(protocol.BoardSize) {
+		return protocol.Chess{}, fmt.Errorf("pbrain: invalid move %q", line)
+	}
+	return protocol.Chess{I: int8(y), J: int8(x)}, nil
+}
+
+// aboutField 从 ABOUT 回复中取出 key="value" 的值
+func aboutField(line string, key string) string {
+	i := strings.Index(line, key+"=\"")
+	if i < 0 {
+		return ""
+	}
+	rest := line[i+len(key)+2:]
+	if j := strings.IndexByte(rest, '"'); j >= 0 {
+		return rest[:j]
+	}
+	return ""
+}
+
+func hasPrefix(steps []protocol.Chess, prefix []protocol.Chess) bool {
+	if len(prefix) > len(steps) {
+		return false
+	}
+	for k := range prefix {
+		if steps[k] != prefix[k] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+// Package puzzle 解析、验证题目，并在解题过程中检查玩家的着法、给出防守方的应对。
+//
+// 题目的文本格式，每行一项，# 开头的行为注释，不带冒号的行为着法：
+//
+//	title: 横向活三
+//	to move: black
+//	goal: vcf 2
+//	h8 a1 i8 a3 j8 a5
+package puzzle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/solver"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// GoalVCF 连续冲四取胜
+const GoalVCF = "vcf"
+
+// MaxDepth 题目允许的最大冲四次数
+const MaxDepth = 16
+
+// Puzzle 题目：在 Steps 还原的局面中由 ToMove 一方行棋，达成 Goal
+type Puzzle struct {
+	Title    string
+	Goal     string // 目前只有 vcf：Depth 次冲四以内连续冲四取胜
+	Depth    int    // 允许的冲四次数
+	ToMove   string
+	Steps    []protocol.Chess
+	Solution []protocol.Chess // 求解得到的一个解
+}
+
+// Outcome 玩家一手棋的结果
+type Outcome string
+
+const (
+	Continue Outcome = "continue" // 着法正确，防守方已应对，继续解题
+	Solved   Outcome = "solved"   // 成五，解题成功
+	Failed   Outcome = "failed"   // 着法无法在剩余的冲四次数内取胜
+)
+
+// Parse 解析文本格式的题目并验证，见 Validate
+func Parse(ctx context.Context, text string) (*Puzzle, error) {
+	p := &Puzzle{}
+	var moves []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			moves = append(moves, line)
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "title":
+			p.Title = value
+		case "to move":
+			p.ToMove = strings.ToLower(value)
+		case "goal":
+			goal, n, _ := strings.Cut(value, " ")
+			depth, err := strconv.Atoi(strings.TrimSpace(n))
+			if err != nil {
+				return nil, fmt.Errorf("puzzle: invalid goal %q", value)
+			}
+			p.Goal, p.Depth = strings.ToLower(goal), depth
+		default:
+			return nil, fmt.Errorf("puzzle: unknown field %q", key)
+		}
+	}
+	steps, err := engine.ParseMoves(strings.Join(moves, " "))
+	if err != nil {
+		return nil, fmt.Errorf("puzzle: %v", err)
+	}
+	p.Steps = steps
+	if err = Validate(ctx, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate 检查题目的局面、行棋方和目标，并求解填入 Solution，无解的题目返回错误
+func Validate(ctx context.Context, p *Puzzle) error {
+	if p.Goal != GoalVCF {
+		return fmt.Errorf("puzzle: unsupported goal %q", p.Goal)
+	}
+	if p.Depth < 1 || p.Depth > MaxDepth {
+		return fmt.Errorf("puzzle: depth must be between 1 and %d", MaxDepth)
+	}
+	b, err := engine.NewBoard(p.Steps)
+	if err != nil {
+		return fmt.Errorf("puzzle: %v", err)
+	}
+	if b.Winner() != engine.Empty {
+		return errors.New("puzzle: the position is already won")
+	}
+	if p.ToMove == "" {
+		p.ToMove = b.ToMove().String()
+	}
+	if p.ToMove != b.ToMove().String() {
+		return fmt.Errorf("puzzle: %v is not to move after %d moves", p.ToMove, len(p.Steps))
+	}
+	if len(solver.FivePoints(b, b.ToMove())) > 0 {
+		return errors.New("puzzle: the side to move already has a five")
+	}
+
+	p.Solution = solver.VCF(ctx, b, p.Depth)
+	if p.Solution == nil {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		return fmt.Errorf("puzzle: no VCF within %d fours", p.Depth)
+	}
+	return nil
+}
+
+// Play 检查玩家在已下 moves 之后的着法 c，着法正确且未成五时返回防守方的应对
+func Play(ctx context.Context, p *Puzzle, moves []protocol.Chess, c protocol.Chess) (Outcome, *protocol.Chess, error) {
+	b, err := engine.NewBoard(append(append([]protocol.Chess(nil), p.Steps...), moves...))
+	if err != nil {
+		return "", nil, fmt.Errorf("puzzle: %v", err)
+	}
+	if !engine.InBoard(int(c.I), int(c.J)) || b.At(int(c.I), int(c.J)) != engine.Empty {
+		return "", nil, fmt.Errorf("puzzle: illegal move %v", engine.FormatMove(c))
+	}
+
+	me := b.ToMove()
+	if b.IsFive(c, me) {
+		return Solved, nil, nil
+	}
+	var s solver.Solver
+	if !s.Wins(ctx, b, c, p.Depth-len(moves)/2) {
+		return Failed, nil, nil
+	}
+	// 冲四之后防守方只能堵成五点，活四或双四时任选其一
+	_ = b.Play(c)
+	reply := solver.FivePoints(b, me)[0]
+	return Continue, &reply, nil
+}
@@ -0,0 +1,170 @@
+// Package solver 连续冲四（VCF）求解器，用于复盘中发现漏掉的必胜和验证题目。
+package solver
+
+import (
+	"context"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// DefaultMaxNodes 单次求解默认最多展开的节点数
+const DefaultMaxNodes = 200000
+
+// Solver 一次求解的状态，零值即可使用
+type Solver struct {
+	MaxNodes int64 // 节点上限，零值使用 DefaultMaxNodes
+	nodes    int64
+}
+
+// VCF 用默认节点上限为行棋方求解 depth 次冲四以内的连续冲四胜，见 Solver.VCF
+func VCF(ctx context.Context, b *engine.Board, depth int) []protocol.Chess {
+	return (&Solver{}).VCF(ctx, b, depth)
+}
+
+// VCF 为行棋方求解 depth 次冲四以内的连续冲四胜，返回从行棋方开始、双方交替、以成五结束的着法序列，
+// 没有找到返回 nil。求解过程中会修改 b，返回前复原。
+func (s *Solver) VCF(ctx context.Context, b *engine.Board, depth int) []protocol.Chess {
+	s.reset()
+	if b.Winner() != engine.Empty {
+		return nil
+	}
+	return s.vcf(ctx, b, depth)
+}
+
+// Wins 判断行棋方下在 c 之后是否仍然保持 depth 次冲四以内的连续冲四胜
+func (s *Solver) Wins(ctx context.Context, b *engine.Board, c protocol.Chess, depth int) bool {
+	s.reset()
+	me := b.ToMove()
+	if b.IsFive(c, me) {
+		return true
+	}
+	if depth == 0 {
+		return false
+	}
+	// 对方有成五点时，只有恰好堵在该点上的冲四才算数
+	if threats := FivePoints(b, me.Opponent()); len(threats) > 1 || len(threats) == 1 && threats[0] != c {
+		return false
+	}
+	return s.four(ctx, b, c, depth) != nil
+}
+
+func (s *Solver) reset() {
+	if s.MaxNodes == 0 {
+		s.MaxNodes = DefaultMaxNodes
+	}
+	s.nodes = 0
+}
+
+func (s *Solver) vcf(ctx context.Context, b *engine.Board, depth int) []protocol.Chess {
+	me := b.ToMove()
+	if fives := FivePoints(b, me); len(fives) > 0 {
+		return []protocol.Chess{fives[0]}
+	}
+	if depth == 0 {
+		return nil
+	}
+	s.nodes++
+	if s.nodes > s.MaxNodes || ctx.Err() != nil {
+		return nil
+	}
+
+	// 对方已有成五点时只能去堵，堵点本身也必须是冲四
+	moves := FourPoints(b, me)
+	if threats := FivePoints(b, me.Opponent()); len(threats) > 1 {
+		return nil
+	} else if len(threats) == 1 {
+		moves = nil
+		for _, c := range FourPoints(b, me) {
+			if c == threats[0] {
+				moves = append(moves, c)
+			}
+		}
+	}
+
+	for _, c := range moves {
+		if line := s.four(ctx, b, c, depth); line != nil {
+			return line
+		}
+	}
+	return nil
+}
+
+// four 行棋方在 c 冲四，对方被迫防守后继续求解，调用方保证对方没有成五点
+func (s *Solver) four(ctx context.Context, b *engine.Board, c protocol.Chess, depth int) []protocol.Chess {
+	me := b.ToMove()
+	_ = b.Play(c)
+	defer b.Undo()
+
+	fives := fivesThrough(b, c, me)
+	switch {
+	case len(fives) >= 2:
+		// 活四或双四，对方只能堵住一个
+		return []protocol.Chess{c, fives[0], fives[1]}
+	case len(fives) == 0:
+		return nil
+	}
+	block := fives[0]
+	if b.IsFive(block, me.Opponent()) {
+		return nil
+	}
+	_ = b.Play(block)
+	rest := s.vcf(ctx, b, depth-1)
+	b.Undo()
+	if rest == nil {
+		return nil
+	}
+	return append([]protocol.Chess{c, block}, rest...)
+}
+
+// FivePoints 返回 s 落下即成五的所有空点
+func FivePoints(b *engine.Board, st engine.Stone) []protocol.Chess {
+	var points []protocol.Chess
+	for _, c := range b.Candidates(1) {
+		if b.IsFive(c, st) {
+			points = append(points, c)
+		}
+	}
+	return points
+}
+
+// FourPoints 返回 s 落下后形成冲四或活四的所有空点
+func FourPoints(b *engine.Board, st engine.Stone) []protocol.Chess {
+	var points []protocol.Chess
+	for _, c := range b.Candidates(2) {
+		for _, shape := range b.Shapes(c, st) {
+			if shape == engine.ShapeFour || shape == engine.ShapeOpenFour {
+				points = append(points, c)
+				break
+			}
+		}
+	}
+	return points
+}
+
+// fivesThrough 返回经过 c 的四条线上 s 落下即成五的空点，c 为 s 刚落下的棋子
+func fivesThrough(b *engine.Board, c protocol.Chess, st engine.Stone) []protocol.Chess {
+	var points []protocol.Chess
+	for _, d := range [4][2]int{{0, 1}, {1, 0}, {1, 1}, {1, -1}} {
+		for k := -4; k <= 4; k++ {
+			i, j := int(c.I)+k*d[0], int(c.J)+k*d[1]
+			if k == 0 || !engine.InBoard(i, j) || b.At(i, j) != engine.Empty {
+				continue
+			}
+			p := protocol.Chess{I: int8(i), J: int8(j)}
+			if b.IsFive(p, st) && !contains(points, p) {
+				points = append(points, p)
+			}
+		}
+	}
+	return points
+}
+
+func contains(points []protocol.Chess, c protocol.Chess) bool {
+	for _, p := range points {
+		if p == c {
+			return true
+		}
+	}
+	return false
+}
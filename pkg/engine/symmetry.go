@@ -0,0 +1,83 @@
+package engine
+
+import (
+	"encoding/hex"
+	"sort"
+
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Symmetry 棋盘的 8 种对称变换：0-3 为顺时针旋转 0、90、180、270 度，4-7 为左右翻转后再旋转
+type Symmetry int
+
+// Symmetries 全部 8 种对称变换
+var Symmetries = [8]Symmetry{0, 1, 2, 3, 4, 5, 6, 7}
+
+// Apply 对坐标做对称变换
+func (s Symmetry) Apply(c protocol.Chess) protocol.Chess {
+	n := int8(Size - 1)
+	i, j := c.I, c.J
+	if s >= 4 {
+		j = n - j
+	}
+	for r := 0; r < int(s%4); r++ {
+		i, j = j, n-i
+	}
+	return protocol.Chess{I: i, J: j}
+}
+
+// Inverse 返回逆变换，翻转类变换的逆为自身
+func (s Symmetry) Inverse() Symmetry {
+	if s >= 4 {
+		return s
+	}
+	return (4 - s) % 4
+}
+
+// Canonical 返回局面在 8 种对称变换下的规范键（与着法顺序无关，只取决于黑白棋子的位置），
+// 以及把局面变换为规范形式的所有对称变换，局面自身对称时会有多个
+func Canonical(steps []protocol.Chess) (string, []Symmetry) {
+	var best string
+	var syms []Symmetry
+	for _, s := range Symmetries {
+		key := positionKey(steps, s)
+		switch {
+		case syms == nil || key < best:
+			best, syms = key, []Symmetry{s}
+		case key == best:
+			syms = append(syms, s)
+		}
+	}
+	return best, syms
+}
+
+// CanonicalMove 返回 c 在 syms 下变换结果中最小的一个，使局面的对称使等价的着法归为同一个
+func CanonicalMove(c protocol.Chess, syms []Symmetry) protocol.Chess {
+	best := syms[0].Apply(c)
+	for _, s := range syms[1:] {
+		if t := s.Apply(c); index(t) < index(best) {
+			best = t
+		}
+	}
+	return best
+}
+
+func index(c protocol.Chess) int {
+	return int(c.I)*Size + int(c.J)
+}
+
+// positionKey 变换后黑白棋子分别排序编码，黑子在前，以 ff 分隔
+func positionKey(steps []protocol.Chess, s Symmetry) string {
+	var black, white []byte
+	for k, c := range steps {
+		t := byte(index(s.Apply(c)))
+		if k%2 == 0 {
+			black = append(black, t)
+		} else {
+			white = append(white, t)
+		}
+	}
+	sort.Slice(black, func(x, y int) bool { return black[x] < black[y] })
+	sort.Slice(white, func(x, y int) bool { return white[x] < white[y] })
+	return hex.EncodeToString(append(append(black, 0xff), white...))
+}
@@ -41,7 +41,10 @@ func init() {
 	logger.SetFormatter(&CustomFormatter{})
 	logger.SetReportCaller(true)
 	logger.SetLevel(logrus.DebugLevel)
-	logger.AddHook(newRotateHook(viper.GetString("log.path"), time.Duration(viper.GetInt("log.maxAge"))*24*time.Hour, 24*time.Hour))
+	// 没有读取配置时（如测试）只输出到标准输出
+	if path := viper.GetString("log.path"); path != "" {
+		logger.AddHook(newRotateHook(path, time.Duration(viper.GetInt("log.maxAge"))*24*time.Hour, 24*time.Hour))
+	}
 
 }
 func newRotateHook(baseLogPath string, maxAge time.Duration, rotationTime time.Duration) *lfshook.LfsHook {
@@ -0,0 +1,76 @@
+package protocol
+
+// Candidate 提示中的一个候选着法，Score 为行棋方视角的评估分
+type Candidate struct {
+	Move  Chess   `json:"move"`
+	Score int     `json:"score"`
+	PV    []Chess `json:"pv,omitempty"`
+}
+
+// HintDTO 提示结果，Candidates 按评估分从高到低排列
+type HintDTO struct {
+	RId        string      `json:"rid,omitempty"`
+	Moves      int         `json:"moves"` // 分析时局面的手数
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Review 一局存档的复盘记录，Moves 与对局的 Steps 一一对应
+type Review struct {
+	GameId    string       `json:"gid"`
+	Engine    string       `json:"engine"`
+	Moves     []MoveReview `json:"moves"`
+	CreatedAt string       `json:"created_at"`
+}
+
+// MoveReview 一手棋的复盘注释，评估分均为黑方视角
+type MoveReview struct {
+	Ply       int     `json:"ply"` // 从 1 开始的手数
+	Move      Chess   `json:"move"`
+	Eval      int     `json:"eval"`      // 这手棋之后的评估
+	Best      Chess   `json:"best"`      // 引擎推荐的着法
+	BestEval  int     `json:"best_eval"` // 下在推荐着法时的评估
+	Loss      int     `json:"loss"`      // 相对推荐着法损失的分数，行棋方视角，不小于 0
+	Blunder   bool    `json:"blunder"`
+	MissedWin bool    `json:"missed_win"`
+	VCF       []Chess `json:"vcf,omitempty"` // 漏掉的连续冲四胜，或这手棋之后对方获得的连续冲四胜
+	PV        []Chess `json:"pv,omitempty"`  // 推荐着法的主要变化
+}
+
+// PuzzleDTO 题目，不包含答案，Moves 为本次解题双方已下的着法
+type PuzzleDTO struct {
+	Id     string  `json:"id"`
+	Title  string  `json:"title"`
+	Goal   string  `json:"goal"`
+	Depth  int     `json:"depth"`
+	ToMove string  `json:"to_move"`
+	Steps  []Chess `json:"steps"`
+	Moves  []Chess `json:"moves"`
+	Daily  bool    `json:"daily"`
+}
+
+// PuzzleMoveDTO 解题落子的结果，Result 为 continue、solved 或 failed，失败时附带一个正确解
+type PuzzleMoveDTO struct {
+	Id        string  `json:"id"`
+	Move      Chess   `json:"move"`
+	Reply     *Chess  `json:"reply,omitempty"`
+	Result    string  `json:"result"`
+	Remaining int     `json:"remaining"` // 剩余的冲四次数
+	Solution  []Chess `json:"solution,omitempty"`
+}
+
+// ExplorerDTO 开局浏览器中一个局面的统计，Moves 按对局数倒序
+type ExplorerDTO struct {
+	Steps []Chess        `json:"steps"`
+	Games int64          `json:"games"`
+	Moves []ExplorerMove `json:"moves"`
+}
+
+// ExplorerMove 在该局面下走过的一手棋，坐标已换回查询局面的方向
+type ExplorerMove struct {
+	Move      Chess   `json:"move"`
+	Games     int64   `json:"games"`
+	BlackWins int64   `json:"black_wins"`
+	WhiteWins int64   `json:"white_wins"`
+	Draws     int64   `json:"draws"`
+	WinRate   float64 `json:"win_rate"` // 走这手棋一方的胜率，和棋计半局
+}
@@ -0,0 +1,79 @@
+package protocol
+
+// 大厅中房间的状态
+const (
+	RoomOpen    = "open"    // 挑战者座位空着
+	RoomFull    = "full"    // 双方已入座，对局未开始
+	RoomPlaying = "playing" // 对局进行中
+)
+
+// 大厅房间的排序方式
+const (
+	SortCreated    = "created"
+	SortRating     = "rating"
+	SortSpectators = "spectators"
+)
+
+// 增量事件的操作
+const (
+	DeltaAdd    = "add"
+	DeltaUpdate = "update"
+	DeltaRemove = "remove"
+)
+
+// Seat 房间中一个座位上的玩家
+type Seat struct {
+	Id     string `json:"id"`
+	Name   string `json:"name"`
+	Rating int    `json:"rating"`
+	Color  int8   `json:"color"`
+	Ready  bool   `json:"ready"`
+}
+
+// RoomSummary 大厅列表中的房间摘要，不包含棋谱、聊天和旁观者名单
+type RoomSummary struct {
+	Id         string       `json:"id"`
+	Host       Seat         `json:"host"`
+	Challenger *Seat        `json:"challenger"` // 座位空着时为 null
+	Spectators int          `json:"spectators"`
+	Settings   RoomSettings `json:"settings"`
+	State      string       `json:"state"`
+	CreateTime string       `json:"create_time"`
+}
+
+// LobbyQuery 大厅查询条件，指针字段为 nil 时不按该条件过滤
+type LobbyQuery struct {
+	Open      *bool  `json:"open,omitempty"`       // 是否有空的挑战者座位
+	Playing   *bool  `json:"playing,omitempty"`    // 是否正在对局
+	Rated     *bool  `json:"rated,omitempty"`      // 房间规则
+	Hints     *bool  `json:"hints,omitempty"`      // 房间规则
+	Analysis  *bool  `json:"analysis,omitempty"`   // 房间规则
+	MinRating int    `json:"min_rating,omitempty"` // 房主积分下限，0 不限
+	MaxRating int    `json:"max_rating,omitempty"` // 房主积分上限，0 不限
+	Sort      string `json:"sort,omitempty"`       // created（默认）、rating 或 spectators
+	Asc       bool   `json:"asc,omitempty"`        // 默认倒序：最新、积分最高、旁观最多的在前
+	Offset    int    `json:"offset,omitempty"`
+	Limit     int    `json:"limit,omitempty"` // 0 使用默认分页大小
+}
+
+// LobbyDTO 一页大厅房间，Total 为过滤后的房间总数
+type LobbyDTO struct {
+	Total  int           `json:"total"`
+	Offset int           `json:"offset"`
+	Limit  int           `json:"limit"`
+	Rooms  []RoomSummary `json:"rooms"`
+}
+
+// LobbyDeltaDTO 大厅中一个房间的变化，remove 时 Room 为 null
+type LobbyDeltaDTO struct {
+	Op   string       `json:"op"`
+	RId  string       `json:"rid"`
+	Room *RoomSummary `json:"room"`
+}
+
+// PlayerDeltaDTO 玩家列表中一个玩家的变化，remove 时 Player 为 null
+type PlayerDeltaDTO struct {
+	Op     string  `json:"op"`
+	PId    string  `json:"pid"`
+	Player *Player `json:"player"`
+}
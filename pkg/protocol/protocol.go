@@ -0,0 +1,93 @@
+// Package protocol 定义 gomoku websocket 协议的版本、消息码、主题和消息数据结构。
+// 服务端的 internal/constants 引用这里的消息码和主题，第三方客户端只需要依赖本包。
+package protocol
+
+// 服务端支持的协议版本范围，客户端在连接时通过 ?version= 声明自己的版本
+const (
+	MinProtocolVersion = 1
+	ProtocolVersion    = 3
+)
+
+// LobbyVersion 起 GetRooms 返回分页的房间摘要，之前的版本返回完整的房间列表
+const LobbyVersion = 2
+
+// TopicVersion 起连接只接收已订阅主题的推送，大厅和玩家列表以增量事件推送；
+// 之前的版本视为订阅了大厅、大厅聊天和玩家列表，并继续接收完整列表
+const TopicVersion = 3
+
+// 消息码
+const (
+	Fail = iota
+	Success
+	HallChat
+	GetHallDialog
+	GetRooms
+	CreateRoom
+	EnterRoom
+	LeaveRoom
+	DelRoom
+	RoomChat
+	GetPlayer
+	GetPlayers
+	PlayerRename
+	SetPlayerStatus
+	SetReady
+	MakeStep
+	RetractStep // 悔棋
+	Surrender   // 投降
+	AskDraw     // 求和
+	GameOver
+	Hello        // 协议版本协商
+	Hint         // 请求提示
+	GetReview    // 获取对局复盘
+	TreeMove     // 分析房间落子，当前节点下没有该着法时新建分支
+	TreeGoto     // 分析房间跳转到变化树的节点
+	TreeDelete   // 分析房间删除节点及其所有后续变化
+	TreeLoad     // 分析房间载入存档对局
+	StartPuzzle  // 开始解题
+	PuzzleMove   // 解题落子
+	ImportPuzzle // 导入题目
+	Explore      // 开局浏览器
+	Subscribe    // 订阅主题
+	Unsubscribe  // 取消订阅主题
+	LobbyDelta   // 大厅房间增量更新
+	PlayerDelta  // 玩家列表增量更新
+)
+
+// BoardSize 棋盘边长
+const BoardSize int8 = 15
+
+// 棋子颜色
+const (
+	BLACK int8 = iota
+	WHITE
+)
+
+// Message websocket 消息信封
+// Id 为客户端自定义的请求 id，服务端在对该请求的直接回复、确认和错误中原样返回
+type Message struct {
+	Id   string      `json:"id,omitempty"`
+	Code int         `json:"code"`
+	Data interface{} `json:"data"`
+}
+
+// AckDTO 请求处理成功的确认，Code 为被确认请求的消息码
+type AckDTO struct {
+	Code int `json:"code"`
+}
+
+// ErrDTO 服务端返回的错误，Code 为 ROOM_NOT_FOUND 这样的错误码
+type ErrDTO struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Params  map[string]interface{} `json:"params,omitempty"`
+}
+
+// HelloDTO 连接建立后下发的协议版本协商结果
+type HelloDTO struct {
+	Version    int `json:"version"`
+	MinVersion int `json:"min_version"`
+	MaxVersion int `json:"max_version"`
+	// ResumeToken 匿名玩家的重连令牌，重连时与玩家 id 一起通过 ?pid=&resume= 出示；登录的连接没有
+	ResumeToken string `json:"resume_token,omitempty"`
+}
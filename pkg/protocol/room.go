@@ -0,0 +1,80 @@
+package protocol
+
+// Chess 棋盘坐标，I、J 从 0 开始
+type Chess struct {
+	I int8 `json:"i"`
+	J int8 `json:"j"`
+}
+
+type DialogMsg struct {
+	Time    string `json:"time"`
+	From    string `json:"from"`
+	Content string `json:"content"`
+}
+
+type Player struct {
+	Id            string `json:"id"`
+	Name          string `json:"name"`
+	Status        string `json:"status"`
+	LoginTime     string `json:"login_time"`
+	MatchesPlayed int    `json:"matches_played"`
+	Rating        int    `json:"rating"`
+}
+
+// PlayerDetails 在某个房间里的玩家详细信息
+type PlayerDetails struct {
+	Player
+	Role  string `json:"role"`
+	Color int8   `json:"color"`
+	Ready bool   `json:"ready"`
+}
+
+// RoomSettings 房间设置，由房主在创建房间时指定
+type RoomSettings struct {
+	Rated    bool `json:"rated"`    // 计分对局，对局进行中禁止提示
+	Hints    bool `json:"hints"`    // 是否允许在对局中请求提示
+	Analysis bool `json:"analysis"` // 分析房间，不进行对局，玩家在变化树上自由摆棋
+}
+
+type Room struct {
+	Id         string        `json:"id"`
+	Settings   RoomSettings  `json:"settings"`
+	Dialog     []DialogMsg   `json:"dialog"`
+	Steps      []Chess       `json:"steps"` // 分析房间中为变化树当前节点的着法序列
+	Tree       *MoveTree     `json:"tree,omitempty"`
+	CreateTime string        `json:"create_time"`
+	Started    bool          `json:"started"`
+	StartTime  string        `json:"start_time"`
+	Host       PlayerDetails `json:"host"`
+	Challenger PlayerDetails `json:"challenger"`
+	Spectators []Player      `json:"spectators"`
+}
+
+// MoveTree 分析房间的变化树，节点 0 为根（空棋盘），Current 为当前所在节点
+type MoveTree struct {
+	Nodes   map[int]*TreeNode `json:"nodes"`
+	Current int               `json:"current"`
+	NextId  int               `json:"next_id"`
+}
+
+// TreeNode 变化树的一个节点，Children 中第一个为主变化
+type TreeNode struct {
+	Id       int    `json:"id"`
+	Parent   int    `json:"parent"` // 根节点为 -1
+	Move     *Chess `json:"move,omitempty"`
+	Children []int  `json:"children"`
+}
+
+// TreeDTO 分析房间的变化树，Steps 为当前节点的着法序列
+type TreeDTO struct {
+	RId   string    `json:"rid"`
+	Tree  *MoveTree `json:"tree"`
+	Steps []Chess   `json:"steps"`
+}
+
+type GameOverDTO struct {
+	RId    string        `json:"rid"`
+	Winner PlayerDetails `json:"winner"`
+	Loser  PlayerDetails `json:"loser"`
+	Cause  string        `json:"cause"`
+}
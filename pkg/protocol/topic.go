@@ -0,0 +1,25 @@
+package protocol
+
+import "strings"
+
+// 可订阅的主题，房间主题为 room:<rid>
+const (
+	TopicLobby   = "lobby"   // 大厅房间增量更新
+	TopicHall    = "hall"    // 大厅聊天
+	TopicPlayers = "players" // 玩家列表增量更新
+
+	roomTopicPrefix = "room:"
+)
+
+// RoomTopic 返回房间 rid 的主题
+func RoomTopic(rid string) string {
+	return roomTopicPrefix + rid
+}
+
+// ParseRoomTopic 从房间主题中取出房间 id，不是房间主题时返回 false
+func ParseRoomTopic(topic string) (string, bool) {
+	if !strings.HasPrefix(topic, roomTopicPrefix) || len(topic) == len(roomTopicPrefix) {
+		return "", false
+	}
+	return topic[len(roomTopicPrefix):], true
+}
@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"fmt"
 	"github.com/redis/go-redis/v9"
 	"github.com/spf13/viper"
 	"github.com/toujourser/gomoku/pkg/logger"
@@ -51,8 +52,12 @@ func init() {
 			return nil
 		},
 	})
-	if RedisClient.Ping(context.Background()).Err() != nil {
-		panic(RedisClient.Ping(context.Background()).Err())
-	}
+}
 
+// Ping 检查 Redis 是否可用，客户端在第一次执行命令时才会建立连接，使用 Redis 的组件启动前需要先检查
+func Ping(ctx context.Context) error {
+	if err := RedisClient.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("error ping redis %v: %v", RedisClient.Options().Addr, err)
+	}
+	return nil
 }
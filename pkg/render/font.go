@@ -0,0 +1,70 @@
+package render
+
+import (
+	"image"
+	"image/draw"
+)
+
+// 3x5 点阵字体，只包含棋盘坐标和手数需要的数字与 a-o
+const (
+	glyphW = 3
+	glyphH = 5
+)
+
+var glyphs = map[rune][glyphH]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", "###", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'a': {".#.", "#.#", "###", "#.#", "#.#"},
+	'b': {"##.", "#.#", "##.", "#.#", "##."},
+	'c': {".##", "#..", "#..", "#..", ".##"},
+	'd': {"##.", "#.#", "#.#", "#.#", "##."},
+	'e': {"###", "#..", "##.", "#..", "###"},
+	'f': {"###", "#..", "##.", "#..", "#.."},
+	'g': {".##", "#..", "#.#", "#.#", ".##"},
+	'h': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'i': {"###", ".#.", ".#.", ".#.", "###"},
+	'j': {"..#", "..#", "..#", "#.#", ".#."},
+	'k': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'l': {"#..", "#..", "#..", "#..", "###"},
+	'm': {"#.#", "###", "###", "#.#", "#.#"},
+	'n': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'o': {".#.", "#.#", "#.#", "#.#", ".#."},
+}
+
+// textSize 返回 scale 倍放大后文本的宽高，字符间隔一个点
+func textSize(s string, scale int) (int, int) {
+	if s == "" {
+		return 0, 0
+	}
+	return (len(s)*(glyphW+1) - 1) * scale, glyphH * scale
+}
+
+// drawText 以 (cx, cy) 为中心绘制文本
+func drawText(img draw.Image, s string, cx int, cy int, scale int, c image.Image) {
+	w, h := textSize(s, scale)
+	x0, y0 := cx-w/2, cy-h/2
+	for k, r := range s {
+		g, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		for y := 0; y < glyphH; y++ {
+			for x := 0; x < glyphW; x++ {
+				if g[y][x] != '#' {
+					continue
+				}
+				px := x0 + (k*(glyphW+1)+x)*scale
+				py := y0 + y*scale
+				draw.Draw(img, image.Rect(px, py, px+scale, py+scale), c, image.Point{}, draw.Src)
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package render 用标准库的 image 包把棋盘绘制为 PNG 图片或 GIF 动画。
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"strconv"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// Options 绘制参数
+type Options struct {
+	Cell    int  // 格子边长（像素），默认 32
+	Numbers bool // 在棋子上标出手数
+	Delay   int  // GIF 每帧的间隔（百分之一秒），默认 80
+}
+
+var (
+	wood      = color.RGBA{R: 0xdc, G: 0xb3, B: 0x5c, A: 0xff}
+	ink       = color.RGBA{R: 0x33, G: 0x22, B: 0x11, A: 0xff}
+	highlight = color.RGBA{R: 0xe0, G: 0x20, B: 0x20, A: 0xff}
+	palette   = color.Palette{wood, ink, color.Black, color.White, highlight}
+)
+
+// Draw 绘制 steps 之后的棋盘，坐标按 h8 记法：字母为列，数字为行，第 1 行在最下方
+func Draw(steps []protocol.Chess, opts Options) *image.Paletted {
+	if opts.Cell <= 0 {
+		opts.Cell = 32
+	}
+	cell := opts.Cell
+	m := margin(cell)
+	size := 2*m + (engine.Size-1)*cell
+	img := image.NewPaletted(image.Rect(0, 0, size, size), palette)
+	draw.Draw(img, img.Bounds(), image.NewUniform(wood), image.Point{}, draw.Src)
+
+	scale := cell / 16
+	if scale < 1 {
+		scale = 1
+	}
+	inkSrc := image.NewUniform(ink)
+	lo, hi := m, m+(engine.Size-1)*cell
+	for k := 0; k < engine.Size; k++ {
+		p := m + k*cell
+		fill(img, image.Rect(lo, p, hi+1, p+1), ink)
+		fill(img, image.Rect(p, lo, p+1, hi+1), ink)
+		drawText(img, string(rune('a'+k)), p, hi+cell*3/4, scale, inkSrc)
+		drawText(img, strconv.Itoa(engine.Size-k), lo-cell*3/4, p, scale, inkSrc)
+	}
+	// 星位
+	for _, i := range []int{3, 7, 11} {
+		for _, j := range []int{3, 7, 11} {
+			x, y := point(i, j, cell)
+			disc(img, x, y, cell/10+1, ink)
+		}
+	}
+
+	for k, c := range steps {
+		x, y := point(int(c.I), int(c.J), cell)
+		r := cell/2 - 1
+		stone, text := color.Color(color.Black), color.Color(color.White)
+		if k%2 == 1 {
+			disc(img, x, y, r, ink)
+			r--
+			stone, text = color.White, color.Black
+		}
+		disc(img, x, y, r, stone)
+
+		last := k == len(steps)-1
+		if last {
+			text = highlight
+		}
+		if opts.Numbers {
+			drawText(img, strconv.Itoa(k+1), x, y, scale, image.NewUniform(text))
+		} else if last {
+			disc(img, x, y, cell/8+1, highlight)
+		}
+	}
+	return img
+}
+
+// PNG 将 steps 之后的棋盘编码为 PNG
+func PNG(w io.Writer, steps []protocol.Chess, opts Options) error {
+	return png.Encode(w, Draw(steps, opts))
+}
+
+// GIF 将整局对局编码为 GIF 动画，第一帧为空棋盘，最后一帧停留更久
+func GIF(w io.Writer, steps []protocol.Chess, opts Options) error {
+	if opts.Delay <= 0 {
+		opts.Delay = 80
+	}
+	anim := &gif.GIF{}
+	for k := 0; k <= len(steps); k++ {
+		anim.Image = append(anim.Image, Draw(steps[:k], opts))
+		anim.Delay = append(anim.Delay, opts.Delay)
+	}
+	anim.Delay[len(anim.Delay)-1] = opts.Delay * 5
+	return gif.EncodeAll(w, anim)
+}
+
+// margin 棋盘四周留给坐标的边距
+func margin(cell int) int {
+	return cell * 5 / 4
+}
+
+// point 返回 (i, j) 的像素坐标，第 15 行在最上方
+func point(i int, j int, cell int) (int, int) {
+	m := margin(cell)
+	return m + j*cell, m + (engine.Size-1-i)*cell
+}
+
+func fill(img draw.Image, r image.Rectangle, c color.Color) {
+	draw.Draw(img, r, image.NewUniform(c), image.Point{}, draw.Src)
+}
+
+// disc 以 (cx, cy) 为圆心画实心圆
+func disc(img *image.Paletted, cx int, cy int, r int, c color.Color) {
+	idx := uint8(palette.Index(c))
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			if x*x+y*y <= r*r && image.Pt(cx+x, cy+y).In(img.Rect) {
+				img.SetColorIndex(cx+x, cy+y, idx)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store/memory"
+)
+
+// 变化树的落子、跳转和删除，旁观者同样可以修改
+func TestMoveTree(t *testing.T) {
+	ctx := context.Background()
+	service.UseStores(memory.NewStores())
+
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	guest, _ := service.NewPlayerConnect(ctx, "guest")
+	r, err := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{Analysis: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.TreeMove(ctx, guest.Id, r.Id, entity.Chess{I: 7, J: 7}); !errcode.Is(err, errcode.NotInRoom) {
+		t.Fatalf("outsider edited the tree: %v", err)
+	}
+	if _, err = service.EnterRoom(ctx, guest.Id, r.Id, "spectator"); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b, c := entity.Chess{I: 7, J: 7}, entity.Chess{I: 7, J: 8}, entity.Chess{I: 8, J: 8}
+	for _, m := range []entity.Chess{a, b} {
+		if r, err = service.TreeMove(ctx, guest.Id, r.Id, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := r.Tree.Current
+
+	// 回到第一手后再下一次相同的着法，进入已有的分支
+	if r, err = service.TreeGoto(ctx, host.Id, r.Id, r.Tree.Nodes[first].Parent); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Steps, []entity.Chess{a}) {
+		t.Fatalf("steps after goto: %+v", r.Steps)
+	}
+	if r, err = service.TreeMove(ctx, host.Id, r.Id, b); err != nil {
+		t.Fatal(err)
+	}
+	if r.Tree.Current != first || len(r.Tree.Nodes) != 3 {
+		t.Fatalf("existing move not reused: current %v, %d nodes", r.Tree.Current, len(r.Tree.Nodes))
+	}
+	if _, err = service.TreeMove(ctx, host.Id, r.Id, a); !errcode.Is(err, errcode.PositionOccupied) {
+		t.Fatalf("occupied point: %v", err)
+	}
+
+	// 在第一手下新建变化，跳转后 Steps 为根到该节点的路径
+	_, _ = service.TreeGoto(ctx, host.Id, r.Id, r.Tree.Nodes[first].Parent)
+	if r, err = service.TreeMove(ctx, host.Id, r.Id, c); err != nil {
+		t.Fatal(err)
+	}
+	branch := r.Tree.Current
+	if r, err = service.TreeGoto(ctx, host.Id, r.Id, first); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Steps, []entity.Chess{a, b}) {
+		t.Fatalf("steps after goto: %+v", r.Steps)
+	}
+	if _, err = service.TreeGoto(ctx, host.Id, r.Id, 99); !errcode.Is(err, errcode.NodeNotFound) {
+		t.Fatalf("missing node: %v", err)
+	}
+
+	// 删除当前节点时回到父节点，其他分支保留
+	parent := r.Tree.Nodes[first].Parent
+	if r, err = service.TreeDelete(ctx, guest.Id, r.Id, first); err != nil {
+		t.Fatal(err)
+	}
+	if r.Tree.Current != parent || !reflect.DeepEqual(r.Steps, []entity.Chess{a}) {
+		t.Fatalf("current %v steps %+v after deleting the current node", r.Tree.Current, r.Steps)
+	}
+	if _, ok := r.Tree.Nodes[first]; ok {
+		t.Fatal("deleted node kept")
+	}
+	if !reflect.DeepEqual(r.Tree.Nodes[parent].Children, []int{branch}) {
+		t.Fatalf("children %+v", r.Tree.Nodes[parent].Children)
+	}
+	if _, err = service.TreeDelete(ctx, host.Id, r.Id, 0); !errcode.Is(err, errcode.NodeNotFound) {
+		t.Fatalf("deleting the root: %v", err)
+	}
+}
+
+// 载入存档替换变化树，着法不合法的存档被拒绝
+func TestTreeLoad(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStores()
+	service.UseStores(s)
+
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	r, _ := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{Analysis: true})
+	steps := []entity.Chess{{I: 7, J: 7}, {I: 7, J: 8}, {I: 8, J: 8}}
+	_ = s.Games.Add(ctx, &entity.Game{Id: "g1", Steps: steps, EndTime: "2024-01-01 00:00:00"})
+	_ = s.Games.Add(ctx, &entity.Game{Id: "bad", Steps: []entity.Chess{{I: 7, J: 7}, {I: 7, J: 7}}, EndTime: "2024-01-01 00:00:00"})
+
+	r, err := service.TreeLoad(ctx, host.Id, r.Id, "g1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(r.Steps, steps) || len(r.Tree.Nodes) != len(steps)+1 {
+		t.Fatalf("loaded tree: steps %+v, %d nodes", r.Steps, len(r.Tree.Nodes))
+	}
+
+	if _, err = service.TreeLoad(ctx, host.Id, r.Id, "bad"); !errcode.Is(err, errcode.InvalidParam) {
+		t.Fatalf("illegal game loaded: %v", err)
+	}
+	if r, _ = service.GetRoom(ctx, r.Id); !reflect.DeepEqual(r.Steps, steps) {
+		t.Fatalf("tree replaced by an illegal game: %+v", r.Steps)
+	}
+}
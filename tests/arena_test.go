@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/pkg/arena"
+	"github.com/toujourser/gomoku/pkg/engine"
+)
+
+// rowEngine 总是在第 row 行最左边的空点落子，执黑的一方先连成五子
+type rowEngine struct {
+	name string
+	row  int8
+}
+
+func (e *rowEngine) Name() string { return e.name }
+
+func (e *rowEngine) Search(_ context.Context, pos engine.Position, _ engine.Limits) (*engine.Result, error) {
+	taken := make(map[entity.Chess]bool, len(pos.Steps))
+	for _, c := range pos.Steps {
+		taken[c] = true
+	}
+	for j := int8(0); j < constants.BoardSize; j++ {
+		if c := (entity.Chess{I: e.row, J: j}); !taken[c] {
+			return &engine.Result{Move: c}, nil
+		}
+	}
+	return nil, errors.New("row is full")
+}
+
+func (e *rowEngine) Close() error { return nil }
+
+// crashEngine 思考 after 之后出错
+type crashEngine struct {
+	after time.Duration
+}
+
+func (crashEngine) Name() string { return "crash" }
+
+func (e crashEngine) Search(context.Context, engine.Position, engine.Limits) (*engine.Result, error) {
+	time.Sleep(e.after)
+	return nil, errors.New("crashed")
+}
+
+func (crashEngine) Close() error { return nil }
+
+func factory(e engine.Engine) engine.Factory {
+	return func(context.Context) (engine.Engine, error) { return e, nil }
+}
+
+// round 保留一位小数，无穷大保持不变
+func round(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+func TestArenaStats(t *testing.T) {
+	inf := math.Inf(1)
+	cases := []struct {
+		stats          arena.Stats
+		score          float64
+		elo, low, high float64
+	}{
+		{arena.Stats{}, 0.5, 0, -inf, inf},
+		{arena.Stats{Wins: 5, Losses: 5}, 0.5, 0, -251.8, 251.8},
+		{arena.Stats{Wins: 3, Draws: 4, Losses: 3}, 0.5, 0, -181.7, 181.7},
+		{arena.Stats{Wins: 6, Draws: 2, Losses: 2}, 0.7, 147.2, -33.4, 504.0},
+		{arena.Stats{Wins: 1, Losses: 3}, 0.25, -190.8, -inf, 126.5},
+		{arena.Stats{Wins: 10}, 1, inf, inf, inf},
+		{arena.Stats{Draws: 2}, 0.5, 0, 0, 0},
+	}
+	for _, tc := range cases {
+		s := tc.stats
+		if got := s.Score(); got != tc.score {
+			t.Errorf("%+v: score %v, want %v", s, got, tc.score)
+		}
+		diff, low, high := s.Elo()
+		if round(diff) != tc.elo || round(low) != tc.low || round(high) != tc.high {
+			t.Errorf("%+v: elo %.1f [%.1f, %.1f], want %.1f [%.1f, %.1f]", s, diff, low, high, tc.elo, tc.low, tc.high)
+		}
+	}
+}
+
+// 胜负按 A 的执子颜色计入统计
+func TestArenaRecord(t *testing.T) {
+	cases := []struct {
+		winner string
+		aBlack bool
+		want   arena.Stats
+	}{
+		{"black", true, arena.Stats{Wins: 1}},
+		{"black", false, arena.Stats{Losses: 1}},
+		{"white", true, arena.Stats{Losses: 1}},
+		{"white", false, arena.Stats{Wins: 1}},
+		{"draw", true, arena.Stats{Draws: 1}},
+		{"draw", false, arena.Stats{Draws: 1}},
+	}
+	for _, tc := range cases {
+		s := arena.Stats{}
+		s.Record(&arena.Game{Winner: tc.winner}, tc.aBlack)
+		if s != tc.want {
+			t.Errorf("%v won, A black %v: got %+v, want %+v", tc.winner, tc.aBlack, s, tc.want)
+		}
+	}
+}
+
+// A 在偶数局执黑，每个开局连续用两局，统计与每局的胜负一致
+func TestArenaMatch(t *testing.T) {
+	a := &rowEngine{name: "a", row: 0}
+	openings := [][]entity.Chess{nil, {{I: 7, J: 7}, {I: 7, J: 8}}}
+	cases := []struct {
+		name  string
+		b     engine.Engine
+		cause string
+		want  arena.Stats
+	}{
+		// 双方都走自己的一行，执黑的一方先成五
+		{"row", &rowEngine{name: "b", row: 14}, "five", arena.Stats{Wins: 3, Losses: 3}},
+		// B 每次搜索都出错，A 无论执黑执白都获胜
+		{"crash", crashEngine{}, "crash", arena.Stats{Wins: 6}},
+	}
+	for _, tc := range cases {
+		var blacks []string
+		m := &arena.Match{
+			A:        factory(a),
+			B:        factory(tc.b),
+			Games:    6,
+			Openings: openings,
+			OnGame: func(n int, aBlack bool, game *arena.Game) {
+				blacks = append(blacks, game.Black)
+				if aBlack != (n%2 == 0) {
+					t.Errorf("%v game %d: A black %v", tc.name, n, aBlack)
+				}
+				opening := openings[(n/2)%len(openings)]
+				for k, c := range opening {
+					if k >= len(game.Steps) || game.Steps[k] != c {
+						t.Errorf("%v game %d: steps %v do not start with opening %v", tc.name, n, game.Steps, opening)
+						break
+					}
+				}
+				if game.Cause != tc.cause {
+					t.Errorf("%v game %d: cause %v, want %v", tc.name, n, game.Cause, tc.cause)
+				}
+			},
+		}
+		stats, err := m.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *stats != tc.want {
+			t.Errorf("%v: got %+v, want %+v", tc.name, *stats, tc.want)
+		}
+		want := []string{"a", tc.b.Name(), "a", tc.b.Name(), "a", tc.b.Name()}
+		if !reflect.DeepEqual(blacks, want) {
+			t.Errorf("%v: black players %v, want %v", tc.name, blacks, want)
+		}
+	}
+}
+
+// 引擎用完时间后出错仍判为 crash，而不是 timeout
+func TestArenaCrashOverTime(t *testing.T) {
+	game, err := arena.Play(context.Background(), crashEngine{after: 30 * time.Millisecond}, &rowEngine{name: "a"}, nil, engine.Rules{}, arena.Clock{Base: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Cause != "crash" || game.Winner != "white" {
+		t.Fatalf("cause %v, winner %v", game.Cause, game.Winner)
+	}
+}
@@ -0,0 +1,146 @@
+package tests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/internal/store/boltdb"
+	"github.com/toujourser/gomoku/internal/store/cache"
+	"github.com/toujourser/gomoku/internal/store/memory"
+	"github.com/toujourser/gomoku/pkg/engine"
+)
+
+// 提示限流、复盘、开局库和题目在进程内存储和数据文件上都不需要 Redis
+func TestLocalBackends(t *testing.T) {
+	settings := map[string]interface{}{
+		"hint.rate":           1,
+		"hint.window":         time.Minute,
+		"hint.engine":         "basic",
+		"hint.time":           50 * time.Millisecond,
+		"hint.max_candidates": 1,
+		"explorer.enabled":    true,
+		"explorer.max_ply":    20,
+	}
+	for k, v := range settings {
+		viper.Set(k, v)
+	}
+	defer func() {
+		for k := range settings {
+			viper.Set(k, nil)
+		}
+	}()
+
+	file, db, err := boltdb.Open(filepath.Join(t.TempDir(), "gomoku.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for name, stores := range map[string]store.Stores{"memory": memory.NewStores(), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			testLocalBackend(t, stores)
+		})
+	}
+}
+
+func testLocalBackend(t *testing.T, stores store.Stores) {
+	ctx := context.Background()
+	service.UseStores(stores)
+	p, _ := service.NewPlayerConnect(ctx, "p1")
+
+	if _, err := service.Hint(ctx, p.Id, "", nil, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.Hint(ctx, p.Id, "", nil, 1); !errcode.Is(err, errcode.TooManyRequests) {
+		t.Fatalf("second hint in the window: %v", err)
+	}
+
+	review := &entity.Review{GameId: "g1", Engine: "basic", Moves: []entity.MoveReview{{Ply: 1}}}
+	if _, err := stores.Reviews.Get(ctx, "g1"); !errcode.Is(err, errcode.ReviewNotReady) {
+		t.Fatalf("missing review: %v", err)
+	}
+	if err := stores.Reviews.Set(ctx, review); err != nil {
+		t.Fatal(err)
+	}
+	if r, err := stores.Reviews.Get(ctx, "g1"); err != nil || r.Engine != "basic" || len(r.Moves) != 1 {
+		t.Fatalf("review: %+v %v", r, err)
+	}
+
+	// 两局以 h8 开局，一局黑胜一局和棋
+	for _, winner := range []string{"black", "draw"} {
+		steps, _ := engine.ParseMoves("h8 h9 i9")
+		service.RecordOpening(ctx, &entity.Game{Steps: steps, Winner: winner})
+	}
+	explored, err := service.Explore(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if explored.Games != 2 || len(explored.Moves) != 1 || engine.FormatMove(explored.Moves[0].Move) != "h8" || explored.Moves[0].WinRate != 0.75 {
+		t.Fatalf("explorer: %+v", explored)
+	}
+
+	imported, err := service.ImportPuzzle(ctx, vcfPuzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list, err := service.GetPuzzles(ctx, 0, 10); err != nil || len(*list) != 1 || (*list)[0].Id != imported.Id {
+		t.Fatalf("puzzles: %+v %v", list, err)
+	}
+	daily, err := service.StartPuzzle(ctx, p.Id, service.DailyPuzzle)
+	if err != nil || daily.Id != imported.Id || !daily.Daily {
+		t.Fatalf("daily puzzle: %+v %v", daily, err)
+	}
+	b15, _ := engine.ParseMove("b15")
+	if res, err := service.PuzzleMove(ctx, p.Id, b15); err != nil || res.Result != "failed" || len(res.Solution) == 0 {
+		t.Fatalf("wrong move: %+v %v", res, err)
+	}
+	if _, err = service.PuzzleMove(ctx, p.Id, b15); !errcode.Is(err, errcode.NoPuzzleAttempt) {
+		t.Fatalf("attempt not cleared: %v", err)
+	}
+}
+
+// 进程内存储、数据文件和写回缓存追加着法和聊天的结果一致
+func TestLocalRoomAppend(t *testing.T) {
+	file, db, err := boltdb.Open(filepath.Join(t.TempDir(), "gomoku.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	l := lock.NewLocalLock()
+	backends := map[string]store.RoomStore{
+		"memory": memory.NewStores().Rooms,
+		"file":   file.Rooms,
+		"cache":  cache.NewRooms(memory.NewStores().Rooms, l),
+	}
+	for name, rooms := range backends {
+		ctx, lease, err := l.Lock(context.Background(), "r1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = rooms.AppendMove(ctx, "r1", entity.Chess{I: 7, J: 7}); !errcode.Is(err, errcode.RoomNotFound) {
+			t.Fatalf("%v: append to missing room: %v", name, err)
+		}
+		_ = rooms.Set(ctx, &entity.Room{Id: "r1", Dialog: chat(store.DialogSize)})
+		if err = rooms.AppendMove(ctx, "r1", entity.Chess{I: 7, J: 7}); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if err = rooms.AppendChat(ctx, "r1", &entity.DialogMsg{From: "bob", Content: "hi"}); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		lease.Unlock()
+		r, err := rooms.Get(context.Background(), "r1")
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if len(r.Steps) != 1 || len(r.Dialog) != store.DialogSize || r.Dialog[0].Content != "1" || r.Dialog[store.DialogSize-1].Content != "hi" {
+			t.Fatalf("%v: %+v", name, r)
+		}
+	}
+}
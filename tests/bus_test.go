@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/bus"
+	"github.com/toujourser/gomoku/internal/redis"
+	"github.com/toujourser/gomoku/internal/store"
+	pkgredis "github.com/toujourser/gomoku/pkg/redis"
+)
+
+// peer 模拟另一个实例，订阅全局频道和自己的实例频道
+func peer(t *testing.T, ctx context.Context, instance string) <-chan *bus.Envelope {
+	t.Helper()
+	sub := pkgredis.RedisClient.Subscribe(ctx, "bus:global", "bus:instance:"+instance)
+	for k := 0; k < 2; k++ {
+		if _, err := sub.Receive(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := make(chan *bus.Envelope, 8)
+	go func() {
+		defer sub.Close()
+		for m := range sub.Channel() {
+			env := &bus.Envelope{}
+			if json.Unmarshal([]byte(m.Payload), env) == nil {
+				out <- env
+			}
+		}
+	}()
+	t.Cleanup(func() { _ = sub.Close() })
+	return out
+}
+
+// none 在短时间内没有收到消息
+func none(t *testing.T, ch <-chan *bus.Envelope, what string) {
+	t.Helper()
+	select {
+	case env := <-ch:
+		t.Fatalf("%v got %+v", what, env)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// 全局消息投递给所有其他实例，玩家消息只发到玩家所在的实例
+func TestBusRouting(t *testing.T) {
+	useMiniredis(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		// 等待总线退出，之后的测试不再向 Redis 发布
+		deadline := time.Now().Add(2 * time.Second)
+		for ok := true; ok && time.Now().Before(deadline); {
+			ok, _ = bus.Publish(context.Background(), &bus.Envelope{Kind: bus.KindRoom})
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	presence := redis.NewStores().Presence
+	local := make(chan *bus.Envelope, 8)
+	bus.Start(ctx, presence, func(env *bus.Envelope) { local <- env })
+	x, y := peer(t, ctx, "x"), peer(t, ctx, "y")
+
+	if ok, err := bus.Publish(ctx, &bus.Envelope{Kind: bus.KindRoom, Target: "r1"}); !ok || err != nil {
+		t.Fatalf("publish room message: %v %v", ok, err)
+	}
+	for name, ch := range map[string]<-chan *bus.Envelope{"x": x, "y": y} {
+		if env := expect(t, ch, name+" room message"); env.Target != "r1" || env.Origin != bus.InstanceId {
+			t.Fatalf("%v got %+v", name, env)
+		}
+	}
+	none(t, local, "own global message")
+
+	if err := presence.SetOnline(ctx, "p1", "x"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := bus.Publish(ctx, &bus.Envelope{Kind: bus.KindPlayer, Target: "p1"}); !ok || err != nil {
+		t.Fatalf("publish player message: %v %v", ok, err)
+	}
+	if env := expect(t, x, "player message"); env.Target != "p1" {
+		t.Fatalf("x got %+v", env)
+	}
+	none(t, y, "instance without the player")
+	if ok, err := bus.Publish(ctx, &bus.Envelope{Kind: bus.KindPlayer, Target: "p2"}); ok || err != nil {
+		t.Fatalf("offline player: %v %v", ok, err)
+	}
+
+	if err := bus.PublishTo(ctx, bus.InstanceId, &bus.Envelope{Kind: bus.KindRelease, Target: "r1"}); err != nil {
+		t.Fatal(err)
+	}
+	if env := expect(t, local, "release request"); env.Kind != bus.KindRelease {
+		t.Fatalf("got %+v", env)
+	}
+	none(t, x, "other instance")
+
+	b, _ := json.Marshal(&bus.Envelope{Origin: "x", Kind: bus.KindRoom, Target: "r2"})
+	if err := pkgredis.RedisClient.Publish(ctx, "bus:global", b).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if env := expect(t, local, "global message from x"); env.Target != "r2" {
+		t.Fatalf("got %+v", env)
+	}
+}
+
+// 玩家换到新实例后，旧实例迟到的下线不会清除新的在线状态；Redis 和进程内的实现行为一致
+func TestPresenceStaleOffline(t *testing.T) {
+	mr := useMiniredis(t)
+	testPresence(t, redis.NewStores().Presence, time.Second, func() { mr.FastForward(2 * time.Second) })
+	testPresence(t, store.NewLocalPresence(), 20*time.Millisecond, func() { time.Sleep(40 * time.Millisecond) })
+}
+
+// testPresence expire 让 ttl 的心跳过期
+func testPresence(t *testing.T, presence store.PresenceStore, ttl time.Duration, expire func()) {
+	ctx := context.Background()
+
+	_ = presence.SetOnline(ctx, "p1", "a")
+	_ = presence.SetOnline(ctx, "p1", "b")
+	if err := presence.SetOffline(ctx, "p1", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if instance, _ := presence.Get(ctx, "p1"); instance != "b" {
+		t.Fatalf("presence %q, want b", instance)
+	}
+	if pids, _ := presence.InstancePlayers(ctx, "a"); len(pids) != 0 {
+		t.Fatalf("player left in the old instance: %v", pids)
+	}
+	if pids, _ := presence.InstancePlayers(ctx, "b"); len(pids) != 1 || pids[0] != "p1" {
+		t.Fatalf("players of b: %v", pids)
+	}
+
+	_ = presence.SetOffline(ctx, "p1", "b")
+	if instance, err := presence.Get(ctx, "p1"); instance != "" || err != nil {
+		t.Fatalf("presence after going offline: %q %v", instance, err)
+	}
+	_ = presence.SetOnline(ctx, "p2", "b")
+	if err := presence.DelInstance(ctx, "b"); err != nil {
+		t.Fatal(err)
+	}
+	if pids, _ := presence.InstancePlayers(ctx, "b"); len(pids) != 0 {
+		t.Fatalf("players of a deleted instance: %v", pids)
+	}
+
+	if err := presence.Heartbeat(ctx, "a", ttl); err != nil {
+		t.Fatal(err)
+	}
+	if alive, _ := presence.IsAlive(ctx, "a"); !alive {
+		t.Fatal("instance with a heartbeat is not alive")
+	}
+	expire()
+	if alive, _ := presence.IsAlive(ctx, "a"); alive {
+		t.Fatal("instance alive after its heartbeat expired")
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/store/cache"
+	"github.com/toujourser/gomoku/internal/store/memory"
+)
+
+// 持锁的修改先写内存，落盘或交出房间时才写入共享存储
+func TestWriteBehindRooms(t *testing.T) {
+	ctx := context.Background()
+	backing := memory.NewStores().Rooms
+	l := lock.NewLocalLock()
+	rooms := cache.NewRooms(backing, l)
+
+	lctx, lease, err := l.Lock(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = rooms.Set(lctx, &entity.Room{Id: "r1", Steps: []entity.Chess{{I: 7, J: 7}}}); err != nil {
+		t.Fatal(err)
+	}
+	lease.Unlock()
+	if _, err = backing.Get(ctx, "r1"); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("write reached backing before flush: %v", err)
+	}
+	if r, err := rooms.Get(ctx, "r1"); err != nil || len(r.Steps) != 1 {
+		t.Fatalf("cached room: %+v %v", r, err)
+	}
+	if list, _ := rooms.List(ctx); len(*list) != 1 {
+		t.Fatalf("list %+v", *list)
+	}
+	rooms.Flush(ctx)
+	if _, err = backing.Get(ctx, "r1"); err != nil {
+		t.Fatalf("flushed room: %v", err)
+	}
+
+	lctx, lease, _ = l.Lock(ctx, "r1")
+	r, _ := rooms.Get(lctx, "r1")
+	r.Steps = append(r.Steps, entity.Chess{I: 7, J: 8})
+	_ = rooms.Set(lctx, r)
+	lease.Unlock()
+	if err = rooms.Evict(ctx, "r1"); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ = backing.Get(ctx, "r1"); len(r.Steps) != 2 {
+		t.Fatalf("evict must write the latest state: %+v", r)
+	}
+
+	lctx, lease, _ = l.Lock(ctx, "r1")
+	_ = rooms.Del(lctx, "r1")
+	lease.Unlock()
+	if _, err = rooms.Get(ctx, "r1"); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("deleted room: %v", err)
+	}
+	if list, _ := rooms.List(ctx); len(*list) != 0 {
+		t.Fatalf("deleted room listed: %+v", *list)
+	}
+	rooms.Flush(ctx)
+	if _, err = backing.Get(ctx, "r1"); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("delete not flushed: %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/client"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// fakeServer 按协议完成握手，回复 CreateRoom，对其他请求返回 ROOM_NOT_FOUND
+func fakeServer(t *testing.T) *httptest.Server {
+	return chattyServer(t, 1)
+}
+
+// chattyServer 同 fakeServer，握手后连续推送 chats 条大厅聊天
+func chattyServer(t *testing.T, chats int) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_ = conn.WriteJSON(dto.NewMsg(constants.Hello, dto.HelloDTO{Version: 1, MinVersion: 1, MaxVersion: 1}))
+		_ = conn.WriteJSON(dto.NewMsg(constants.GetPlayer, entity.Player{Id: "p1", Name: "unnamed"}))
+		for k := 0; k < chats; k++ {
+			_ = conn.WriteJSON(dto.NewMsg(constants.HallChat, entity.DialogMsg{From: "bob", Content: "hi"}))
+		}
+		for {
+			req := &dto.Message{}
+			if err := conn.ReadJSON(req); err != nil {
+				return
+			}
+			if req.Code == constants.CreateRoom {
+				_ = conn.WriteJSON(&dto.Message{Id: req.Id, Code: req.Code, Data: entity.Room{Id: "r1"}})
+				_ = conn.WriteJSON(dto.NewAckMsg(req))
+				continue
+			}
+			_ = conn.WriteJSON(dto.NewErrReplyMsg(req, errcode.New(errcode.RoomNotFound, nil), r.URL.Query().Get("lang")))
+		}
+	}))
+}
+
+func TestClientRequests(t *testing.T) {
+	srv := fakeServer(t)
+	defer srv.Close()
+
+	chat := make(chan protocol.DialogMsg, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := client.Dial(ctx, client.Options{
+		URL:         "ws" + strings.TrimPrefix(srv.URL, "http"),
+		Lang:        errcode.EN,
+		NoReconnect: true,
+	}, client.Handlers{
+		OnHallChat: func(msg protocol.DialogMsg) { chat <- msg },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if c.Player().Id != "p1" || c.Version() != 1 {
+		t.Fatalf("unexpected handshake: %+v version %v", c.Player(), c.Version())
+	}
+
+	room, err := c.CreateRoom(ctx, constants.BLACK)
+	if err != nil || room.Id != "r1" {
+		t.Fatalf("CreateRoom = %+v, %v", room, err)
+	}
+
+	err = c.EnterRoom(ctx, "nope", "challenger")
+	e, ok := err.(*client.Error)
+	if !ok || e.Code != string(errcode.RoomNotFound) || e.Message != "Room not found" {
+		t.Fatalf("EnterRoom error = %#v", err)
+	}
+
+	select {
+	case msg := <-chat:
+		if msg.Content != "hi" {
+			t.Fatalf("unexpected hall chat %+v", msg)
+		}
+	case <-ctx.Done():
+		t.Fatal("hall chat event not delivered")
+	}
+}
+
+// 回调中发起请求时，即使后面排着大量事件，读循环也能收到请求的回复
+func TestClientRequestFromCallback(t *testing.T) {
+	const chats = 1000
+	srv := chattyServer(t, chats)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	ready := make(chan *client.Client)
+	created := make(chan error, 1)
+	done := make(chan struct{})
+	n := 0
+	c, err := client.Dial(ctx, client.Options{
+		URL:            "ws" + strings.TrimPrefix(srv.URL, "http"),
+		NoReconnect:    true,
+		RequestTimeout: 2 * time.Second,
+	}, client.Handlers{
+		OnHallChat: func(msg protocol.DialogMsg) {
+			if n++; n == 1 {
+				_, err := (<-ready).CreateRoom(ctx, constants.BLACK)
+				created <- err
+			}
+			if n == chats {
+				close(done)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ready <- c
+
+	if err = expect(t, created, "CreateRoom reply"); err != nil {
+		t.Fatalf("request from a callback: %v", err)
+	}
+	expect(t, done, "queued events")
+}
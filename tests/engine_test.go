@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/alphabeta"
+	"github.com/toujourser/gomoku/pkg/engine/analysis"
+	"github.com/toujourser/gomoku/pkg/engine/engines"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+	"github.com/toujourser/gomoku/pkg/engine/solver"
+)
+
+// 每个内置引擎都应当完成自己的五连，并堵住对方的四
+func TestBuiltinEnginesTactics(t *testing.T) {
+	cases := []struct {
+		moves string
+		want  []string
+	}{
+		{"h8 a1 i8 a3 j8 a5 k8 a7", []string{"g8", "l8"}},
+		{"a1 h8 a3 i8 a5 j8 o15 k8", []string{"g8", "l8"}},
+	}
+	for _, name := range engines.Builtin {
+		e, err := engines.New(context.Background(), name, pbrain.Options{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, tc := range cases {
+			steps, err := engine.ParseMoves(tc.moves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := e.Search(context.Background(), engine.Position{Steps: steps}, engine.Limits{Time: 200 * time.Millisecond})
+			if err != nil {
+				t.Fatalf("%v: %v", name, err)
+			}
+			got := engine.FormatMove(res.Move)
+			if got != tc.want[0] && got != tc.want[1] {
+				t.Errorf("%v on %q played %v, want one of %v", name, tc.moves, got, tc.want)
+			}
+		}
+	}
+}
+
+// 根局面已经有五连时返回错误而不是崩溃
+func TestAlphabetaWonPosition(t *testing.T) {
+	steps, _ := engine.ParseMoves("h8 a1 i8 a3 j8 a5 k8 a7 l8")
+	res, err := alphabeta.New(alphabeta.Options{}).Search(context.Background(), engine.Position{Steps: steps}, engine.Limits{Depth: 2})
+	if err == nil {
+		t.Fatalf("search on a won position returned %+v", res)
+	}
+}
+
+func TestExactFiveRule(t *testing.T) {
+	steps, _ := engine.ParseMoves("a8 a1 b8 a3 c8 a5 e8 a7 f8 a9")
+	b, err := engine.NewBoardWithRules(steps, engine.Rules{ExactFive: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d8, _ := engine.ParseMove("d8")
+	if b.IsFive(d8, engine.Black) {
+		t.Fatal("overline must not win under the exact five rule")
+	}
+	b, _ = engine.NewBoard(steps)
+	if !b.IsFive(d8, engine.Black) {
+		t.Fatal("overline wins under the default rule")
+	}
+}
+
+// 多候选分析的第一候选应当是制胜点
+func TestTopMoves(t *testing.T) {
+	steps, _ := engine.ParseMoves("h8 a1 i8 a3 j8 a5 k8 a7")
+	e, _ := engines.New(context.Background(), "alphabeta", pbrain.Options{})
+	cands, err := analysis.TopMoves(context.Background(), e, engine.Position{Steps: steps}, 3, engine.Limits{Time: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cands) != 3 {
+		t.Fatalf("got %d candidates", len(cands))
+	}
+	if got := engine.FormatMove(cands[0].Move); got != "g8" && got != "l8" {
+		t.Fatalf("best candidate %v", got)
+	}
+}
+
+func TestVCF(t *testing.T) {
+	// 黑方 h8 i8 j8 横向三子、h9 h10 纵向两子，白方无威胁：k8 冲四后 h11 h12 形成连续冲四
+	steps, _ := engine.ParseMoves("h8 a1 i8 a3 j8 o1 h9 o3 h10 a15")
+	b, _ := engine.NewBoard(steps)
+	line := solver.VCF(context.Background(), b, 8)
+	if line == nil {
+		t.Fatal("expected a VCF")
+	}
+	if len(line)%2 != 1 {
+		t.Fatalf("VCF %v must end with the attacker", engine.FormatMoves(line))
+	}
+	for _, c := range line {
+		if err := b.Play(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b.Winner() != engine.Black {
+		t.Fatalf("VCF %v does not win", engine.FormatMoves(line))
+	}
+
+	steps, _ = engine.ParseMoves("h8 a1 i8")
+	b, _ = engine.NewBoard(steps)
+	if line = solver.VCF(context.Background(), b, 8); line != nil {
+		t.Fatalf("unexpected VCF %v", engine.FormatMoves(line))
+	}
+}
+
+// 黑方有冲四胜时下在别处应被标记为漏胜
+func TestReviewMissedWin(t *testing.T) {
+	steps, _ := engine.ParseMoves("h8 a1 i8 a3 j8 o1 h9 o3 h10 a15 b15")
+	e, _ := engines.New(context.Background(), "alphabeta", pbrain.Options{})
+	moves, err := analysis.Review(context.Background(), e, engine.Position{Steps: steps}, analysis.ReviewOptions{Limits: engine.Limits{Depth: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moves) != len(steps) {
+		t.Fatalf("got %d annotations", len(moves))
+	}
+	last := moves[len(moves)-1]
+	if !last.MissedWin || len(last.VCF) == 0 {
+		t.Fatalf("b15 should be a missed win: %+v", last)
+	}
+	if moves[0].MissedWin || moves[0].Blunder {
+		t.Fatalf("h8 is not a mistake: %+v", moves[0])
+	}
+}
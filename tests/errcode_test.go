@@ -0,0 +1,40 @@
+package tests
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/errcode"
+)
+
+func TestErrcodeLocalize(t *testing.T) {
+	err := errcode.New(errcode.NameTaken, errcode.Params{"name": "bob2"})
+	if got := err.Message(errcode.EN); got != "The name is already taken, you have been renamed to bob2" {
+		t.Fatalf("unexpected en message: %v", got)
+	}
+	if got := err.Message(errcode.ZH); got != "该用户名已存在，系统自动重置名称为 bob2" {
+		t.Fatalf("unexpected zh message: %v", got)
+	}
+}
+
+func TestErrcodeHidesInternalErrors(t *testing.T) {
+	e := dto.NewErrDTO(fmt.Errorf("dial tcp 127.0.0.1:6379: connection refused"), errcode.EN)
+	if e.Code != errcode.Internal || e.Message != "Internal server error, please try again later" {
+		t.Fatalf("internal error leaked: %+v", e)
+	}
+}
+
+func TestParseLang(t *testing.T) {
+	cases := map[string]string{
+		"":                       errcode.ZH,
+		"en-US,en;q=0.9":         errcode.EN,
+		"fr-FR, zh-CN;q=0.8, en": errcode.ZH,
+		"de":                     errcode.ZH,
+	}
+	for in, want := range cases {
+		if got := errcode.ParseLang(in); got != want {
+			t.Errorf("ParseLang(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/feed"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store/memory"
+)
+
+// 订阅者只收到所订阅房间中有对应事件名的消息，取消订阅后不再收到
+func TestFeedSubscribe(t *testing.T) {
+	f := feed.New()
+	events, cancel := f.Subscribe("r1")
+	other, cancelOther := f.Subscribe("r2")
+	defer cancelOther()
+
+	f.Publish("r1", &dto.Message{Code: constants.MakeStep, Data: entity.Chess{I: 7, J: 7}})
+	f.Publish("r1", &dto.Message{Code: constants.GetPlayer})
+	f.Publish("r1", &dto.Message{Code: constants.DelRoom, Data: "r1"})
+	for _, name := range []string{"step", "close"} {
+		if e := expect(t, events, name); e.Name != name {
+			t.Fatalf("got %v, want %v", e.Name, name)
+		}
+	}
+	if len(events) != 0 || len(other) != 0 {
+		t.Fatalf("unexpected events: %v %v", len(events), len(other))
+	}
+
+	cancel()
+	cancel()
+	f.Publish("r1", &dto.Message{Code: constants.RoomChat})
+	if len(events) != 0 {
+		t.Fatal("event delivered after unsubscribe")
+	}
+}
+
+// 订阅者的缓冲区满了以后丢弃新事件，不阻塞发布者
+func TestFeedDropsWhenFull(t *testing.T) {
+	f := feed.New()
+	events, cancel := f.Subscribe("r1")
+	defer cancel()
+
+	for i := 0; i < 100; i++ {
+		f.Publish("r1", &dto.Message{Code: constants.RoomChat, Data: i})
+	}
+	if len(events) != 64 {
+		t.Fatalf("buffered %v events, want 64", len(events))
+	}
+	if e := <-events; e.Data != 0 {
+		t.Fatalf("first event %v, want the oldest one", e.Data)
+	}
+}
+
+// SSE 先发送房间快照，收到 close 事件后结束响应；房间不存在时返回 404
+func TestRoomEventsSSE(t *testing.T) {
+	ctx := context.Background()
+	service.UseStores(memory.NewStores())
+	srv := httptest.NewServer(restRouter())
+	defer srv.Close()
+
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	room, err := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(srv.URL + "/api/rooms/missing/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("missing room: %v", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/api/rooms/" + room.Id + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	r := bufio.NewReader(resp.Body)
+	readEvent := func() (string, string) {
+		t.Helper()
+		var name, data string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("reading event: %v", err)
+			}
+			line = strings.TrimRight(line, "\n")
+			if line == "" && name != "" {
+				return name, data
+			}
+			if v, ok := strings.CutPrefix(line, "event:"); ok {
+				name = v
+			} else if v, ok := strings.CutPrefix(line, "data:"); ok {
+				data = v
+			}
+		}
+	}
+
+	// 快照发出时已经订阅，之后发布的事件不会丢
+	if name, data := readEvent(); name != "snapshot" || !strings.Contains(data, room.Id) {
+		t.Fatalf("first event %v %v, want snapshot", name, data)
+	}
+	feed.RoomFeed.Publish(room.Id, &dto.Message{Code: constants.MakeStep, Data: entity.Chess{I: 7, J: 7}})
+	feed.RoomFeed.Publish(room.Id, &dto.Message{Code: constants.DelRoom, Data: room.Id})
+	if name, _ := readEvent(); name != "step" {
+		t.Fatalf("got %v, want step", name)
+	}
+	if name, _ := readEvent(); name != "close" {
+		t.Fatalf("got %v, want close", name)
+	}
+	if rest, err := io.ReadAll(r); err != nil || len(rest) != 0 {
+		t.Fatalf("stream not closed after close event: %q %v", rest, err)
+	}
+}
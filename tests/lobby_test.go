@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store/memory"
+)
+
+// 大厅按座位、规则和积分过滤，排序稳定，分页返回过滤后的总数
+func TestLobby(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStores()
+	service.UseStores(s)
+
+	for i, rating := range []int{1400, 1500, 1600, 1700} {
+		pid := string(rune('a' + i))
+		_ = s.Players.Set(ctx, &entity.Player{Id: pid, Name: pid, Rating: rating})
+		settings := entity.RoomSettings{Rated: i%2 == 0}
+		if _, err := service.CreateRoom(ctx, pid, 0, settings); err != nil {
+			t.Fatal(err)
+		}
+	}
+	guest, _ := service.NewPlayerConnect(ctx, "guest")
+	page, _ := service.Lobby(ctx, &dto.LobbyQuery{Sort: dto.SortRating})
+	if page.Total != 4 || page.Rooms[0].Host.Rating != 1700 || page.Rooms[3].Host.Rating != 1400 {
+		t.Fatalf("rooms by rating: %+v", page)
+	}
+	if _, err := service.EnterRoom(ctx, guest.Id, page.Rooms[0].Id, "challenger"); err != nil {
+		t.Fatal(err)
+	}
+
+	yes, no := true, false
+	page, _ = service.Lobby(ctx, &dto.LobbyQuery{Open: &yes, MinRating: 1500, Sort: dto.SortRating, Asc: true})
+	if page.Total != 2 || page.Rooms[0].Host.Rating != 1500 || page.Rooms[1].Host.Rating != 1600 {
+		t.Fatalf("open rooms rated 1500+: %+v", page)
+	}
+	page, _ = service.Lobby(ctx, &dto.LobbyQuery{Open: &no})
+	if page.Total != 1 || page.Rooms[0].State != dto.RoomFull || page.Rooms[0].Challenger.Id != guest.Id {
+		t.Fatalf("full rooms: %+v", page)
+	}
+	page, _ = service.Lobby(ctx, &dto.LobbyQuery{Rated: &yes, Playing: &no})
+	if page.Total != 2 {
+		t.Fatalf("rated rooms: %+v", page)
+	}
+
+	first, _ := service.Lobby(ctx, &dto.LobbyQuery{Limit: 3})
+	second, _ := service.Lobby(ctx, &dto.LobbyQuery{Offset: 3, Limit: 3})
+	if len(first.Rooms) != 3 || len(second.Rooms) != 1 || second.Total != 4 {
+		t.Fatalf("pages: %+v %+v", first, second)
+	}
+	for _, r := range first.Rooms {
+		if r.Id == second.Rooms[0].Id {
+			t.Fatalf("room %v on both pages", r.Id)
+		}
+	}
+
+	if _, err := service.Lobby(ctx, &dto.LobbyQuery{Sort: "name"}); !errcode.Is(err, errcode.InvalidParam) {
+		t.Fatalf("unknown sort: %v", err)
+	}
+}
+
+// 计分对局结束后双方积分按 Elo 更新
+func TestRatedGame(t *testing.T) {
+	if a, b := service.Elo(1500, 1500, 1); a != 1516 || b != 1484 {
+		t.Fatalf("Elo(1500, 1500, win) = %v, %v", a, b)
+	}
+	if a, b := service.Elo(1400, 1600, 0.5); a <= 1400 || a+b != 3000 {
+		t.Fatalf("Elo(1400, 1600, draw) = %v, %v", a, b)
+	}
+
+	ctx := context.Background()
+	service.UseStores(memory.NewStores())
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	guest, _ := service.NewPlayerConnect(ctx, "guest")
+	r, _ := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{Rated: true})
+	_, _ = service.EnterRoom(ctx, guest.Id, r.Id, "challenger")
+	_, _ = service.SetReady(ctx, r.Id, host.Id, true)
+	_, _ = service.SetReady(ctx, r.Id, guest.Id, true)
+	if _, _, err := service.Surrender(ctx, guest.Id, r.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	host, _ = service.GetPlayer(ctx, host.Id)
+	guest, _ = service.GetPlayer(ctx, guest.Id)
+	if host.Rating != 1516 || guest.Rating != 1484 {
+		t.Fatalf("ratings after surrender: host %v guest %v", host.Rating, guest.Rating)
+	}
+	page, _ := service.Lobby(ctx, &dto.LobbyQuery{})
+	if page.Rooms[0].Host.Rating != 1516 {
+		t.Fatalf("room seat not updated: %+v", page.Rooms[0].Host)
+	}
+}
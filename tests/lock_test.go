@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/lock"
+)
+
+// 释放后不留下空闲条目，ctx 结束的等待者返回 ctx 的错误
+func TestKeyedCleanup(t *testing.T) {
+	k := lock.NewKeyed()
+	unlock, err := k.Lock(context.Background(), "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Len() != 1 {
+		t.Fatalf("len %d while held", k.Len())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err = k.Lock(ctx, "r1"); err != context.DeadlineExceeded {
+		t.Fatalf("waiter: %v", err)
+	}
+	unlock()
+	unlock()
+	if k.Len() != 0 {
+		t.Fatalf("len %d after unlock", k.Len())
+	}
+
+	// 已结束的 ctx 即使锁空闲也不加锁
+	for i := 0; i < 100; i++ {
+		if _, err = k.Lock(ctx, "r2"); err != context.DeadlineExceeded {
+			t.Fatalf("expired ctx: %v", err)
+		}
+	}
+	if k.Len() != 0 {
+		t.Fatalf("len %d after expired waits", k.Len())
+	}
+}
+
+// 同一 id 的加锁依次进行，不同 id 互不影响
+func TestKeyedSerializes(t *testing.T) {
+	k := lock.NewKeyed()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	holders, peak := 0, 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock, err := k.Lock(ctx, "r1")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			holders++
+			if holders > peak {
+				peak = holders
+			}
+			mu.Unlock()
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			holders--
+			mu.Unlock()
+			unlock()
+		}()
+	}
+
+	unlock, err := k.Lock(ctx, "r2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+	wg.Wait()
+	if peak != 1 {
+		t.Fatalf("%d holders at once", peak)
+	}
+	if k.Len() != 0 {
+		t.Fatalf("len %d after all unlocked", k.Len())
+	}
+}
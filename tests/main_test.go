@@ -0,0 +1,15 @@
+package tests
+
+import (
+	"os"
+	"testing"
+)
+
+// TestMain 在设置了 GOMOKU_STUB_ENGINE 时把测试二进制当作 pbrain 桩引擎运行
+func TestMain(m *testing.M) {
+	if mode, ok := os.LookupEnv("GOMOKU_STUB_ENGINE"); ok {
+		runStubEngine(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/pbrain"
+)
+
+// runStubEngine 一个最小的 pbrain 引擎：总是下在第一行从左数第一个空位。
+// mode 为 crash 时收到 TURN 即退出，为 slow 时收到 TURN 后不回复。
+func runStubEngine(mode string) {
+	taken := map[string]bool{}
+	reply := func() {
+		for x := 0; ; x++ {
+			k := fmt.Sprintf("%d,0", x)
+			if !taken[k] {
+				taken[k] = true
+				fmt.Println(k)
+				return
+			}
+		}
+	}
+	in := bufio.NewScanner(os.Stdin)
+	for in.Scan() {
+		cmd, arg, _ := strings.Cut(strings.TrimSpace(in.Text()), " ")
+		switch cmd {
+		case "START", "RESTART":
+			taken = map[string]bool{}
+			fmt.Println("OK")
+		case "ABOUT":
+			fmt.Println(`name="stub", version="1.0"`)
+		case "BEGIN":
+			fmt.Println("MESSAGE thinking")
+			reply()
+		case "TURN":
+			switch mode {
+			case "crash":
+				os.Exit(3)
+			case "slow":
+				continue
+			}
+			taken[arg] = true
+			reply()
+		case "BOARD":
+			for in.Scan() && in.Text() != "DONE" {
+				parts := strings.Split(in.Text(), ",")
+				taken[parts[0]+","+parts[1]] = true
+			}
+			reply()
+		case "END":
+			return
+		}
+	}
+}
+
+func startStub(t *testing.T, mode string, opts pbrain.Options) *pbrain.Engine {
+	t.Setenv("GOMOKU_STUB_ENGINE", mode)
+	e, err := pbrain.Start(context.Background(), os.Args[0], nil, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = e.Close() })
+	return e
+}
+
+func move(e *pbrain.Engine, steps []entity.Chess) (entity.Chess, error) {
+	res, err := e.Search(context.Background(), engine.Position{Steps: steps}, engine.Limits{})
+	if err != nil {
+		return entity.Chess{}, err
+	}
+	return res.Move, nil
+}
+
+func TestPbrainMoves(t *testing.T) {
+	e := startStub(t, "", pbrain.Options{TurnTimeout: time.Second})
+	if e.Name() != "stub" {
+		t.Fatalf("name = %v", e.Name())
+	}
+	// BEGIN
+	c, err := move(e, nil)
+	if err != nil || c != (entity.Chess{I: 0, J: 0}) {
+		t.Fatalf("BEGIN = %v, %v", c, err)
+	}
+	// TURN
+	steps := []entity.Chess{c, {I: 7, J: 7}}
+	c, err = move(e, steps)
+	if err != nil || c != (entity.Chess{I: 0, J: 1}) {
+		t.Fatalf("TURN = %v, %v", c, err)
+	}
+	// BOARD：局面与引擎已知的不连续
+	c, err = move(e, []entity.Chess{{I: 0, J: 0}, {I: 0, J: 1}, {I: 0, J: 2}})
+	if err != nil || c != (entity.Chess{I: 0, J: 3}) {
+		t.Fatalf("BOARD = %v, %v", c, err)
+	}
+}
+
+func TestPbrainCrash(t *testing.T) {
+	e := startStub(t, "crash", pbrain.Options{TurnTimeout: time.Second})
+	c, err := move(e, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = move(e, []entity.Chess{c, {I: 7, J: 7}})
+	if !errors.Is(err, pbrain.ErrCrashed) {
+		t.Fatalf("expected crash, got %v", err)
+	}
+}
+
+func TestPbrainTimeout(t *testing.T) {
+	e := startStub(t, "slow", pbrain.Options{TurnTimeout: 100 * time.Millisecond, Grace: 100 * time.Millisecond})
+	c, err := move(e, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = move(e, []entity.Chess{c, {I: 7, J: 7}})
+	if !errors.Is(err, pbrain.ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/pkg/engine/analysis"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+// pkg/protocol 中的类型与服务端下发的 JSON 一一对应：服务端的每个字段都能解析，重新编码后内容不变
+func TestProtocolMatchesServer(t *testing.T) {
+	c := entity.Chess{I: 7, J: 8}
+	pd := entity.PlayerDetails{
+		Player: entity.Player{Id: "p1", Name: "alice", Status: "playing", LoginTime: "t", MatchesPlayed: 3, Rating: 1520},
+		Role:   "host",
+		Color:  constants.WHITE,
+		Ready:  true,
+	}
+	tree := &entity.MoveTree{
+		Nodes:   map[int]*entity.TreeNode{0: {Id: 0, Parent: -1, Children: []int{1}}, 1: {Id: 1, Move: &c, Children: []int{}}},
+		Current: 1,
+		NextId:  2,
+	}
+	room := entity.Room{
+		Id:         "r1",
+		Settings:   entity.RoomSettings{Rated: true, Hints: true, Analysis: true},
+		Dialog:     []entity.DialogMsg{{Time: "t", From: "alice", Content: "hi"}},
+		Steps:      []entity.Chess{c},
+		Tree:       tree,
+		CreateTime: "t",
+		Started:    true,
+		StartTime:  "t",
+		Host:       pd,
+		Challenger: pd,
+		Spectators: []entity.Player{pd.Player},
+	}
+	summary := dto.NewRoomSummary(&room)
+	cases := []struct {
+		name   string
+		server interface{}
+		client interface{}
+	}{
+		{"room", room, &protocol.Room{}},
+		{"hello", dto.HelloDTO{Version: 3, MinVersion: 1, MaxVersion: 3, ResumeToken: "t"}, &protocol.HelloDTO{}},
+		{"error", dto.NewErrDTO(errcode.New(errcode.RoomNotFound, errcode.Params{"rid": "r1"}), errcode.EN), &protocol.ErrDTO{}},
+		{"ack", dto.AckDTO{Code: constants.MakeStep}, &protocol.AckDTO{}},
+		{"lobby", dto.LobbyDTO{Total: 1, Limit: 20, Rooms: []dto.RoomSummary{summary}}, &protocol.LobbyDTO{}},
+		{"lobby query", dto.LobbyQuery{Open: &room.Started, Playing: &room.Started, Rated: &room.Started, Hints: &room.Started, Analysis: &room.Started,
+			MinRating: 1, MaxRating: 2, Sort: dto.SortRating, Asc: true, Offset: 1, Limit: 2}, &protocol.LobbyQuery{}},
+		{"lobby delta", dto.LobbyDeltaDTO{Op: dto.DeltaUpdate, RId: "r1", Room: &summary}, &protocol.LobbyDeltaDTO{}},
+		{"player delta", dto.PlayerDeltaDTO{Op: dto.DeltaAdd, PId: "p1", Player: &pd.Player}, &protocol.PlayerDeltaDTO{}},
+		{"game over", dto.GameOverDTO{RId: "r1", Winner: pd, Loser: pd, Cause: "five"}, &protocol.GameOverDTO{}},
+		{"tree", dto.TreeDTO{RId: "r1", Tree: tree, Steps: []entity.Chess{c}}, &protocol.TreeDTO{}},
+		{"hint", dto.HintDTO{RId: "r1", Moves: 1, Candidates: []analysis.Candidate{{Move: c, Score: 10, PV: []entity.Chess{c}}}}, &protocol.HintDTO{}},
+		{"review", entity.Review{GameId: "g1", Engine: "alphabeta", CreatedAt: "t", Moves: []entity.MoveReview{{
+			Ply: 1, Move: c, Eval: 1, Best: c, BestEval: 2, Loss: 1, Blunder: true, MissedWin: true, VCF: []entity.Chess{c}, PV: []entity.Chess{c},
+		}}}, &protocol.Review{}},
+		{"puzzle", dto.PuzzleDTO{Id: "z1", Title: "t", Goal: "vcf", Depth: 3, ToMove: "black", Steps: []entity.Chess{c}, Moves: []entity.Chess{c}, Daily: true}, &protocol.PuzzleDTO{}},
+		{"puzzle move", dto.PuzzleMoveDTO{Id: "z1", Move: c, Reply: &c, Result: "failed", Remaining: 1, Solution: []entity.Chess{c}}, &protocol.PuzzleMoveDTO{}},
+		{"explorer", dto.ExplorerDTO{Steps: []entity.Chess{c}, Games: 2, Moves: []dto.ExplorerMove{{Move: c, Games: 2, BlackWins: 1, Draws: 1, WinRate: 0.75}}}, &protocol.ExplorerDTO{}},
+	}
+	for _, tc := range cases {
+		want, _ := json.Marshal(tc.server)
+		d := json.NewDecoder(bytes.NewReader(want))
+		d.DisallowUnknownFields()
+		if err := d.Decode(tc.client); err != nil {
+			t.Fatalf("%v: %v", tc.name, err)
+		}
+		if got, _ := json.Marshal(tc.client); !bytes.Equal(got, want) {
+			t.Fatalf("%v:\n got %s\nwant %s", tc.name, got, want)
+		}
+	}
+}
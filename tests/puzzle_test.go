@@ -0,0 +1,69 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/engine/puzzle"
+)
+
+const vcfPuzzle = `
+# 黑先，两次冲四取胜
+title: 横竖冲四
+to move: black
+goal: vcf 2
+h8 a1 i8 a3 j8 o1
+h9 o3 h10 a15
+`
+
+func TestPuzzleImport(t *testing.T) {
+	p, err := puzzle.Parse(context.Background(), vcfPuzzle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Title != "横竖冲四" || p.Depth != 2 || len(p.Steps) != 10 || len(p.Solution) == 0 {
+		t.Fatalf("unexpected puzzle %+v", p)
+	}
+
+	for _, text := range []string{
+		"goal: vcf 2\nh8 a1 i8",                    // 无解
+		"to move: white\ngoal: vcf 2\nh8 a1 i8 a3", // 行棋方不符
+		"goal: vct 2\nh8 a1 i8",                    // 不支持的目标
+		"goal: vcf 2\nh8 h8",                       // 非法局面
+	} {
+		if _, err = puzzle.Parse(context.Background(), text); err == nil {
+			t.Errorf("expected %q to be rejected", text)
+		}
+	}
+}
+
+func TestPuzzlePlay(t *testing.T) {
+	p, _ := puzzle.Parse(context.Background(), vcfPuzzle)
+
+	// 不冲四的着法失败
+	b15, _ := engine.ParseMove("b15")
+	if outcome, _, _ := puzzle.Play(context.Background(), p, nil, b15); outcome != puzzle.Failed {
+		t.Fatalf("b15 = %v", outcome)
+	}
+
+	// 按解答下，每一步都应继续直到成五
+	var moves = p.Solution[:0:0]
+	for k := 0; k < len(p.Solution); k += 2 {
+		outcome, reply, err := puzzle.Play(context.Background(), p, moves, p.Solution[k])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if k == len(p.Solution)-1 {
+			if outcome != puzzle.Solved {
+				t.Fatalf("final move = %v", outcome)
+			}
+			return
+		}
+		if outcome != puzzle.Continue || reply == nil {
+			t.Fatalf("move %d = %v", k, outcome)
+		}
+		moves = append(moves, p.Solution[k], *reply)
+	}
+	t.Fatal("solution does not end with a five")
+}
@@ -0,0 +1,359 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/lock"
+	"github.com/toujourser/gomoku/internal/redis"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/internal/store/cache"
+	"github.com/toujourser/gomoku/internal/store/memory"
+	pkgredis "github.com/toujourser/gomoku/pkg/redis"
+)
+
+// useMiniredis 让 Redis 客户端连接到进程内的 miniredis
+func useMiniredis(t *testing.T) *miniredis.Miniredis {
+	mr := miniredis.RunT(t)
+	client := pkgredis.RedisClient
+	pkgredis.RedisClient = goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		_ = pkgredis.RedisClient.Close()
+		pkgredis.RedisClient = client
+	})
+	return mr
+}
+
+func chat(n int) []entity.DialogMsg {
+	msgs := make([]entity.DialogMsg, n)
+	for k := range msgs {
+		msgs[k] = entity.DialogMsg{From: "alice", Content: fmt.Sprint(k)}
+	}
+	return msgs
+}
+
+// 房间按字段拆分保存，每次写入后读回的房间与写入的一致
+func TestRedisRoomLayout(t *testing.T) {
+	mr := useMiniredis(t)
+	ctx := context.Background()
+	rooms := redis.NewStores().Rooms
+
+	r := &entity.Room{
+		Id:         "r1",
+		Settings:   entity.RoomSettings{Hints: true},
+		Dialog:     chat(2),
+		Steps:      []entity.Chess{{I: 7, J: 7}, {I: 7, J: 8}, {I: 8, J: 8}},
+		Started:    true,
+		Host:       entity.PlayerDetails{Player: entity.Player{Id: "p1", Name: "alice"}, Role: "host"},
+		Challenger: entity.PlayerDetails{Player: entity.Player{Id: "p2", Name: "bob"}, Role: "challenger", Color: 1},
+		Spectators: []entity.Player{{Id: "p3", Name: "carol"}, {Id: "p4", Name: "dave"}},
+	}
+	check := func(step string) {
+		t.Helper()
+		if err := rooms.Set(ctx, r); err != nil {
+			t.Fatalf("%v: %v", step, err)
+		}
+		got, err := rooms.Get(ctx, r.Id)
+		if err != nil {
+			t.Fatalf("%v: %v", step, err)
+		}
+		if !reflect.DeepEqual(got, r) {
+			t.Fatalf("%v: got %+v, want %+v", step, got, r)
+		}
+	}
+	check("create")
+
+	r.Steps = append(r.Steps, entity.Chess{I: 9, J: 9})
+	r.Dialog = append(r.Dialog, chat(store.DialogSize)...)[2:]
+	check("move and chat")
+	if n, _ := mr.List("room:r1:chat"); len(n) != store.DialogSize {
+		t.Fatalf("chat not capped: %d", len(n))
+	}
+	r.Steps = r.Steps[:2]
+	check("retract")
+	r.Steps = []entity.Chess{{I: 0, J: 0}}
+	r.Spectators = r.Spectators[1:]
+	check("new game")
+	r.Dialog = []entity.DialogMsg{}
+	r.Tree = &entity.MoveTree{Nodes: map[int]*entity.TreeNode{0: {Id: 0, Parent: -1, Children: []int{}}}, NextId: 1}
+	check("analysis")
+
+	list, err := rooms.List(ctx)
+	if err != nil || len(*list) != 1 || len((*list)[0].Steps) != 0 || (*list)[0].Host.Name != "alice" {
+		t.Fatalf("list %+v %v", list, err)
+	}
+
+	lctx := lock.WithToken(ctx, 5)
+	if err = rooms.Set(lctx, r); err != nil {
+		t.Fatal(err)
+	}
+	if err = rooms.Set(lock.WithToken(ctx, 3), r); !errcode.Is(err, errcode.RoomBusy) {
+		t.Fatalf("stale token: %v", err)
+	}
+	if err = rooms.Del(lctx, r.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = rooms.Get(ctx, r.Id); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("deleted room: %v", err)
+	}
+	if list, _ = rooms.List(ctx); len(*list) != 0 {
+		t.Fatalf("deleted room listed: %+v", *list)
+	}
+}
+
+// 落子和聊天只追加对应的列表，不改写房间的其他部分
+func TestRedisRoomAppend(t *testing.T) {
+	mr := useMiniredis(t)
+	ctx := context.Background()
+	rooms := redis.NewStores().Rooms
+
+	if err := rooms.AppendMove(ctx, "r1", entity.Chess{I: 7, J: 7}); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("append to missing room: %v", err)
+	}
+	r := &entity.Room{Id: "r1", Dialog: chat(store.DialogSize), Steps: []entity.Chess{{I: 7, J: 7}}, Spectators: []entity.Player{},
+		Host: entity.PlayerDetails{Player: entity.Player{Id: "p1"}}}
+	lctx := lock.WithToken(ctx, 5)
+	if err := rooms.Set(lctx, r); err != nil {
+		t.Fatal(err)
+	}
+	// 房间的 hash 被改写时能看出来
+	mr.HSet("room:r1", "start_time", "marker")
+
+	if err := rooms.AppendMove(lctx, "r1", entity.Chess{I: 7, J: 8}); err != nil {
+		t.Fatal(err)
+	}
+	msg := entity.DialogMsg{From: "bob", Content: "hi"}
+	if err := rooms.AppendChat(lctx, "r1", &msg); err != nil {
+		t.Fatal(err)
+	}
+	got, err := rooms.Get(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.StartTime != "marker" {
+		t.Fatal("append rewrote the room hash")
+	}
+	if len(got.Steps) != 2 || got.Steps[1] != (entity.Chess{I: 7, J: 8}) {
+		t.Fatalf("steps %+v", got.Steps)
+	}
+	if len(got.Dialog) != store.DialogSize || got.Dialog[store.DialogSize-1] != msg || got.Dialog[0].Content != "1" {
+		t.Fatalf("dialog %+v", got.Dialog)
+	}
+
+	stale := lock.WithToken(ctx, 3)
+	if err = rooms.AppendMove(stale, "r1", entity.Chess{I: 0, J: 0}); !errcode.Is(err, errcode.RoomBusy) {
+		t.Fatalf("stale move: %v", err)
+	}
+	if err = rooms.AppendChat(stale, "r1", &msg); !errcode.Is(err, errcode.RoomBusy) {
+		t.Fatalf("stale chat: %v", err)
+	}
+
+	if err = rooms.Del(ctx, "r1"); err != nil {
+		t.Fatal(err)
+	}
+	if ttl := mr.TTL("fence:room:r1"); ttl <= 0 {
+		t.Fatalf("fence of deleted room kept forever: %v", ttl)
+	}
+}
+
+// 旧格式整个房间保存在 room hash 的一个字段里，迁移后按字段拆分
+func TestRedisMigrateRooms(t *testing.T) {
+	mr := useMiniredis(t)
+	ctx := context.Background()
+
+	r := &entity.Room{Id: "r1", Dialog: chat(1), Steps: []entity.Chess{{I: 7, J: 7}}, Spectators: []entity.Player{},
+		Host: entity.PlayerDetails{Player: entity.Player{Id: "p1"}}}
+	b, _ := json.Marshal(r)
+	mr.HSet("room", r.Id, string(b))
+
+	if n, err := redis.MigrateRooms(ctx); err != nil || n != 1 {
+		t.Fatalf("migrated %d: %v", n, err)
+	}
+	if mr.Exists("room") {
+		t.Fatal("old hash left behind")
+	}
+	got, err := redis.NewStores().Rooms.Get(ctx, r.Id)
+	if err != nil || !reflect.DeepEqual(got, r) {
+		t.Fatalf("got %+v %v", got, err)
+	}
+	if n, _ := redis.MigrateRooms(ctx); n != 0 {
+		t.Fatalf("second migration moved %d rooms", n)
+	}
+}
+
+// 保留的租约在同一实例内重复使用，其他实例加锁时请求持有者交出
+func TestStickyLockHandoff(t *testing.T) {
+	useMiniredis(t)
+	ctx := context.Background()
+
+	locks := map[string]*lock.Lock{"a": lock.NewLock("lock:room:"), "b": lock.NewLock("lock:room:")}
+	released := make(chan int64, 1)
+	for owner, l := range locks {
+		owner := owner
+		l.Sticky(lock.StickyOptions{
+			Owner: owner,
+			Request: func(holder string, id string) {
+				go func() { _ = locks[holder].Release(ctx, id) }()
+			},
+			Release: func(ctx context.Context, id string) error {
+				if owner == "a" {
+					token, _ := lock.Token(ctx)
+					released <- token
+				}
+				return nil
+			},
+		})
+	}
+
+	_, lease, err := locks["a"].Lock(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := lease.Token
+	lease.Unlock()
+	if _, lease, _ = locks["a"].Lock(ctx, "r1"); lease.Token != first {
+		t.Fatalf("lease not reused: %d != %d", lease.Token, first)
+	}
+	lease.Unlock()
+
+	_, lease, err = locks["b"].Lock(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lease.Unlock()
+	if token := <-released; token != first {
+		t.Fatalf("released with token %d, want %d", token, first)
+	}
+	if lease.Token <= first {
+		t.Fatalf("new holder token %d must be greater than %d", lease.Token, first)
+	}
+}
+
+// failingRooms Set 在 fail 为 true 时返回错误，模拟共享存储暂时不可用
+type failingRooms struct {
+	store.RoomStore
+	fail bool
+}
+
+func (s *failingRooms) Set(ctx context.Context, room *entity.Room) error {
+	if s.fail {
+		return errors.New("backing unavailable")
+	}
+	return s.RoomStore.Set(ctx, room)
+}
+
+// 交出房间时写入失败，房间留在内存中且不交出租约，恢复后再次交出
+func TestEvictFailureKeepsLease(t *testing.T) {
+	mr := useMiniredis(t)
+	ctx := context.Background()
+
+	backing := &failingRooms{RoomStore: memory.NewStores().Rooms, fail: true}
+	l := lock.NewLock("lock:room:")
+	rooms := cache.NewRooms(backing, l)
+	l.Sticky(lock.StickyOptions{Owner: "a", Release: rooms.Evict, Lost: rooms.Forget})
+
+	lctx, lease, err := l.Lock(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := lease.Token
+	_ = rooms.Set(lctx, &entity.Room{Id: "r1", Steps: []entity.Chess{{I: 7, J: 7}}})
+	lease.Unlock()
+
+	if err = l.Release(ctx, "r1"); err == nil {
+		t.Fatal("release must fail while the room cannot be written")
+	}
+	if !mr.Exists("lock:room:r1") {
+		t.Fatal("lease given up with unsaved changes")
+	}
+	if r, err := rooms.Get(ctx, "r1"); err != nil || len(r.Steps) != 1 {
+		t.Fatalf("cached room lost: %+v %v", r, err)
+	}
+	if _, lease, _ = l.Lock(ctx, "r1"); lease.Token != token {
+		t.Fatalf("lease not kept: %d != %d", lease.Token, token)
+	}
+	lease.Unlock()
+
+	backing.fail = false
+	if err = l.Release(ctx, "r1"); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists("lock:room:r1") {
+		t.Fatal("lease not released")
+	}
+	if r, err := backing.Get(ctx, "r1"); err != nil || len(r.Steps) != 1 {
+		t.Fatalf("room not written: %+v %v", r, err)
+	}
+}
+
+// 两个实例竞争同一房间：后来者等到租约释放才拿到锁，等待超时返回 RoomBusy；
+// 租约过期后旧持有者的写入因 fencing token 过期被拒绝
+func TestRedisLockFencing(t *testing.T) {
+	mr := useMiniredis(t)
+	ctx := context.Background()
+	viper.Set("lock.wait", 100*time.Millisecond)
+	defer viper.Set("lock.wait", nil)
+
+	a, b := lock.NewLock("lock:room:"), lock.NewLock("lock:room:")
+	_, first, err := a.Lock(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = b.Lock(ctx, "r1"); err != lock.ErrTimeout {
+		t.Fatalf("second locker: %v", err)
+	}
+
+	stores := redis.NewStores()
+	stores.RoomLock = b
+	service.UseStores(stores)
+	if _, err = service.RoomChat(ctx, "r1", &entity.DialogMsg{Content: "hi"}); !errcode.Is(err, errcode.RoomBusy) {
+		t.Fatalf("service while the room is locked: %v", err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		first.Unlock()
+	}()
+	_, second, err := b.Lock(ctx, "r1")
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	if second.Token <= first.Token {
+		t.Fatalf("token %d not greater than %d", second.Token, first.Token)
+	}
+	second.Unlock()
+
+	// a 的租约过期后 b 拿到锁并写入，a 之后的写入被拒绝
+	actx, stale, _ := a.Lock(ctx, "r1")
+	mr.FastForward(time.Minute)
+	bctx, fresh, err := b.Lock(ctx, "r1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Unlock()
+	room := &entity.Room{Id: "r1", Host: entity.PlayerDetails{Player: entity.Player{Id: "p1"}}}
+	if err = stores.Rooms.Set(bctx, room); err != nil {
+		t.Fatal(err)
+	}
+	if err = stores.Rooms.Set(actx, room); !errcode.Is(err, errcode.RoomBusy) {
+		t.Fatalf("write with an expired lease: %v", err)
+	}
+	if err = stores.Rooms.AppendMove(actx, "r1", entity.Chess{I: 7, J: 7}); !errcode.Is(err, errcode.RoomBusy) {
+		t.Fatalf("append with an expired lease: %v", err)
+	}
+	stale.Unlock()
+	if !mr.Exists("lock:room:r1") {
+		t.Fatal("stale holder released the new lease")
+	}
+}
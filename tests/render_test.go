@@ -0,0 +1,38 @@
+package tests
+
+import (
+	"bytes"
+	"image/gif"
+	"image/png"
+	"testing"
+
+	"github.com/toujourser/gomoku/pkg/engine"
+	"github.com/toujourser/gomoku/pkg/render"
+)
+
+func TestRenderPNGAndGIF(t *testing.T) {
+	steps, _ := engine.ParseMoves("h8 i9 j10 h9")
+	buf := &bytes.Buffer{}
+	if err := render.PNG(buf, steps, render.Options{Numbers: true}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != b.Dy() || b.Dx() < 15*32 {
+		t.Fatalf("unexpected size %v", b)
+	}
+
+	buf.Reset()
+	if err = render.GIF(buf, steps, render.Options{Cell: 16}); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != len(steps)+1 {
+		t.Fatalf("got %d frames, want %d", len(anim.Image), len(steps)+1)
+	}
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/toujourser/gomoku/internal/dto"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/rest"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store/memory"
+)
+
+func restRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	rest.Register(router)
+	return router
+}
+
+// do 发送请求并在 out 不为 nil 时解析响应
+func do(t *testing.T, router http.Handler, method string, path string, body string, header map[string]string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%v %v: %v %s", method, path, err, w.Body.String())
+		}
+	}
+	return w.Code
+}
+
+// 大厅、存档和排行榜的 HTTP 接口，包括找不到资源时的 404
+func TestRestQueries(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStores()
+	service.UseStores(s)
+	router := restRouter()
+
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	if _, err := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{}); err != nil {
+		t.Fatal(err)
+	}
+	lobby := dto.LobbyDTO{}
+	if code := do(t, router, http.MethodGet, "/api/rooms?open=true", "", nil, &lobby); code != http.StatusOK || lobby.Total != 1 || lobby.Rooms[0].Host.Id != "host" {
+		t.Fatalf("rooms: %v %+v", code, lobby)
+	}
+	if code := do(t, router, http.MethodGet, "/api/rooms?sort=name", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("bad sort: %v", code)
+	}
+
+	game := &entity.Game{
+		Id:      "g1",
+		Black:   entity.Player{Id: "host", Name: "alice"},
+		White:   entity.Player{Id: "guest", Name: "bob"},
+		Winner:  "black",
+		EndTime: "2024-01-01 00:00:00",
+	}
+	if err := s.Games.Add(ctx, game); err != nil {
+		t.Fatal(err)
+	}
+	got := entity.Game{}
+	if code := do(t, router, http.MethodGet, "/api/games/g1", "", nil, &got); code != http.StatusOK || got.Id != "g1" {
+		t.Fatalf("game: %v %+v", code, got)
+	}
+	errDTO := dto.ErrDTO{}
+	if code := do(t, router, http.MethodGet, "/api/games/missing", "", nil, &errDTO); code != http.StatusNotFound || errDTO.Code != errcode.GameNotFound {
+		t.Fatalf("missing game: %v %+v", code, errDTO)
+	}
+
+	// 排行榜按 id 统计，显示在线玩家当前的名字
+	ranks := []entity.Rank{}
+	if code := do(t, router, http.MethodGet, "/api/leaderboard", "", nil, &ranks); code != http.StatusOK ||
+		len(ranks) != 1 || ranks[0] != (entity.Rank{Id: "host", Name: "unnamed", Wins: 1}) {
+		t.Fatalf("leaderboard: %v %+v", code, ranks)
+	}
+	if code := do(t, router, http.MethodGet, "/api/leaderboard?limit=-1", "", nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("bad limit: %v", code)
+	}
+}
+
+// 注册、登录和令牌校验
+func TestRestAccounts(t *testing.T) {
+	service.UseStores(memory.NewStores())
+	router := restRouter()
+
+	account := dto.AccountDTO{}
+	if code := do(t, router, http.MethodPost, "/api/register", `{"name":"alice","password":"secret1"}`, nil, &account); code != http.StatusCreated || account.Name != "alice" || account.Rating != service.DefaultRating {
+		t.Fatalf("register: %v %+v", code, account)
+	}
+	if code := do(t, router, http.MethodPost, "/api/register", `{"name":"alice","password":"secret2"}`, nil, nil); code != http.StatusConflict {
+		t.Fatalf("duplicate name: %v", code)
+	}
+	if code := do(t, router, http.MethodPost, "/api/register", `{"name":"bob","password":"123"}`, nil, nil); code != http.StatusBadRequest {
+		t.Fatalf("short password: %v", code)
+	}
+
+	if code := do(t, router, http.MethodPost, "/api/login", `{"name":"alice","password":"wrong!!"}`, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("wrong password: %v", code)
+	}
+	if code := do(t, router, http.MethodPost, "/api/login", `{"name":"nobody","password":"secret1"}`, nil, nil); code != http.StatusUnauthorized {
+		t.Fatalf("unknown name: %v", code)
+	}
+	login := dto.LoginDTO{}
+	if code := do(t, router, http.MethodPost, "/api/login", `{"name":"alice","password":"secret1"}`, nil, &login); code != http.StatusOK || login.Token == "" || login.Account.Id != account.Id {
+		t.Fatalf("login: %v %+v", code, login)
+	}
+
+	me := dto.AccountDTO{}
+	if code := do(t, router, http.MethodGet, "/api/me", "", map[string]string{"Authorization": "Bearer " + login.Token}, &me); code != http.StatusOK || me.Id != account.Id {
+		t.Fatalf("me: %v %+v", code, me)
+	}
+	if code := do(t, router, http.MethodGet, "/api/me", "", map[string]string{"Authorization": "Bearer nope"}, nil); code != http.StatusUnauthorized {
+		t.Fatalf("bad token: %v", code)
+	}
+}
@@ -0,0 +1,186 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/errcode"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store"
+	"github.com/toujourser/gomoku/internal/store/boltdb"
+	"github.com/toujourser/gomoku/internal/store/memory"
+)
+
+// 使用进程内存储走一遍建房、入座、准备和落子
+func TestRoomFlow(t *testing.T) {
+	ctx := context.Background()
+	service.UseStores(memory.NewStores())
+
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	guest, _ := service.NewPlayerConnect(ctx, "guest")
+	r, err := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.EnterRoom(ctx, guest.Id, r.Id, "challenger"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = service.EnterRoom(ctx, guest.Id, r.Id, "spectator"); !errcode.Is(err, errcode.AlreadyInRoom) {
+		t.Fatalf("entering twice: %v", err)
+	}
+	if _, err = service.SetReady(ctx, r.Id, host.Id, true); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = service.SetReady(ctx, r.Id, guest.Id, true); err != nil || !r.Started {
+		t.Fatalf("game should start: %v", err)
+	}
+
+	if _, _, _, err = service.MakeStep(ctx, guest.Id, r.Id, entity.Chess{I: 7, J: 7}); !errcode.Is(err, errcode.NotYourTurn) {
+		t.Fatalf("white moved first: %v", err)
+	}
+	if _, _, _, err = service.MakeStep(ctx, host.Id, r.Id, entity.Chess{I: 7, J: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, err = service.MakeStep(ctx, guest.Id, r.Id, entity.Chess{I: 7, J: 7}); !errcode.Is(err, errcode.PositionOccupied) {
+		t.Fatalf("occupied point: %v", err)
+	}
+	if r, err = service.GetRoom(ctx, r.Id); err != nil || len(r.Steps) != 1 {
+		t.Fatalf("room after one move: %+v %v", r, err)
+	}
+}
+
+// 进程内存储与 Redis 实现行为一致：读到的是副本，聊天只保留最近的消息，存档按结束时间倒序
+func TestMemoryStores(t *testing.T) {
+	ctx := context.Background()
+	s := memory.NewStores()
+
+	_ = s.Players.Set(ctx, &entity.Player{Id: "p1", Name: "alice"})
+	p, _ := s.Players.Get(ctx, "p1")
+	p.Name = "bob"
+	if p, _ = s.Players.Get(ctx, "p1"); p.Name != "alice" {
+		t.Fatal("store must return copies")
+	}
+	if _, err := s.Rooms.Get(ctx, "r1"); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("missing room: %v", err)
+	}
+
+	for i := 0; i < store.DialogSize+3; i++ {
+		_ = s.Chat.Add(ctx, &entity.DialogMsg{Content: fmt.Sprint(i)})
+	}
+	dialog, _ := s.Chat.List(ctx)
+	if len(*dialog) != store.DialogSize || (*dialog)[0].Content != "3" {
+		t.Fatalf("dialog %+v", *dialog)
+	}
+
+	for k, end := range []string{"2024-01-02 00:00:00", "2024-01-03 00:00:00", "2024-01-01 00:00:00"} {
+		_ = s.Games.Add(ctx, &entity.Game{
+			Id:      fmt.Sprint("g", k),
+			Winner:  "black",
+			Black:   []entity.Player{{Id: "p1", Name: "alice"}, {Id: "p2", Name: "bob"}, {Id: "p1", Name: "alice2"}}[k],
+			EndTime: end,
+		})
+	}
+	games, _ := s.Games.List(ctx, 1, 5)
+	if len(*games) != 2 || (*games)[0].Id != "g0" || (*games)[1].Id != "g2" {
+		t.Fatalf("games %+v", *games)
+	}
+	ranks, _ := s.Games.Leaderboard(ctx, 1)
+	if len(*ranks) != 1 || (*ranks)[0] != (entity.Rank{Id: "p1", Name: "alice2", Wins: 2}) {
+		t.Fatalf("leaderboard %+v", *ranks)
+	}
+}
+
+// 文件存储在重启后保留数据：进行中的对局等待玩家重连，未开始的房间和不在对局中的玩家被清理
+func TestFileStoreRecovery(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "gomoku.db")
+	stores, db, err := boltdb.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.UseStores(stores)
+	for _, pid := range []string{"host", "guest", "idle"} {
+		_, _ = service.NewPlayerConnect(ctx, pid)
+	}
+	token, err := service.IssueResumeToken(ctx, "host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	playing, _ := service.CreateRoom(ctx, "host", 0, entity.RoomSettings{})
+	_, _ = service.EnterRoom(ctx, "guest", playing.Id, "challenger")
+	_, _ = service.SetReady(ctx, playing.Id, "host", true)
+	_, _ = service.SetReady(ctx, playing.Id, "guest", true)
+	_, _, _, _ = service.MakeStep(ctx, "host", playing.Id, entity.Chess{I: 7, J: 7})
+	waiting, _ := service.CreateRoom(ctx, "idle", 0, entity.RoomSettings{})
+	_ = service.HallChat(ctx, &entity.DialogMsg{From: "idle", Content: "hi"})
+	_ = db.Close()
+
+	if stores, db, err = boltdb.Open(path); err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	service.UseStores(stores)
+	summary, err := service.Recover(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.RestoredGames != 1 || summary.DeletedRooms != 1 || summary.OfflinePlayers != 2 || summary.DroppedPlayers != 1 {
+		t.Fatalf("summary %+v", *summary)
+	}
+	if _, err = service.GetRoom(ctx, waiting.Id); !errcode.Is(err, errcode.RoomNotFound) {
+		t.Fatalf("waiting room: %v", err)
+	}
+	if dialog, _ := service.GetHallDialog(ctx); len(*dialog) != 1 {
+		t.Fatalf("dialog %+v", *dialog)
+	}
+
+	// 只知道玩家 id 不能接管座位
+	for _, bad := range []string{"", "guess"} {
+		if _, _, ok := service.ResumePlayer(ctx, "host", bad); ok {
+			t.Fatalf("resumed with token %q", bad)
+		}
+	}
+	p, r, ok := service.ResumePlayer(ctx, "host", token)
+	if !ok || r == nil || r.Id != playing.Id || len(r.Steps) != 1 || p.Status != "leisure" {
+		t.Fatalf("resume: %v %+v %+v", ok, p, r)
+	}
+	if _, _, ok = service.ResumePlayer(ctx, "host", token); ok {
+		t.Fatal("a connected player must not be resumed twice")
+	}
+}
+
+// 计分对局中的玩家不传 rid、把对局的着法作为 steps 也拿不到提示
+func TestHintSeatedBypass(t *testing.T) {
+	ctx := context.Background()
+	service.UseStores(memory.NewStores())
+	for k, v := range map[string]interface{}{"hint.engine": "basic", "hint.time": 50 * time.Millisecond, "hint.max_candidates": 1} {
+		viper.Set(k, v)
+		defer viper.Set(k, nil)
+	}
+
+	host, _ := service.NewPlayerConnect(ctx, "host")
+	guest, _ := service.NewPlayerConnect(ctx, "guest")
+	other, _ := service.NewPlayerConnect(ctx, "other")
+	r, _ := service.CreateRoom(ctx, host.Id, 0, entity.RoomSettings{Rated: true, Hints: true})
+	_, _ = service.EnterRoom(ctx, guest.Id, r.Id, "challenger")
+	_, _ = service.SetReady(ctx, r.Id, host.Id, true)
+	_, _ = service.SetReady(ctx, r.Id, guest.Id, true)
+	steps := []entity.Chess{{I: 7, J: 7}}
+	if _, _, _, err := service.MakeStep(ctx, host.Id, r.Id, steps[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pid := range []string{host.Id, guest.Id} {
+		if _, err := service.Hint(ctx, pid, "", steps, 1); !errcode.Is(err, errcode.HintsDisabled) {
+			t.Fatalf("seated player %v got a hint: %v", pid, err)
+		}
+	}
+	if _, err := service.Hint(ctx, other.Id, "", steps, 1); err != nil {
+		t.Fatalf("free analysis: %v", err)
+	}
+}
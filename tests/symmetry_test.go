@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/pkg/engine"
+)
+
+func TestSymmetryInverse(t *testing.T) {
+	c := entity.Chess{I: 2, J: 5}
+	for _, s := range engine.Symmetries {
+		if got := s.Inverse().Apply(s.Apply(c)); got != c {
+			t.Errorf("symmetry %d: inverse gives %v", s, got)
+		}
+	}
+}
+
+// 对称的局面和着法顺序不同的局面应归为同一个规范局面
+func TestCanonicalPosition(t *testing.T) {
+	a, _ := engine.ParseMoves("h8 i9 h9")
+	key, _ := engine.Canonical(a)
+	for _, s := range engine.Symmetries {
+		b := make([]entity.Chess, len(a))
+		for k, c := range a {
+			b[k] = s.Apply(c)
+		}
+		if got, _ := engine.Canonical(b); got != key {
+			t.Errorf("symmetry %d changes the key", s)
+		}
+	}
+	b, _ := engine.ParseMoves("h9 i9 h8")
+	if got, _ := engine.Canonical(b); got != key {
+		t.Error("transposition changes the key")
+	}
+	c, _ := engine.ParseMoves("h8 h9 i9")
+	if got, _ := engine.Canonical(c); got == key {
+		t.Error("different colors must not share a key")
+	}
+
+	// 天元开局后，四个相邻点是等价的着法
+	center, _ := engine.ParseMoves("h8")
+	_, syms := engine.Canonical(center)
+	want := engine.CanonicalMove(entity.Chess{I: 7, J: 8}, syms)
+	for _, m := range []entity.Chess{{I: 6, J: 7}, {I: 8, J: 7}, {I: 7, J: 6}} {
+		if got := engine.CanonicalMove(m, syms); got != want {
+			t.Errorf("%v canonicalizes to %v, want %v", m, got, want)
+		}
+	}
+}
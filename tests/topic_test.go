@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/olahol/melody"
+	"github.com/toujourser/gomoku/internal/constants"
+	"github.com/toujourser/gomoku/internal/entity"
+	"github.com/toujourser/gomoku/internal/service"
+	"github.com/toujourser/gomoku/internal/store/memory"
+	ws "github.com/toujourser/gomoku/internal/websocket"
+	"github.com/toujourser/gomoku/pkg/client"
+	"github.com/toujourser/gomoku/pkg/protocol"
+)
+
+func topicServer(t *testing.T) *httptest.Server {
+	service.UseStores(memory.NewStores())
+	m := melody.New()
+	ms := &ws.MelodySocket{M: m}
+	m.HandleMessage(ms.Receive)
+	m.HandleConnect(ms.Connect)
+	m.HandleDisconnect(ms.Disconnect)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = m.HandleRequest(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func expect[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%v not delivered", what)
+	}
+	var zero T
+	return zero
+}
+
+// 只有订阅了主题的连接收到推送，大厅和玩家列表以增量事件推送，旧版本连接继续收到完整列表
+func TestTopicSubscriptions(t *testing.T) {
+	srv := topicServer(t)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	legacy, _, err := websocket.DefaultDialer.DialContext(ctx, url+"?version=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer legacy.Close()
+
+	snapshot := make(chan []protocol.Player, 1)
+	players := make(chan protocol.PlayerDeltaDTO, 4)
+	lobby := make(chan protocol.LobbyDeltaDTO, 4)
+	chat := make(chan protocol.DialogMsg, 4)
+	a, err := client.Dial(ctx, client.Options{URL: url, NoReconnect: true, Topics: []string{constants.TopicLobby, constants.TopicPlayers}}, client.Handlers{
+		OnPlayers:     func(p []protocol.Player) { snapshot <- p },
+		OnPlayerDelta: func(d protocol.PlayerDeltaDTO) { players <- d },
+		OnLobbyDelta:  func(d protocol.LobbyDeltaDTO) { lobby <- d },
+		OnHallChat:    func(msg protocol.DialogMsg) { chat <- msg },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+	if list := expect(t, snapshot, "player list"); len(list) != 2 {
+		t.Fatalf("player list snapshot: %+v", list)
+	}
+
+	bLobby := make(chan protocol.LobbyDeltaDTO, 4)
+	b, err := client.Dial(ctx, client.Options{URL: url, NoReconnect: true}, client.Handlers{
+		OnLobbyDelta: func(d protocol.LobbyDeltaDTO) { bLobby <- d },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if d := expect(t, players, "player delta"); d.Op != protocol.DeltaAdd || d.PId != b.Player().Id {
+		t.Fatalf("player joined: %+v", d)
+	}
+
+	room, err := b.CreateRoom(ctx, constants.BLACK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := expect(t, lobby, "lobby delta"); d.Op != protocol.DeltaAdd || d.Room.Id != room.Id || d.Room.State != protocol.RoomOpen {
+		t.Fatalf("room created: %+v", d)
+	}
+	if err = b.HallChat(ctx, "hi"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = a.Subscribe(ctx, constants.TopicHall); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.HallChat(ctx, "again"); err != nil {
+		t.Fatal(err)
+	}
+	if msg := expect(t, chat, "hall chat"); msg.Content != "again" {
+		t.Fatalf("hall chat before subscribing was delivered: %+v", msg)
+	}
+	select {
+	case d := <-bLobby:
+		t.Fatalf("unsubscribed client got lobby delta %+v", d)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 版本 1 的连接没有订阅也收到完整的房间列表
+	_ = legacy.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for {
+		var msg struct {
+			Code int             `json:"code"`
+			Data json.RawMessage `json:"data"`
+		}
+		if err = legacy.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Code != constants.GetRooms {
+			continue
+		}
+		var rooms []entity.Room
+		if err = json.Unmarshal(msg.Data, &rooms); err != nil || len(rooms) != 1 || rooms[0].Id != room.Id {
+			t.Fatalf("legacy room list: %s %v", msg.Data, err)
+		}
+		break
+	}
+}
+
+// 格式错误的请求只收到带请求 id 的错误回复，连接和服务都不受影响；房间聊天的发送者取自连接而不是请求
+func TestMalformedRequests(t *testing.T) {
+	srv := topicServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	type reply struct {
+		Id   string          `json:"id"`
+		Code int             `json:"code"`
+		Data json.RawMessage `json:"data"`
+	}
+	// request 发送请求，返回第一条带有同一 id 的回复，chat 收集期间推送的房间聊天
+	var chat []entity.DialogMsg
+	request := func(id string, code int, data interface{}) reply {
+		t.Helper()
+		if err := conn.WriteJSON(map[string]interface{}{"id": id, "code": code, "data": data}); err != nil {
+			t.Fatal(err)
+		}
+		for {
+			var msg reply
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("%v: %v", id, err)
+			}
+			if msg.Code == constants.RoomChat && msg.Id == "" {
+				var m entity.DialogMsg
+				_ = json.Unmarshal(msg.Data, &m)
+				chat = append(chat, m)
+			}
+			if msg.Id == id {
+				return msg
+			}
+		}
+	}
+
+	for _, code := range []int{constants.EnterRoom, constants.RoomChat, constants.SetReady, constants.MakeStep, constants.RetractStep, constants.AskDraw} {
+		for k, data := range []interface{}{"r1", map[string]interface{}{}, map[string]interface{}{"rid": 1}} {
+			id := fmt.Sprint(code, "-", k)
+			if msg := request(id, code, data); msg.Code != constants.Fail {
+				t.Fatalf("%v: got %+v", id, msg)
+			}
+		}
+	}
+
+	created := request("create", constants.CreateRoom, float64(constants.BLACK))
+	var room entity.Room
+	if err = json.Unmarshal(created.Data, &room); err != nil || room.Id == "" {
+		t.Fatalf("create room: %s %v", created.Data, err)
+	}
+	if msg := request("chat", constants.RoomChat, map[string]interface{}{"rid": room.Id, "from": "admin", "content": "hi"}); msg.Code != constants.Success {
+		t.Fatalf("room chat: %+v", msg)
+	}
+	if len(chat) != 1 || chat[0].From != room.Host.Name || chat[0].Content != "hi" {
+		t.Fatalf("room chat %+v, want from %q", chat, room.Host.Name)
+	}
+}